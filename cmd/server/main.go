@@ -4,11 +4,25 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
+	"tor-admin/internal/audit"
 	"tor-admin/internal/auth"
+	"tor-admin/internal/instance"
+	"tor-admin/internal/metrics"
+	"tor-admin/internal/onion"
+	"tor-admin/internal/proxy"
+	"tor-admin/internal/scheduler"
+	"tor-admin/internal/services"
+	"tor-admin/internal/torlog"
 	"tor-admin/internal/ui"
 	"tor-admin/web"
 )
@@ -26,6 +40,82 @@ func main() {
 		port = "8080"
 	}
 
+	audit.SetPath(filepath.Join(auth.ConfigDir(), "audit.log"))
+
+	// Tor instances: the default one, Debian's /etc/tor/instances and any
+	// listed explicitly in instances.json
+	if err := instance.Load(filepath.Join(auth.ConfigDir(), "instances.json")); err != nil {
+		log.Fatalf("Failed to load tor instances: %v", err)
+	}
+
+	// Start a bandwidth scheduler per instance; each is a no-op until its schedule is enabled
+	scheds := map[string]*scheduler.Scheduler{}
+	for _, inst := range instance.List() {
+		file := "bandwidth-schedule.json"
+		if inst.ID != instance.DefaultID {
+			file = "bandwidth-schedule-" + inst.ID + ".json"
+		}
+		sched, err := scheduler.New(filepath.Join(auth.ConfigDir(), file), inst)
+		if err != nil {
+			log.Fatalf("Failed to load bandwidth schedule for %s: %v", inst.ID, err)
+		}
+		scheds[inst.ID] = sched
+		go sched.Run(context.Background())
+	}
+
+	// Follow tor's log events into a ring buffer for the log viewer
+	logs := torlog.NewBuffer(2000)
+	logLevel := os.Getenv("TOR_LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "NOTICE"
+	}
+
+	// TOR_SUPERVISE=true runs the default instance as our child instead of
+	// going through an init system; its stdout/stderr then feed the log viewer
+	// directly. Every other instance is followed over its control port.
+	supervised := ""
+	if os.Getenv("TOR_SUPERVISE") == "true" {
+		binary := os.Getenv("TOR_BINARY")
+		if binary == "" {
+			binary = "tor"
+		}
+		inst, _ := instance.Get(instance.DefaultID)
+		sup := services.NewSupervisor(binary, inst, logs)
+		services.Use(sup)
+		if err := sup.Start(); err != nil {
+			log.Fatalf("Failed to start tor: %v", err)
+		}
+		supervised = inst.ID
+		go forwardSignals(sup)
+	}
+	for _, inst := range instance.List() {
+		if inst.ID != supervised {
+			go torlog.Follow(context.Background(), logs, logLevel, inst)
+		}
+	}
+
+	// Hold each instance's ephemeral onion services and restore persisted ones after tor restarts
+	onions := map[string]*onion.Manager{}
+	for _, inst := range instance.List() {
+		file := "ephemeral-onions.json"
+		if inst.ID != instance.DefaultID {
+			file = "ephemeral-onions-" + inst.ID + ".json"
+		}
+		m, err := onion.NewManager(filepath.Join(auth.ConfigDir(), file), inst)
+		if err != nil {
+			log.Fatalf("Failed to load ephemeral onions for %s: %v", inst.ID, err)
+		}
+		onions[inst.ID] = m
+		go m.Run(context.Background())
+	}
+
+	// Reverse proxy sites that advertise their onion with Onion-Location
+	sites, err := proxy.NewManager(filepath.Join(auth.ConfigDir(), "proxy-sites.json"))
+	if err != nil {
+		log.Fatalf("Failed to load proxy sites: %v", err)
+	}
+	sites.Start()
+
 	// Create base router
 	mux := http.NewServeMux()
 
@@ -33,11 +123,95 @@ func main() {
 	ui.RegisterStatic(mux)
 
 	// Register web routes (handlers + templates)
-	web.RegisterRoutes(mux, cfg)
+	web.RegisterRoutes(mux, web.Deps{
+		Schedulers: scheds,
+		Logs:       logs,
+		Onions:     onions,
+		Vanity:     onion.NewVanity(),
+		Proxy:      sites,
+	})
+
+	// Prometheus metrics, either on the main listener or a separate one
+	metricsToken := os.Getenv("METRICS_TOKEN")
+	if metricsToken == "" && cfg != nil {
+		metricsToken = cfg.APIToken
+	}
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", auth.ProtectAPI(metricsToken, metrics.Handler()))
+		go func() {
+			log.Printf("📈 metrics listening on %s", addr)
+			log.Fatal(http.ListenAndServe(addr, metricsMux))
+		}()
+	} else {
+		mux.Handle("/metrics", auth.ProtectAPI(metricsToken, metrics.Handler()))
+	}
 
 	// Wrap with top-level middleware
-	handler := auth.WithSession(mux)
+	handler := metrics.Instrument(auth.WithSession(mux))
+
+	// ONION_ONLY=true keeps the UI off clearnet: listen on LISTEN_SOCKET or
+	// loopback only and publish it as a client-authorized onion service
+	if os.Getenv("ONION_ONLY") == "true" {
+		log.Fatal(serveOnionOnly(port, handler))
+	}
 
 	log.Printf("🚀 tor-admin is running on http://localhost:%s", port)
 	log.Fatal(http.ListenAndServe(":"+port, handler))
 }
+
+func serveOnionOnly(port string, handler http.Handler) error {
+	// ADMIN_ONION_INSTANCE picks the tor that publishes the admin service
+	inst, err := instance.Get(os.Getenv("ADMIN_ONION_INSTANCE"))
+	if err != nil {
+		return err
+	}
+	dir := os.Getenv("ADMIN_ONION_DIR")
+	if dir == "" {
+		dir = "/var/lib/tor/tor-admin"
+	}
+
+	var ln net.Listener
+	var target string
+	if sock := os.Getenv("LISTEN_SOCKET"); sock != "" {
+		_ = os.Remove(sock) // stale socket from a previous run
+		if ln, err = net.Listen("unix", sock); err != nil {
+			return err
+		}
+		onion.ShareSocket(sock, dir)
+		target = "unix:" + sock
+	} else {
+		if ln, err = net.Listen("tcp", "127.0.0.1:"+port); err != nil {
+			return err
+		}
+		target = ln.Addr().String()
+	}
+
+	admin, err := onion.ProvisionAdmin(inst, dir, target, filepath.Join(auth.ConfigDir(), "admin-onion-client.key"))
+	if err != nil {
+		ln.Close()
+		return fmt.Errorf("provisioning admin onion service: %w", err)
+	}
+	log.Printf("🧅 tor-admin is reachable only at http://%s (listening on %s)", admin.Hostname, target)
+	log.Printf("🔑 client auth: save this line as admin.auth_private in your ClientOnionAuthDir:")
+	log.Printf("   %s", admin.ClientAuth)
+	return http.Serve(ln, handler)
+}
+
+// forwardSignals passes SIGHUP on to the supervised tor as a reload and stops
+// tor before exiting on SIGINT/SIGTERM
+func forwardSignals(sup *services.Supervisor) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	for sig := range sigs {
+		if sig == syscall.SIGHUP {
+			if err := sup.Reload(); err != nil {
+				log.Printf("reload tor: %v", err)
+			}
+			continue
+		}
+		log.Printf("received %s, stopping tor", sig)
+		_ = sup.Stop()
+		os.Exit(0)
+	}
+}
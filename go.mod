@@ -2,4 +2,11 @@ module github.com/casjaysdev/tor-admin
 
 go 1.21
 
-require github.com/gorilla/securecookie v1.1.1
+require (
+	filippo.io/edwards25519 v1.1.0
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/gorilla/securecookie v1.1.1
+	golang.org/x/crypto v0.27.0
+)
+
+require golang.org/x/sys v0.25.0 // indirect
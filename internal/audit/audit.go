@@ -0,0 +1,62 @@
+// File: internal/audit/audit.go
+// Purpose: Append-only audit trail of administrative actions (JSON lines)
+
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry is one audited action
+type Entry struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Action string    `json:"action"`
+	Target string    `json:"target,omitempty"`
+	Result string    `json:"result"`
+	Error  string    `json:"error,omitempty"`
+}
+
+var (
+	mu   sync.Mutex
+	path string
+)
+
+// SetPath sets the audit log file; until called entries only go to the process log
+func SetPath(p string) {
+	mu.Lock()
+	path = p
+	mu.Unlock()
+}
+
+// Record logs an action and appends it to the audit file
+func Record(user, action, target string, err error) {
+	e := Entry{Time: time.Now().UTC(), User: user, Action: action, Target: target, Result: "ok"}
+	if err != nil {
+		e.Result, e.Error = "error", err.Error()
+	}
+	log.Printf("audit: user=%q action=%s target=%q result=%s", e.User, e.Action, e.Target, e.Result)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		log.Printf("audit: %v", err)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("audit: %v", err)
+		return
+	}
+	defer f.Close()
+	line, _ := json.Marshal(e)
+	f.Write(append(line, '\n'))
+}
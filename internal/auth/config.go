@@ -25,6 +25,11 @@ func userConfigDir() string {
 	return "."
 }
 
+// ConfigDir returns the directory holding tor-admin's own state files
+func ConfigDir() string {
+	return filepath.Dir(configPath)
+}
+
 // LoadOrInitConfig loads the config or returns nil if setup is needed.
 func LoadOrInitConfig() (*UserConfig, error) {
 	if _, err := os.Stat(configPath); errors.Is(err, os.ErrNotExist) {
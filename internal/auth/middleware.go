@@ -50,6 +50,41 @@ func RequireLogin(next http.Handler) http.Handler {
 	})
 }
 
+// IsAdmin reports whether the request's user is the administrator account
+// (the one created during setup). The config is read per request so an
+// account created after startup is recognized without a restart.
+func IsAdmin(r *http.Request) bool {
+	if disabledAuth {
+		return true
+	}
+	user := GetUser(r)
+	if user == "" {
+		return false
+	}
+	cfg, err := LoadOrInitConfig()
+	return err == nil && cfg != nil && user == cfg.Username
+}
+
+// RequireAdmin allows only the administrator account and answers everyone
+// else with 403
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if disabledAuth {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if GetUser(r) == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !IsAdmin(r) {
+			http.Error(w, "Forbidden: admin only", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // Helper: check if Authorization token matches expected
 func isValidToken(header, expectedToken string) bool {
 	if header == "" || expectedToken == "" {
@@ -0,0 +1,278 @@
+// File: internal/bandwidth/accounting.go
+// Purpose: Plan Tor Accounting settings and forecast when a relay would hibernate
+
+package bandwidth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AccountingPeriod string
+type AccountingRule string
+
+const (
+	PeriodDay   AccountingPeriod = "day"
+	PeriodWeek  AccountingPeriod = "week"
+	PeriodMonth AccountingPeriod = "month"
+
+	RuleSum AccountingRule = "sum"
+	RuleMax AccountingRule = "max"
+	RuleIn  AccountingRule = "in"
+	RuleOut AccountingRule = "out"
+)
+
+// minRelayRate is the lowest BandwidthRate tor accepts for a relay (75 KBytes)
+const minRelayRate = 75 * 1024
+
+// AccountingStart mirrors torrc's "AccountingStart day|week|month [day] HH:MM"
+type AccountingStart struct {
+	Period AccountingPeriod `json:"period"`
+	Day    int              `json:"day"` // 1-7 (Monday=1) for week, 1-28 for month, ignored for day
+	Hour   int              `json:"hour"`
+	Minute int              `json:"minute"`
+}
+
+// String formats the start the way torrc expects it
+func (s AccountingStart) String() string {
+	clock := fmt.Sprintf("%02d:%02d", s.Hour, s.Minute)
+	if s.Period == PeriodDay {
+		return string(s.Period) + " " + clock
+	}
+	return fmt.Sprintf("%s %d %s", s.Period, s.Day, clock)
+}
+
+// Validate checks the day, hour and minute ranges tor enforces
+func (s AccountingStart) Validate() error {
+	switch s.Period {
+	case PeriodDay:
+	case PeriodWeek:
+		if s.Day < 1 || s.Day > 7 {
+			return errors.New("week start day must be 1-7 (Monday=1)")
+		}
+	case PeriodMonth:
+		if s.Day < 1 || s.Day > 28 {
+			return errors.New("month start day must be 1-28")
+		}
+	default:
+		return errors.New("invalid accounting period")
+	}
+	if s.Hour < 0 || s.Hour > 23 || s.Minute < 0 || s.Minute > 59 {
+		return errors.New("invalid accounting start time")
+	}
+	return nil
+}
+
+// ParseAccountingStart parses an AccountingStart torrc value such as "month 1 00:00"
+func ParseAccountingStart(input string) (AccountingStart, error) {
+	fields := strings.Fields(strings.ToLower(input))
+	if len(fields) < 2 {
+		return AccountingStart{}, errors.New("accounting start must be: day|week|month [day] HH:MM")
+	}
+	s := AccountingStart{Period: AccountingPeriod(fields[0])}
+	clock := fields[len(fields)-1]
+	switch {
+	case s.Period == PeriodDay && len(fields) == 2:
+	case (s.Period == PeriodWeek || s.Period == PeriodMonth) && len(fields) == 3:
+		day, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return AccountingStart{}, errors.New("invalid accounting start day")
+		}
+		s.Day = day
+	default:
+		return AccountingStart{}, errors.New("accounting start must be: day|week|month [day] HH:MM")
+	}
+	hh, mm, ok := strings.Cut(clock, ":")
+	if !ok {
+		return AccountingStart{}, errors.New("invalid accounting start time")
+	}
+	var err1, err2 error
+	s.Hour, err1 = strconv.Atoi(hh)
+	s.Minute, err2 = strconv.Atoi(mm)
+	if err1 != nil || err2 != nil {
+		return AccountingStart{}, errors.New("invalid accounting start time")
+	}
+	return s, s.Validate()
+}
+
+// ParseAccountingRule converts user input into a valid AccountingRule
+func ParseAccountingRule(input string) (AccountingRule, error) {
+	switch rule := AccountingRule(strings.ToLower(strings.TrimSpace(input))); rule {
+	case RuleSum, RuleMax, RuleIn, RuleOut:
+		return rule, nil
+	case "":
+		return RuleMax, nil // tor's default
+	default:
+		return "", errors.New("invalid accounting rule")
+	}
+}
+
+// Interval returns the bounds of the accounting period containing t, using
+// real calendar lengths (28-31 day months, DST shifts) in t's location
+func (s AccountingStart) Interval(t time.Time) (time.Time, time.Time) {
+	y, m, d := t.Date()
+	loc := t.Location()
+	switch s.Period {
+	case PeriodWeek:
+		// time.Weekday has Sunday=0; tor counts Monday=1 .. Sunday=7
+		offset := (int(t.Weekday())+6)%7 + 1 - s.Day
+		if offset < 0 {
+			offset += 7
+		}
+		start := time.Date(y, m, d-offset, s.Hour, s.Minute, 0, 0, loc)
+		if start.After(t) {
+			start = start.AddDate(0, 0, -7)
+		}
+		return start, start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		start := time.Date(y, m, s.Day, s.Hour, s.Minute, 0, 0, loc)
+		if start.After(t) {
+			start = start.AddDate(0, -1, 0)
+		}
+		return start, start.AddDate(0, 1, 0)
+	default:
+		start := time.Date(y, m, d, s.Hour, s.Minute, 0, 0, loc)
+		if start.After(t) {
+			start = start.AddDate(0, 0, -1)
+		}
+		return start, start.AddDate(0, 0, 1)
+	}
+}
+
+// AccountingPlan holds torrc-ready accounting settings for one period
+type AccountingPlan struct {
+	AccountingMax       string    `json:"accounting_max"`
+	AccountingStart     string    `json:"accounting_start"`
+	AccountingRule      string    `json:"accounting_rule"`
+	RelayBandwidthRate  string    `json:"relay_bandwidth_rate"`
+	RelayBandwidthBurst string    `json:"relay_bandwidth_burst"`
+	IntervalStart       time.Time `json:"interval_start"`
+	IntervalEnd         time.Time `json:"interval_end"`
+	QuotaBytes          int64     `json:"quota_bytes"`
+	RateBytes           int64     `json:"rate_bytes"`
+	BurstBytes          int64     `json:"burst_bytes"`
+
+	rule AccountingRule
+}
+
+// PlanAccounting builds accounting settings that spread quota evenly across
+// the accounting period containing now
+func PlanAccounting(quota int64, rule AccountingRule, start AccountingStart, now time.Time) (*AccountingPlan, error) {
+	if quota <= 0 {
+		return nil, errors.New("accounting quota must be positive")
+	}
+	if err := start.Validate(); err != nil {
+		return nil, err
+	}
+	rule, err := ParseAccountingRule(string(rule))
+	if err != nil {
+		return nil, err
+	}
+	begin, end := start.Interval(now)
+	seconds := int64(end.Sub(begin) / time.Second)
+
+	// With "sum" both directions draw from one quota, and relays move
+	// roughly as many bytes out as in
+	rate := quota / seconds
+	if rule == RuleSum {
+		rate /= 2
+	}
+	if rate >= unitMap[KB] {
+		rate -= rate % unitMap[KB]
+	}
+	if rate < minRelayRate {
+		return nil, errors.New("quota too small: suggested rate is below tor's 75 KBytes relay minimum")
+	}
+	burst := rate * 2
+
+	return &AccountingPlan{
+		AccountingMax:       FormatBytes(quota),
+		AccountingStart:     start.String(),
+		AccountingRule:      string(rule),
+		RelayBandwidthRate:  FormatBytes(rate),
+		RelayBandwidthBurst: FormatBytes(burst),
+		IntervalStart:       begin,
+		IntervalEnd:         end,
+		QuotaBytes:          quota,
+		RateBytes:           rate,
+		BurstBytes:          burst,
+		rule:                rule,
+	}, nil
+}
+
+// WithRate replaces the suggested relay rate, e.g. to see how soon a faster
+// rate would exhaust the quota; burst stays at twice the rate
+func (p *AccountingPlan) WithRate(rate int64) error {
+	if rate < minRelayRate {
+		return errors.New("relay rate must be at least 75 KBytes")
+	}
+	p.RateBytes, p.BurstBytes = rate, rate*2
+	p.RelayBandwidthRate, p.RelayBandwidthBurst = FormatBytes(rate), FormatBytes(rate*2)
+	return nil
+}
+
+// TorrcLines returns the plan as torrc "Key Value" lines
+func (p *AccountingPlan) TorrcLines() []string {
+	return []string{
+		"AccountingMax " + p.AccountingMax,
+		"AccountingStart " + p.AccountingStart,
+		"AccountingRule " + p.AccountingRule,
+		"RelayBandwidthRate " + p.RelayBandwidthRate,
+		"RelayBandwidthBurst " + p.RelayBandwidthBurst,
+	}
+}
+
+// TrafficProfile is the expected bytes/sec in each direction for every hour of the day
+type TrafficProfile [24]int64
+
+// FlatProfile returns a profile with the same rate all day
+func FlatProfile(bytesPerSec int64) TrafficProfile {
+	var p TrafficProfile
+	for i := range p {
+		p[i] = bytesPerSec
+	}
+	return p
+}
+
+// HibernationForecast is the outcome of simulating a plan against a profile
+type HibernationForecast struct {
+	Hibernates  bool      `json:"hibernates"`
+	HibernateAt time.Time `json:"hibernate_at,omitempty"`
+	UsedBytes   int64     `json:"used_bytes"`
+	LeftBytes   int64     `json:"left_bytes"`
+}
+
+// Simulate walks the accounting period hour by hour, capping traffic at the
+// planned rate, and reports when the quota would run out
+func (p *AccountingPlan) Simulate(profile TrafficProfile) HibernationForecast {
+	var used int64
+	for t := p.IntervalStart; t.Before(p.IntervalEnd); {
+		next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		if next.After(p.IntervalEnd) {
+			next = p.IntervalEnd
+		}
+		perDir := profile[t.Hour()]
+		if perDir > p.RateBytes {
+			perDir = p.RateBytes
+		}
+		counted := perDir
+		if p.rule == RuleSum {
+			counted *= 2
+		}
+		step := counted * int64(next.Sub(t)/time.Second)
+		if counted > 0 && used+step >= p.QuotaBytes {
+			secs := (p.QuotaBytes - used) / counted
+			return HibernationForecast{
+				Hibernates:  true,
+				HibernateAt: t.Add(time.Duration(secs) * time.Second),
+				UsedBytes:   p.QuotaBytes,
+			}
+		}
+		used += step
+		t = next
+	}
+	return HibernationForecast{UsedBytes: used, LeftBytes: p.QuotaBytes - used}
+}
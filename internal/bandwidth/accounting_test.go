@@ -0,0 +1,184 @@
+package bandwidth
+
+import (
+	"testing"
+	"time"
+)
+
+func utc(y int, m time.Month, d, hh, mm int) time.Time {
+	return time.Date(y, m, d, hh, mm, 0, 0, time.UTC)
+}
+
+func TestParseAccountingStart(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    AccountingStart
+		wantErr bool
+	}{
+		{in: "day 06:30", want: AccountingStart{Period: PeriodDay, Hour: 6, Minute: 30}},
+		{in: "week 1 00:00", want: AccountingStart{Period: PeriodWeek, Day: 1}},
+		{in: "Month 28 23:59", want: AccountingStart{Period: PeriodMonth, Day: 28, Hour: 23, Minute: 59}},
+		{in: "month 29 00:00", wantErr: true},
+		{in: "week 8 00:00", wantErr: true},
+		{in: "week 0 00:00", wantErr: true},
+		{in: "day 24:00", wantErr: true},
+		{in: "day 1 00:00", wantErr: true},
+		{in: "month 00:00", wantErr: true},
+		{in: "year 1 00:00", wantErr: true},
+		{in: "day 0630", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAccountingStart(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAccountingStart(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseAccountingStart(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAccountingStartString(t *testing.T) {
+	for _, in := range []string{"day 06:30", "week 7 22:00", "month 1 00:00"} {
+		s, err := ParseAccountingStart(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.String() != in {
+			t.Errorf("String() = %q, want %q", s.String(), in)
+		}
+	}
+}
+
+func TestAccountingInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		start      string
+		at         time.Time
+		begin, end time.Time
+	}{
+		{"day before start time", "day 06:30", utc(2026, 10, 19, 5, 0), utc(2026, 10, 18, 6, 30), utc(2026, 10, 19, 6, 30)},
+		{"day exactly at start", "day 06:30", utc(2026, 10, 19, 6, 30), utc(2026, 10, 19, 6, 30), utc(2026, 10, 20, 6, 30)},
+		{"week ending sunday night", "week 1 00:00", utc(2026, 10, 25, 23, 59), utc(2026, 10, 19, 0, 0), utc(2026, 10, 26, 0, 0)},
+		{"week exactly at start", "week 1 00:00", utc(2026, 10, 19, 0, 0), utc(2026, 10, 19, 0, 0), utc(2026, 10, 26, 0, 0)},
+		{"week starting sunday", "week 7 22:00", utc(2026, 10, 25, 21, 0), utc(2026, 10, 18, 22, 0), utc(2026, 10, 25, 22, 0)},
+		{"month of february", "month 1 00:00", utc(2026, 2, 15, 12, 0), utc(2026, 2, 1, 0, 0), utc(2026, 3, 1, 0, 0)},
+		{"month just before start", "month 15 12:00", utc(2026, 3, 15, 11, 59), utc(2026, 2, 15, 12, 0), utc(2026, 3, 15, 12, 0)},
+		{"month across new year", "month 28 00:00", utc(2026, 1, 5, 0, 0), utc(2025, 12, 28, 0, 0), utc(2026, 1, 28, 0, 0)},
+	}
+	for _, tt := range tests {
+		s, err := ParseAccountingStart(tt.start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		begin, end := s.Interval(tt.at)
+		if !begin.Equal(tt.begin) || !end.Equal(tt.end) {
+			t.Errorf("%s: Interval = %v – %v, want %v – %v", tt.name, begin, end, tt.begin, tt.end)
+		}
+	}
+}
+
+func TestAccountingIntervalDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	s := AccountingStart{Period: PeriodMonth, Day: 1}
+	begin, end := s.Interval(time.Date(2026, 3, 20, 0, 0, 0, 0, loc))
+	if got, want := end.Sub(begin), 31*24*time.Hour-time.Hour; got != want {
+		t.Errorf("March interval = %v, want %v (clocks go forward)", got, want)
+	}
+}
+
+func TestPlanAccounting(t *testing.T) {
+	start := AccountingStart{Period: PeriodMonth, Day: 1}
+	now := utc(2026, 10, 19, 12, 0)
+
+	p, err := PlanAccounting(1<<40, RuleSum, start, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"AccountingMax 1 TBytes",
+		"AccountingStart month 1 00:00",
+		"AccountingRule sum",
+		"RelayBandwidthRate 200 KBytes",
+		"RelayBandwidthBurst 400 KBytes",
+	}
+	got := p.TorrcLines()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("TorrcLines()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if _, err := PlanAccounting(1<<30, RuleMax, start, now); err == nil {
+		t.Error("1 GBytes a month should be below the relay minimum rate")
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	start := AccountingStart{Period: PeriodMonth, Day: 1}
+	p, err := PlanAccounting(1<<40, RuleMax, start, utc(2026, 10, 19, 12, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.RateBytes != 400<<10 {
+		t.Fatalf("RateBytes = %d, want 400 KBytes", p.RateBytes)
+	}
+
+	// Traffic is capped at the planned rate, which fits the 31-day October
+	f := p.Simulate(FlatProfile(p.RateBytes * 10))
+	if f.Hibernates {
+		t.Fatalf("planned rate hibernates at %v", f.HibernateAt)
+	}
+	if used := p.RateBytes * 31 * 86400; f.UsedBytes != used || f.LeftBytes != 1<<40-used {
+		t.Errorf("used/left = %d/%d, want %d/%d", f.UsedBytes, f.LeftBytes, used, 1<<40-used)
+	}
+
+	// Twice the rate runs out of quota partway through the month
+	if err := p.WithRate(800 << 10); err != nil {
+		t.Fatal(err)
+	}
+	f = p.Simulate(FlatProfile(p.RateBytes))
+	want := utc(2026, 10, 1, 0, 0).Add(time.Duration((1<<40)/p.RateBytes) * time.Second)
+	if !f.Hibernates || !f.HibernateAt.Equal(want) {
+		t.Errorf("forecast = %+v, want hibernation at %v", f, want)
+	}
+
+	// No traffic at night moves hibernation later
+	night := FlatProfile(p.RateBytes)
+	for h := 0; h < 6; h++ {
+		night[h] = 0
+	}
+	if g := p.Simulate(night); !g.Hibernates || !g.HibernateAt.After(want) {
+		t.Errorf("quiet nights: forecast = %+v, want hibernation after %v", g, want)
+	}
+
+	if err := p.WithRate(1024); err == nil {
+		t.Error("WithRate below the relay minimum should fail")
+	}
+}
+
+func TestBytesPerSecondAt(t *testing.T) {
+	tests := []struct {
+		interval Interval
+		at       time.Time
+		want     int64
+	}{
+		{Monthly, utc(2026, 2, 10, 0, 0), (28 << 30) / (28 * 86400)},
+		{Monthly, utc(2026, 10, 19, 0, 0), (28 << 30) / (31 * 86400)},
+		{Weekly, utc(2026, 10, 19, 0, 0), (28 << 30) / (7 * 86400)},
+		{Daily, utc(2026, 10, 19, 0, 0), (28 << 30) / 86400},
+	}
+	for _, tt := range tests {
+		got, err := BytesPerSecondAt(28<<30, tt.interval, tt.at)
+		if err != nil || got != tt.want {
+			t.Errorf("BytesPerSecondAt(28 GBytes, %s, %v) = %d, %v; want %d", tt.interval, tt.at, got, err, tt.want)
+		}
+	}
+	if _, err := BytesPerSecondAt(1, "yearly", time.Now()); err == nil {
+		t.Error("unknown interval should fail")
+	}
+}
@@ -1,5 +1,5 @@
 // File: internal/bandwidth/math.go
-// Purpose: Convert MB/GB per day/week/month into Tor's expected bytes/sec
+// Purpose: Convert MB/GB per calendar day/week/month into Tor's expected bytes/sec
 
 package bandwidth
 
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type Unit string
@@ -30,24 +31,32 @@ var unitMap = map[Unit]int64{
 	TB: 1024 * 1024 * 1024 * 1024,
 }
 
-var intervalSeconds = map[Interval]int64{
-	Daily:   86400,
-	Weekly:  604800,
-	Monthly: 2592000, // 30 days
+// intervalStarts maps calculator intervals onto accounting periods starting
+// at midnight (weeks on Monday) so their lengths follow the calendar
+var intervalStarts = map[Interval]AccountingStart{
+	Daily:   {Period: PeriodDay},
+	Weekly:  {Period: PeriodWeek, Day: 1},
+	Monthly: {Period: PeriodMonth, Day: 1},
 }
 
-// ToBytesPerSecond converts user input into Tor-compatible bytes/sec
-func ToBytesPerSecond(amount int64, unit Unit, interval Interval) (int64, error) {
-	mult, ok := unitMap[unit]
+// IntervalBounds returns the calendar day, week or month containing now
+func IntervalBounds(interval Interval, now time.Time) (time.Time, time.Time, error) {
+	start, ok := intervalStarts[interval]
 	if !ok {
-		return 0, errors.New("invalid unit")
+		return time.Time{}, time.Time{}, errors.New("invalid interval")
 	}
-	seconds, ok := intervalSeconds[interval]
-	if !ok {
-		return 0, errors.New("invalid interval")
+	begin, end := start.Interval(now)
+	return begin, end, nil
+}
+
+// BytesPerSecondAt spreads totalBytes over the real length of the interval
+// containing now (28-31 day months, DST days)
+func BytesPerSecondAt(totalBytes int64, interval Interval, now time.Time) (int64, error) {
+	begin, end, err := IntervalBounds(interval, now)
+	if err != nil {
+		return 0, err
 	}
-	totalBytes := amount * mult
-	return totalBytes / seconds, nil
+	return totalBytes / int64(end.Sub(begin)/time.Second), nil
 }
 
 // PrettyPrintBytes formats a value in bytes/sec into a readable string
@@ -64,21 +73,24 @@ func PrettyPrintBytes(bytesPerSec int64) string {
 	}
 }
 
-// ParseUnit converts user input into a valid Unit
+// ParseUnit converts user input into a valid Unit, accepting any spelling
+// in tor's size unit table ("KB", "kilobytes", "MBits", ...)
 func ParseUnit(input string) (Unit, error) {
-	input = strings.ToUpper(strings.TrimSpace(input))
-	switch input {
-	case "KB":
+	def, ok := lookupUnit(input, false)
+	if !ok || strings.TrimSpace(input) == "" {
+		return "", errors.New("invalid unit")
+	}
+	switch def.Name {
+	case "KBytes":
 		return KB, nil
-	case "MB":
+	case "MBytes":
 		return MB, nil
-	case "GB":
+	case "GBytes":
 		return GB, nil
-	case "TB":
+	case "TBytes":
 		return TB, nil
-	default:
-		return "", errors.New("invalid unit")
 	}
+	return Unit(def.Name), nil
 }
 
 // ParseInterval converts user input into a valid Interval
@@ -0,0 +1,125 @@
+// File: internal/bandwidth/schedule.go
+// Purpose: Weekly time-of-day bandwidth schedules (which rate applies when)
+
+package bandwidth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ScheduleEntry applies Rate/Burst on the given days between StartHour
+// (inclusive) and EndHour (exclusive, 1-24)
+type ScheduleEntry struct {
+	Days      []string `json:"days"` // "mon".."sun"; empty means every day
+	StartHour int      `json:"start_hour"`
+	EndHour   int      `json:"end_hour"`
+	Rate      string   `json:"rate"`
+	Burst     string   `json:"burst"`
+}
+
+// Schedule is a weekly plan of rates. Later entries win when they overlap;
+// hours not covered by any entry use DefaultRate/DefaultBurst.
+type Schedule struct {
+	Enabled      bool            `json:"enabled"`
+	Relay        bool            `json:"relay"`   // set RelayBandwidthRate/Burst instead of BandwidthRate/Burst
+	Persist      bool            `json:"persist"` // also write applied values to torrc
+	DefaultRate  string          `json:"default_rate"`
+	DefaultBurst string          `json:"default_burst"`
+	Entries      []ScheduleEntry `json:"entries"`
+}
+
+// Validate checks day names, hour ranges and that every rate parses
+func (s *Schedule) Validate() error {
+	if err := validateRatePair(s.DefaultRate, s.DefaultBurst); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for i, e := range s.Entries {
+		for _, d := range e.Days {
+			if weekdayIndex(d) < 0 {
+				return fmt.Errorf("entry %d: invalid day %q", i+1, d)
+			}
+		}
+		if e.StartHour < 0 || e.StartHour > 23 || e.EndHour <= e.StartHour || e.EndHour > 24 {
+			return fmt.Errorf("entry %d: hours must satisfy 0 <= start < end <= 24", i+1)
+		}
+		if err := validateRatePair(e.Rate, e.Burst); err != nil {
+			return fmt.Errorf("entry %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateRatePair(rate, burst string) error {
+	r, err := ParseRate(rate)
+	if err != nil {
+		return fmt.Errorf("rate: %w", err)
+	}
+	b, err := ParseRate(burst)
+	if err != nil {
+		return fmt.Errorf("burst: %w", err)
+	}
+	if b.Bytes < r.Bytes {
+		return errors.New("burst must be at least the rate")
+	}
+	return nil
+}
+
+// At returns the rate and burst in effect at t (in t's location)
+func (s *Schedule) At(t time.Time) (string, string) {
+	rate, burst := s.DefaultRate, s.DefaultBurst
+	for _, e := range s.Entries {
+		if e.covers(t.Weekday(), t.Hour()) {
+			rate, burst = e.Rate, e.Burst
+		}
+	}
+	return rate, burst
+}
+
+// Heatmap returns the scheduled rate in bytes/sec for every weekday
+// (Sunday first) and hour, for rendering a weekly grid
+func (s *Schedule) Heatmap() [7][24]int64 {
+	var grid [7][24]int64
+	for d := range grid {
+		for h := range grid[d] {
+			rate := s.DefaultRate
+			for _, e := range s.Entries {
+				if e.covers(time.Weekday(d), h) {
+					rate = e.Rate
+				}
+			}
+			q, _ := ParseRate(rate)
+			grid[d][h] = q.Bytes
+		}
+	}
+	return grid
+}
+
+func (e ScheduleEntry) covers(day time.Weekday, hour int) bool {
+	if hour < e.StartHour || hour >= e.EndHour {
+		return false
+	}
+	if len(e.Days) == 0 {
+		return true
+	}
+	for _, d := range e.Days {
+		if weekdayIndex(d) == int(day) {
+			return true
+		}
+	}
+	return false
+}
+
+func weekdayIndex(name string) int {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, n := range weekdayNames {
+		if len(name) >= 3 && strings.HasPrefix(name, n) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,182 @@
+// File: internal/bandwidth/units.go
+// Purpose: Parse and format tor's native byte and rate strings ("5 MBytes", "800 KBits", ...)
+
+package bandwidth
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type unitDef struct {
+	Name  string // canonical spelling used when formatting
+	Bytes int64  // bytes per one of this unit
+	Bits  bool
+}
+
+// Byte units are binary in tor (KBytes = 1024 bytes) for both sizes and rates
+var byteUnits = []unitDef{
+	{"bytes", 1, false},
+	{"KBytes", 1 << 10, false},
+	{"MBytes", 1 << 20, false},
+	{"GBytes", 1 << 30, false},
+	{"TBytes", 1 << 40, false},
+}
+
+// Bit units in size options (AccountingMax, MaxMemInQueues) are binary: 1 KBit = 1024 bits
+var memoryBitUnits = []unitDef{
+	{"KBits", 1 << 7, true},
+	{"MBits", 1 << 17, true},
+	{"GBits", 1 << 27, true},
+	{"TBits", 1 << 37, true},
+}
+
+// Bit units in rate options (BandwidthRate, RelayBandwidthBurst, ...) are SI: 1 KBit = 1000 bits
+var rateBitUnits = []unitDef{
+	{"KBits", 1000 / 8, true},
+	{"MBits", 1000 * 1000 / 8, true},
+	{"GBits", 1000 * 1000 * 1000 / 8, true},
+	{"TBits", 1000 * 1000 * 1000 * 1000 / 8, true},
+}
+
+// unitAliases maps every lower-case spelling tor accepts (config.c's
+// memory_units and rate_units) to a canonical unit name
+var unitAliases = buildUnitAliases()
+
+// sizeOnlyAliases are in tor's size table but not its rate table
+var sizeOnlyAliases = map[string]bool{"m": true}
+
+func buildUnitAliases() map[string]string {
+	aliases := map[string]string{
+		"": "bytes", "b": "bytes", "byte": "bytes", "bytes": "bytes",
+		"m": "MBytes", // tor's legacy shorthand for megabytes, sizes only
+	}
+	prefixes := []struct{ short, long, letter string }{
+		{"k", "kilo", "K"}, {"m", "mega", "M"}, {"g", "giga", "G"}, {"t", "tera", "T"},
+	}
+	for _, p := range prefixes {
+		for _, s := range []string{p.short + "b", p.short + "byte", p.short + "bytes", p.long + "byte", p.long + "bytes"} {
+			aliases[s] = p.letter + "Bytes"
+		}
+		for _, s := range []string{p.short + "bit", p.short + "bits", p.long + "bit", p.long + "bits"} {
+			aliases[s] = p.letter + "Bits"
+		}
+	}
+	return aliases
+}
+
+// lookupUnit resolves a spelling tor accepts to its definition in the size or
+// rate table; anything tor would reject, such as "MB/s", is refused
+func lookupUnit(name string, rate bool) (unitDef, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	canonical, ok := unitAliases[name]
+	if !ok || rate && sizeOnlyAliases[name] {
+		return unitDef{}, false
+	}
+	table := memoryBitUnits
+	if rate {
+		table = rateBitUnits
+	}
+	for _, defs := range [][]unitDef{byteUnits, table} {
+		for _, d := range defs {
+			if d.Name == canonical {
+				return d, true
+			}
+		}
+	}
+	return unitDef{}, false
+}
+
+// Quantity is a parsed tor value that remembers the unit it was written in
+type Quantity struct {
+	Bytes int64  `json:"bytes"`
+	Unit  string `json:"unit"`
+	Rate  bool   `json:"rate,omitempty"`
+}
+
+// ParseBytes parses a size value such as AccountingMax ("1 GB", "10 megabytes", "4096")
+func ParseBytes(input string) (Quantity, error) {
+	return parseQuantity(input, false)
+}
+
+// ParseRate parses a rate value such as BandwidthRate ("5 MBytes", "800 KBits", "100 kb")
+func ParseRate(input string) (Quantity, error) {
+	return parseQuantity(input, true)
+}
+
+func parseQuantity(input string, rate bool) (Quantity, error) {
+	input = strings.TrimSpace(input)
+	end := 0
+	for end < len(input) && (input[end] >= '0' && input[end] <= '9' || input[end] == '.') {
+		end++
+	}
+	if end == 0 {
+		return Quantity{}, errors.New("bandwidth value must start with a number")
+	}
+	amount, err := strconv.ParseFloat(input[:end], 64)
+	if err != nil {
+		return Quantity{}, errors.New("invalid numeric bandwidth value")
+	}
+	def, ok := lookupUnit(input[end:], rate)
+	if !ok {
+		return Quantity{}, fmt.Errorf("unknown bandwidth unit %q", strings.TrimSpace(input[end:]))
+	}
+	total := amount * float64(def.Bytes)
+	if total > math.MaxInt64 {
+		return Quantity{}, errors.New("bandwidth value too large")
+	}
+	return Quantity{Bytes: int64(math.Round(total)), Unit: def.Name, Rate: rate}, nil
+}
+
+// String formats the quantity back in the unit it was parsed from
+func (q Quantity) String() string {
+	def, ok := lookupUnit(q.Unit, q.Rate)
+	if !ok {
+		return FormatBytes(q.Bytes)
+	}
+	if q.Bytes%def.Bytes == 0 {
+		return fmt.Sprintf("%d %s", q.Bytes/def.Bytes, def.Name)
+	}
+	return strconv.FormatFloat(float64(q.Bytes)/float64(def.Bytes), 'f', -1, 64) + " " + def.Name
+}
+
+// IsBits reports whether the quantity was written in a bit unit
+func (q Quantity) IsBits() bool {
+	def, ok := lookupUnit(q.Unit, q.Rate)
+	return ok && def.Bits
+}
+
+// FormatBytes renders a size in the largest byte unit that divides it exactly,
+// so the value survives a round trip through torrc unchanged
+func FormatBytes(n int64) string {
+	for i := len(byteUnits) - 1; i > 0; i-- {
+		if u := byteUnits[i]; n >= u.Bytes && n%u.Bytes == 0 {
+			return fmt.Sprintf("%d %s", n/u.Bytes, u.Name)
+		}
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// FormatRate renders bytes/sec in the largest bit or byte unit not exceeding it,
+// rounded down to two decimals so the result never exceeds the input
+func FormatRate(bytesPerSec int64, bits bool) string {
+	table := byteUnits
+	if bits {
+		table = rateBitUnits
+	}
+	for i := len(table) - 1; i >= 0; i-- {
+		u := table[i]
+		if bytesPerSec < u.Bytes && i > 0 {
+			continue
+		}
+		if bytesPerSec%u.Bytes == 0 {
+			return fmt.Sprintf("%d %s", bytesPerSec/u.Bytes, u.Name)
+		}
+		v := math.Floor(float64(bytesPerSec)/float64(u.Bytes)*100) / 100
+		return strconv.FormatFloat(v, 'f', -1, 64) + " " + u.Name
+	}
+	return fmt.Sprintf("%d bytes", bytesPerSec)
+}
@@ -0,0 +1,174 @@
+package bandwidth
+
+import "testing"
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		in      string
+		bytes   int64
+		unit    string
+		wantErr bool
+	}{
+		{in: "4096", bytes: 4096, unit: "bytes"},
+		{in: "1 byte", bytes: 1, unit: "bytes"},
+		{in: "1 KB", bytes: 1 << 10, unit: "KBytes"},
+		{in: "10 megabytes", bytes: 10 << 20, unit: "MBytes"},
+		{in: "2 m", bytes: 2 << 20, unit: "MBytes"},
+		{in: "1.5 GBytes", bytes: 3 << 29, unit: "GBytes"},
+		{in: "1 terabyte", bytes: 1 << 40, unit: "TBytes"},
+		{in: "8 KBits", bytes: 8 << 7, unit: "KBits"},
+		{in: "1 megabit", bytes: 1 << 17, unit: "MBits"},
+		{in: "1 gbit", bytes: 1 << 27, unit: "GBits"},
+		{in: "1 tbits", bytes: 1 << 37, unit: "TBits"},
+		{in: "5 MB/s", wantErr: true},
+		{in: "5 MiB", wantErr: true},
+		{in: "MBytes", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBytes(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBytes(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (got.Bytes != tt.bytes || got.Unit != tt.unit || got.Rate) {
+			t.Errorf("ParseBytes(%q) = %+v, want %d %s", tt.in, got, tt.bytes, tt.unit)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		bytes   int64
+		unit    string
+		wantErr bool
+	}{
+		{in: "0", bytes: 0, unit: "bytes"},
+		{in: "5 MBytes", bytes: 5 << 20, unit: "MBytes"},
+		{in: "100 kb", bytes: 100 << 10, unit: "KBytes"},
+		{in: "1 GB", bytes: 1 << 30, unit: "GBytes"},
+		{in: "1 KBits", bytes: 125, unit: "KBits"},
+		{in: "800 kilobits", bytes: 800 * 125, unit: "KBits"},
+		{in: "100 MBits", bytes: 100 * 125000, unit: "MBits"},
+		{in: "1 gigabit", bytes: 125000000, unit: "GBits"},
+		{in: "1 tbit", bytes: 125000000000, unit: "TBits"},
+		{in: "5 MB/s", wantErr: true},
+		{in: "800 KBits/s", wantErr: true},
+		{in: "2 m", wantErr: true},
+		{in: "1 kbps", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (got.Bytes != tt.bytes || got.Unit != tt.unit || !got.Rate) {
+			t.Errorf("ParseRate(%q) = %+v, want %d %s", tt.in, got, tt.bytes, tt.unit)
+		}
+	}
+}
+
+// Bit units mean different byte counts in tor's size and rate tables
+func TestBitUnitsSizeVsRate(t *testing.T) {
+	size, err := ParseBytes("1 KBits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rate, err := ParseRate("1 KBits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size.Bytes != 1<<7 || rate.Bytes != 125 {
+		t.Errorf("1 KBits = %d bytes as a size and %d as a rate, want 128 and 125", size.Bytes, rate.Bytes)
+	}
+	if !size.IsBits() || !rate.IsBits() {
+		t.Error("bit units not reported as bits")
+	}
+	if b, _ := ParseRate("1 KBytes"); b.IsBits() {
+		t.Error("byte unit reported as bits")
+	}
+}
+
+func TestQuantityRoundTrip(t *testing.T) {
+	tests := []struct {
+		in   string
+		rate bool
+		want string
+	}{
+		{"5 mb", true, "5 MBytes"},
+		{"800 kbits", true, "800 KBits"},
+		{"1.5 GBytes", true, "1.5 GBytes"},
+		{"10 megabits", false, "10 MBits"},
+		{"2 m", false, "2 MBytes"},
+		{"4096", false, "4096 bytes"},
+		{"1 terabytes", false, "1 TBytes"},
+	}
+	for _, tt := range tests {
+		parse := ParseBytes
+		if tt.rate {
+			parse = ParseRate
+		}
+		q, err := parse(tt.in)
+		if err != nil {
+			t.Errorf("parse %q: %v", tt.in, err)
+			continue
+		}
+		if got := q.String(); got != tt.want {
+			t.Errorf("%q formatted as %q, want %q", tt.in, got, tt.want)
+		}
+		again, err := parse(q.String())
+		if err != nil || again != q {
+			t.Errorf("%q does not survive a round trip: %+v, %v", tt.in, again, err)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 bytes"},
+		{1000, "1000 bytes"},
+		{1 << 10, "1 KBytes"},
+		{3 << 19, "1536 KBytes"},
+		{500 << 30, "500 GBytes"},
+		{2 << 40, "2 TBytes"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+		if q, err := ParseBytes(tt.want); err != nil || q.Bytes != tt.n {
+			t.Errorf("ParseBytes(%q) = %+v, %v, want %d bytes", tt.want, q, err, tt.n)
+		}
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	tests := []struct {
+		n    int64
+		bits bool
+		want string
+	}{
+		{125, true, "1 KBits"},
+		{125000, true, "1 MBits"},
+		{12500000, true, "100 MBits"},
+		{128, true, "1.02 KBits"},
+		{5 << 20, false, "5 MBytes"},
+		{1536, false, "1.5 KBytes"},
+		{100, false, "100 bytes"},
+	}
+	for _, tt := range tests {
+		got := FormatRate(tt.n, tt.bits)
+		if got != tt.want {
+			t.Errorf("FormatRate(%d, %v) = %q, want %q", tt.n, tt.bits, got, tt.want)
+			continue
+		}
+		q, err := ParseRate(got)
+		if err != nil || q.Bytes > tt.n {
+			t.Errorf("ParseRate(%q) = %+v, %v; must not exceed %d", got, q, err, tt.n)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// File: internal/config/drift.go
+// Purpose: Compare torrc on disk with tor's running configuration
+
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"tor-admin/internal/bandwidth"
+)
+
+const (
+	DriftUnsaved       = "unsaved"        // changed in the running tor (e.g. SETCONF) but not on disk
+	DriftPendingReload = "pending_reload" // torrc edited since tor last loaded it
+	DriftDiffers       = "differs"        // both changed, or no record of what tor loaded
+)
+
+// Drift is one option whose disk and running values differ
+type Drift struct {
+	Key  string   `json:"key"`
+	Kind string   `json:"kind"`
+	Disk []string `json:"disk"`
+	Live []string `json:"live"`
+}
+
+// CheckDrift compares every known option in tc with the running values from
+// GETCONF. Options missing from the torrc are compared against their default.
+// loaded is the torrc as tor last (re)loaded it, or nil when that is unknown;
+// each differing key is classified by which side moved away from it.
+func CheckDrift(tc, loaded *TorConfig, live map[string][]string) []Drift {
+	drifts := []Drift{}
+	for _, opt := range GetAllOptions() {
+		running, ok := liveValues(live, opt.Name)
+		if !ok {
+			continue // tor did not report it (unknown to this version)
+		}
+		disk := diskValues(tc, opt, running)
+		if sameValues(opt, disk, running) {
+			continue
+		}
+		kind := DriftDiffers
+		if loaded != nil {
+			was := diskValues(loaded, opt, running)
+			switch {
+			case sameValues(opt, disk, was):
+				kind = DriftUnsaved
+			case sameValues(opt, was, running):
+				kind = DriftPendingReload
+			}
+		}
+		drifts = append(drifts, Drift{Key: opt.Name, Kind: kind, Disk: nonNil(disk), Live: nonNil(running)})
+	}
+	return drifts
+}
+
+// diskValues returns tc's values for opt as tor would apply them
+func diskValues(tc *TorConfig, opt TorOption, running []string) []string {
+	disk := tc.GetAll(opt.Name)
+	// tor keeps the last value of a single-valued option; several running
+	// values mean tor treats it as a list (e.g. more than one SocksPort)
+	if !opt.Multiple && len(running) <= 1 && len(disk) > 1 {
+		disk = disk[len(disk)-1:]
+	}
+	return disk
+}
+
+// liveValues finds name in a GETCONF result regardless of how it was spelled
+func liveValues(live map[string][]string, name string) ([]string, bool) {
+	if v, ok := live[name]; ok {
+		return v, true
+	}
+	for k, v := range live {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func sameValues(opt TorOption, disk, live []string) bool {
+	if len(disk) == 0 {
+		disk = []string{opt.Default}
+	}
+	if len(live) == 0 {
+		live = []string{opt.Default}
+	}
+	norm := func(vs []string) []string {
+		out := make([]string, 0, len(vs))
+		for _, v := range vs {
+			if n := normalizeValue(opt, v); n != "" {
+				out = append(out, n)
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+	a, b := norm(disk), norm(live)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeValue maps equivalent spellings ("1 GB" vs "1073741824") to one form
+func normalizeValue(opt TorOption, v string) string {
+	v = strings.Join(strings.Fields(v), " ")
+	switch {
+	case rateOptions[opt.Name]:
+		if q, err := bandwidth.ParseRate(v); err == nil {
+			return "bytes:" + bandwidth.FormatBytes(q.Bytes)
+		}
+	case sizeOptions[opt.Name]:
+		if q, err := bandwidth.ParseBytes(v); err == nil {
+			return "bytes:" + bandwidth.FormatBytes(q.Bytes)
+		}
+	}
+	if opt.Type == TypeBool {
+		switch strings.ToLower(v) {
+		case "1", "true", "yes":
+			return "1"
+		case "0", "false", "no":
+			return "0"
+		}
+	}
+	return strings.ToLower(v)
+}
+
+func nonNil(vs []string) []string {
+	if vs == nil {
+		return []string{}
+	}
+	return vs
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseTorrc(text string) *TorConfig {
+	tc := &TorConfig{}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		tc.Entries = append(tc.Entries, parseLine(line))
+	}
+	return tc
+}
+
+func TestCheckDrift(t *testing.T) {
+	tests := []struct {
+		name  string
+		torrc string
+		live  map[string][]string
+		want  []string // drifting keys
+	}{
+		{
+			name: "repeated hidden service lines",
+			torrc: `HiddenServiceDir /var/lib/tor/a
+HiddenServicePort 80 127.0.0.1:8080
+HiddenServiceDir /var/lib/tor/b
+HiddenServicePort 80 127.0.0.1:8081
+HiddenServicePort 443 127.0.0.1:8443`,
+			live: map[string][]string{
+				"HiddenServiceDir":  {"/var/lib/tor/a", "/var/lib/tor/b"},
+				"HiddenServicePort": {"80 127.0.0.1:8080", "80 127.0.0.1:8081", "443 127.0.0.1:8443"},
+			},
+		},
+		{
+			name:  "hidden service port removed live",
+			torrc: "HiddenServiceDir /var/lib/tor/a\nHiddenServicePort 80 127.0.0.1:8080\nHiddenServicePort 443 127.0.0.1:8443",
+			live: map[string][]string{
+				"HiddenServiceDir":  {"/var/lib/tor/a"},
+				"HiddenServicePort": {"80 127.0.0.1:8080"},
+			},
+			want: []string{"HiddenServicePort"},
+		},
+		{
+			name:  "several log lines",
+			torrc: "Log notice file /var/log/tor/notices.log\nLog warn syslog",
+			live:  map[string][]string{"Log": {"notice file /var/log/tor/notices.log", "warn syslog"}},
+		},
+		{
+			name:  "repeated single-valued option keeps the last",
+			torrc: "SafeLogging 0\nSafeLogging 1",
+			live:  map[string][]string{"SafeLogging": {"1"}},
+		},
+		{
+			name:  "several socks ports",
+			torrc: "SocksPort 9050\nSocksPort 9150",
+			live:  map[string][]string{"SocksPort": {"9050", "9150"}},
+		},
+		{
+			name:  "size in bits uses binary bit units",
+			torrc: "AccountingMax 8 GBits",
+			live:  map[string][]string{"AccountingMax": {"1073741824"}},
+		},
+		{
+			name:  "rate in bits uses SI bit units",
+			torrc: "BandwidthRate 8 MBits",
+			live:  map[string][]string{"BandwidthRate": {"1000000"}},
+		},
+		{
+			name:  "accounting max changed live",
+			torrc: "AccountingMax 1 GBytes",
+			live:  map[string][]string{"AccountingMax": {"2147483648"}},
+			want:  []string{"AccountingMax"},
+		},
+		{
+			name:  "option names are case-insensitive",
+			torrc: "socksport 9052\nexitrelay 1",
+			live:  map[string][]string{"SocksPort": {"9052"}, "exitrelay": {"1"}},
+		},
+		{
+			name:  "missing option compares against default",
+			torrc: "# nothing set",
+			live:  map[string][]string{"SafeLogging": {"1"}, "ExitRelay": {"1"}},
+			want:  []string{"ExitRelay"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := parseTorrc(tt.torrc)
+			var got []string
+			for _, d := range CheckDrift(tc, tc, tt.live) {
+				got = append(got, d.Key)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("drifting keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDriftKinds(t *testing.T) {
+	loaded := parseTorrc("SocksPort 9050\nExitRelay 0\nSafeLogging 1")
+	disk := parseTorrc("SocksPort 9052\nExitRelay 0\nSafeLogging 0")
+	live := map[string][]string{
+		"SocksPort":   {"9050"}, // torrc edited, not reloaded
+		"ExitRelay":   {"1"},    // SETCONF, not saved
+		"SafeLogging": {"relay"},
+	}
+	want := map[string]string{
+		"SocksPort":   DriftPendingReload,
+		"ExitRelay":   DriftUnsaved,
+		"SafeLogging": DriftDiffers, // changed on both sides
+	}
+	check := func(loaded *TorConfig, want map[string]string) {
+		t.Helper()
+		got := map[string]string{}
+		for _, d := range CheckDrift(disk, loaded, live) {
+			got[d.Key] = d.Kind
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("kinds = %v, want %v", got, want)
+		}
+	}
+	check(loaded, want)
+	// without a record of what tor loaded, no key can be classified
+	check(nil, map[string]string{"SocksPort": DriftDiffers, "ExitRelay": DriftDiffers, "SafeLogging": DriftDiffers})
+}
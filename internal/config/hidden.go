@@ -0,0 +1,175 @@
+// File: internal/config/hidden.go
+// Purpose: Read and edit HiddenServiceDir blocks (a Dir line plus the HiddenService* lines after it)
+
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// HiddenServiceBlock is one onion service as configured in the torrc
+type HiddenServiceBlock struct {
+	Dir     string
+	Start   int // index of the HiddenServiceDir entry
+	End     int // index just past the block's last option
+	Options []TorConfigEntry
+}
+
+// Get returns the first value of an option inside the block
+func (b HiddenServiceBlock) Get(key string) (string, bool) {
+	for _, e := range b.Options {
+		if strings.EqualFold(e.Key, key) {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
+
+// GetAll returns every value of a repeatable option (e.g. HiddenServicePort)
+func (b HiddenServiceBlock) GetAll(key string) []string {
+	var values []string
+	for _, e := range b.Options {
+		if strings.EqualFold(e.Key, key) {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+// HiddenServices returns every HiddenServiceDir block in file order
+func (tc *TorConfig) HiddenServices() []HiddenServiceBlock {
+	var blocks []HiddenServiceBlock
+	var cur *HiddenServiceBlock
+	for i, e := range tc.Entries {
+		if e.IsComment {
+			continue
+		}
+		switch {
+		case strings.EqualFold(e.Key, "HiddenServiceDir"):
+			if cur != nil {
+				blocks = append(blocks, *cur)
+			}
+			cur = &HiddenServiceBlock{Dir: e.Value, Start: i, End: i + 1}
+		case cur != nil && isHiddenServiceKey(e.Key):
+			cur.Options = append(cur.Options, e)
+			cur.End = i + 1
+		default:
+			if cur != nil {
+				blocks = append(blocks, *cur)
+				cur = nil
+			}
+		}
+	}
+	if cur != nil {
+		blocks = append(blocks, *cur)
+	}
+	return blocks
+}
+
+// HiddenService returns the block for dir, if configured
+func (tc *TorConfig) HiddenService(dir string) (*HiddenServiceBlock, bool) {
+	for _, b := range tc.HiddenServices() {
+		if b.Dir == dir {
+			return &b, true
+		}
+	}
+	return nil, false
+}
+
+// AddHiddenService appends a new block with the given "virtport target" mappings
+func (tc *TorConfig) AddHiddenService(dir string, ports []string) error {
+	if _, ok := tc.HiddenService(dir); ok {
+		return errors.New("hidden service directory already configured")
+	}
+	if len(ports) == 0 {
+		return errors.New("at least one HiddenServicePort is required")
+	}
+	for _, p := range ports {
+		if err := ValidatePortMapping(p); err != nil {
+			return err
+		}
+	}
+	tc.Entries = append(tc.Entries, TorConfigEntry{RawLine: "", IsComment: true}, entry("HiddenServiceDir", dir))
+	for _, p := range ports {
+		tc.Entries = append(tc.Entries, entry("HiddenServicePort", p))
+	}
+	return nil
+}
+
+// isHiddenServiceKey reports whether key is a HiddenService* option; tor
+// matches option names case-insensitively
+func isHiddenServiceKey(key string) bool {
+	const prefix = "HiddenService"
+	return len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix)
+}
+
+func entry(key, value string) TorConfigEntry {
+	return TorConfigEntry{Key: key, Value: value, RawLine: key + " " + value}
+}
+
+// Lines returns the block's torrc lines, starting with HiddenServiceDir
+func (b HiddenServiceBlock) Lines() []string {
+	lines := []string{"HiddenServiceDir " + b.Dir}
+	for _, e := range b.Options {
+		lines = append(lines, e.Key+" "+e.Value)
+	}
+	return lines
+}
+
+// RestoreHiddenService appends a block from lines produced by Lines
+func (tc *TorConfig) RestoreHiddenService(lines []string) error {
+	if len(lines) == 0 {
+		return errors.New("empty hidden service block")
+	}
+	first := parseLine(lines[0])
+	if !strings.EqualFold(first.Key, "HiddenServiceDir") {
+		return errors.New("hidden service block must start with HiddenServiceDir")
+	}
+	if _, ok := tc.HiddenService(first.Value); ok {
+		return errors.New("hidden service directory already configured")
+	}
+	entries := []TorConfigEntry{{RawLine: "", IsComment: true}, entry(first.Key, first.Value)}
+	for _, l := range lines[1:] {
+		e := parseLine(l)
+		if e.IsComment || !isHiddenServiceKey(e.Key) || strings.EqualFold(e.Key, "HiddenServiceDir") {
+			return errors.New("unexpected line in hidden service block: " + l)
+		}
+		entries = append(entries, entry(e.Key, e.Value))
+	}
+	tc.Entries = append(tc.Entries, entries...)
+	return nil
+}
+
+// SetHiddenServiceOption sets a single-valued option inside dir's block,
+// replacing an existing line or adding one at the end of the block
+func (tc *TorConfig) SetHiddenServiceOption(dir, key, value string) error {
+	b, ok := tc.HiddenService(dir)
+	if !ok {
+		return errors.New("not a configured HiddenServiceDir")
+	}
+	for i := b.Start + 1; i < b.End; i++ {
+		if !tc.Entries[i].IsComment && strings.EqualFold(tc.Entries[i].Key, key) {
+			tc.Entries[i] = entry(key, value)
+			return nil
+		}
+	}
+	tc.Entries = append(tc.Entries[:b.End], append([]TorConfigEntry{entry(key, value)}, tc.Entries[b.End:]...)...)
+	return nil
+}
+
+// UnsetHiddenServiceOption removes every line of key from dir's block
+func (tc *TorConfig) UnsetHiddenServiceOption(dir, key string) error {
+	b, ok := tc.HiddenService(dir)
+	if !ok {
+		return errors.New("not a configured HiddenServiceDir")
+	}
+	kept := append([]TorConfigEntry(nil), tc.Entries[:b.Start+1]...)
+	for i := b.Start + 1; i < b.End; i++ {
+		if tc.Entries[i].IsComment || !strings.EqualFold(tc.Entries[i].Key, key) {
+			kept = append(kept, tc.Entries[i])
+		}
+	}
+	tc.Entries = append(kept, tc.Entries[b.End:]...)
+	return nil
+}
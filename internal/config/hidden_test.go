@@ -0,0 +1,37 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHiddenServicesCaseInsensitive(t *testing.T) {
+	tc := parseTorrc(`hiddenservicedir /var/lib/tor/a
+hiddenserviceport 80 127.0.0.1:8080
+HIDDENSERVICEVERSION 3
+SocksPort 9050
+HiddenServiceDir /var/lib/tor/b
+HiddenServicePort 80 127.0.0.1:8081`)
+	blocks := tc.HiddenServices()
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+	if got := blocks[0].GetAll("HiddenServicePort"); !reflect.DeepEqual(got, []string{"80 127.0.0.1:8080"}) {
+		t.Errorf("ports of a = %v", got)
+	}
+	if v, ok := blocks[0].Get("HiddenServiceVersion"); !ok || v != "3" {
+		t.Errorf("version of a = %q, %v", v, ok)
+	}
+	if err := tc.RestoreHiddenService([]string{"HIDDENSERVICEDIR /var/lib/tor/c", "hiddenserviceport 80 127.0.0.1:8082"}); err != nil {
+		t.Fatalf("RestoreHiddenService: %v", err)
+	}
+	if err := tc.RestoreHiddenService([]string{"HiddenServiceDir /var/lib/tor/d", "hiddenservicedir /var/lib/tor/e"}); err == nil {
+		t.Error("a second HiddenServiceDir inside a block should be rejected")
+	}
+	if err := tc.UnsetHiddenServiceOption("/var/lib/tor/a", "HiddenServicePort"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := tc.HiddenService("/var/lib/tor/a"); len(b.GetAll("HiddenServicePort")) != 0 {
+		t.Errorf("hiddenserviceport line was not removed: %v", b.Options)
+	}
+}
@@ -45,13 +45,41 @@ var allTorOptions = []TorOption{
 		Name: "BandwidthBurst", Type: TypeString, Default: "1 MB", Description: "Bandwidth burst",
 		Category: "Bandwidth", InputType: "text", Placeholder: "10 MB", Resettable: true,
 	},
+	{
+		Name: "RelayBandwidthRate", Type: TypeString, Default: "0", Description: "Rate limit for relayed traffic only",
+		Category: "Bandwidth", InputType: "text", Placeholder: "500 KBytes", Resettable: true,
+	},
+	{
+		Name: "RelayBandwidthBurst", Type: TypeString, Default: "0", Description: "Burst limit for relayed traffic only",
+		Category: "Bandwidth", InputType: "text", Placeholder: "1 MBytes", Resettable: true,
+	},
+	{
+		Name: "AccountingMax", Type: TypeString, Default: "0", Description: "Traffic allowed per accounting period before hibernating",
+		Category: "Accounting", InputType: "text", Placeholder: "500 GBytes", Resettable: true,
+	},
+	{
+		Name: "AccountingStart", Type: TypeString, Default: "month 1 00:00", Description: "When each accounting period begins",
+		Category: "Accounting", InputType: "text", Placeholder: "month 1 00:00", Resettable: true,
+	},
+	{
+		Name: "AccountingRule", Type: TypeString, Default: "max", Description: "Which traffic counts toward AccountingMax",
+		Category: "Accounting", InputType: "select", Choices: []string{"sum", "max", "in", "out"}, Resettable: true,
+	},
 	{
 		Name: "HiddenServiceDir", Type: TypeString, Default: "", Description: "Hidden Service directory",
-		Category: "Hidden Services", InputType: "text", Placeholder: "/var/lib/tor/hs1", Resettable: true,
+		Category: "Hidden Services", Multiple: true, InputType: "text", Placeholder: "/var/lib/tor/hs1", Resettable: true,
 	},
 	{
 		Name: "HiddenServicePort", Type: TypeString, Default: "", Description: "Map virtual port to target address",
-		Category: "Hidden Services", InputType: "text", Placeholder: "80 127.0.0.1:8080", Resettable: true,
+		Category: "Hidden Services", Multiple: true, InputType: "text", Placeholder: "80 127.0.0.1:8080", Resettable: true,
+	},
+	{
+		Name: "MetricsPort", Type: TypeString, Default: "", Description: "Expose tor's own Prometheus metrics on this address",
+		Category: "Metrics", InputType: "text", Placeholder: "127.0.0.1:9035 prometheus", Resettable: true,
+	},
+	{
+		Name: "MetricsPortPolicy", Type: TypeString, Default: "", Description: "Who may connect to MetricsPort (accept/reject address policy)",
+		Category: "Metrics", Multiple: true, InputType: "text", Placeholder: "accept 127.0.0.1", Resettable: true,
 	},
 	{
 		Name: "ExitRelay", Type: TypeBool, Default: "0", Description: "Advertise as an exit node",
@@ -63,7 +91,7 @@ var allTorOptions = []TorOption{
 	},
 	{
 		Name: "Log", Type: TypeString, Default: "notice stdout", Description: "Log level and target",
-		Category: "Logging", InputType: "text", Placeholder: "notice stdout", Resettable: true,
+		Category: "Logging", Multiple: true, InputType: "text", Placeholder: "notice stdout", Resettable: true,
 	},
 }
 
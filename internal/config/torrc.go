@@ -21,6 +21,14 @@ type TorConfig struct {
 	Entries []TorConfigEntry
 }
 
+// TorrcPath returns the torrc location from TORRC_PATH, defaulting to /etc/tor/torrc
+func TorrcPath() string {
+	if p := os.Getenv("TORRC_PATH"); p != "" {
+		return p
+	}
+	return "/etc/tor/torrc"
+}
+
 // LoadTorrc loads the torrc file and parses it into structured entries
 func LoadTorrc(path string) (*TorConfig, error) {
 	file, err := os.Open(path)
@@ -59,20 +67,32 @@ func parseLine(line string) TorConfigEntry {
 	}
 }
 
-// Get returns the value of a given config key, if present
+// Get returns the value of a given config key, if present. Like tor, option
+// names match case-insensitively.
 func (tc *TorConfig) Get(key string) (string, bool) {
 	for _, e := range tc.Entries {
-		if !e.IsComment && e.Key == key {
+		if !e.IsComment && strings.EqualFold(e.Key, key) {
 			return e.Value, true
 		}
 	}
 	return "", false
 }
 
+// GetAll returns every value of a key that may repeat (e.g. HiddenServicePort)
+func (tc *TorConfig) GetAll(key string) []string {
+	var values []string
+	for _, e := range tc.Entries {
+		if !e.IsComment && strings.EqualFold(e.Key, key) {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
 // Set updates or appends a key/value pair
 func (tc *TorConfig) Set(key string, value string) {
 	for i, e := range tc.Entries {
-		if !e.IsComment && e.Key == key {
+		if !e.IsComment && strings.EqualFold(e.Key, key) {
 			tc.Entries[i].Value = value
 			tc.Entries[i].RawLine = key + " " + value
 			return
@@ -85,6 +105,17 @@ func (tc *TorConfig) Set(key string, value string) {
 	})
 }
 
+// Unset removes every line setting key so tor falls back to its default
+func (tc *TorConfig) Unset(key string) {
+	kept := tc.Entries[:0]
+	for _, e := range tc.Entries {
+		if e.IsComment || !strings.EqualFold(e.Key, key) {
+			kept = append(kept, e)
+		}
+	}
+	tc.Entries = kept
+}
+
 // Save writes the config back to file, preserving formatting
 func (tc *TorConfig) Save(path string) error {
 	backup := path + ".bak"
@@ -110,7 +141,7 @@ func (tc *TorConfig) Save(path string) error {
 func (tc *TorConfig) GetHiddenServiceDirs() ([]string, error) {
 	var dirs []string
 	for _, e := range tc.Entries {
-		if !e.IsComment && e.Key == "HiddenServiceDir" {
+		if !e.IsComment && strings.EqualFold(e.Key, "HiddenServiceDir") {
 			dirs = append(dirs, e.Value)
 		}
 	}
@@ -5,29 +5,98 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
+
+	"tor-admin/internal/bandwidth"
 )
 
-// ValidateBandwidth ensures the bandwidth string is numeric and has a valid suffix
+// ValidateBandwidth ensures the value is a rate tor accepts (e.g. 5 MBytes, 800 KBits, 1 GB)
 func ValidateBandwidth(value string) error {
-	value = strings.ToUpper(strings.TrimSpace(value))
-	validSuffixes := []string{"KB", "MB", "GB", "TB"}
-	for _, suffix := range validSuffixes {
-		if strings.HasSuffix(value, suffix) {
-			n := strings.TrimSuffix(value, suffix)
-			n = strings.TrimSpace(n)
-			if _, err := strconv.Atoi(n); err != nil {
-				return errors.New("invalid numeric bandwidth value")
+	_, err := bandwidth.ParseRate(value)
+	return err
+}
+
+// ValidateByteSize ensures the value is a size tor accepts (e.g. AccountingMax 500 GBytes)
+func ValidateByteSize(value string) error {
+	_, err := bandwidth.ParseBytes(value)
+	return err
+}
+
+// rateOptions take a bandwidth rate and sizeOptions a byte size; tor reads
+// bit units differently in each
+var (
+	rateOptions = map[string]bool{"BandwidthRate": true, "BandwidthBurst": true, "RelayBandwidthRate": true, "RelayBandwidthBurst": true}
+	sizeOptions = map[string]bool{"AccountingMax": true}
+)
+
+// ValidateOption checks a value against the option's type before it is saved
+func ValidateOption(opt TorOption, value string) error {
+	err := validateOptionValue(opt, strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("%s: %w", opt.Name, err)
+	}
+	return nil
+}
+
+func validateOptionValue(opt TorOption, value string) error {
+	switch {
+	case rateOptions[opt.Name]:
+		return ValidateBandwidth(value)
+	case sizeOptions[opt.Name]:
+		return ValidateByteSize(value)
+	case opt.Name == "AccountingStart":
+		_, err := bandwidth.ParseAccountingStart(value)
+		return err
+	case opt.Name == "HiddenServiceDir":
+		return ValidateOnionDir(value)
+	case opt.Name == "HiddenServicePort":
+		return ValidatePortMapping(value)
+	case len(opt.Choices) > 0:
+		for _, c := range opt.Choices {
+			if strings.EqualFold(c, value) {
+				return nil
 			}
-			return nil
+		}
+		return errors.New("must be one of " + strings.Join(opt.Choices, ", "))
+	case opt.Type == TypeBool:
+		if value != "0" && value != "1" {
+			return errors.New("must be 0 or 1")
+		}
+	case opt.Type == TypeInt && strings.HasSuffix(opt.Name, "Port"):
+		return validatePortSpec(value)
+	case opt.Type == TypeInt:
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return errors.New("must be a non-negative number")
 		}
 	}
-	return errors.New("invalid bandwidth format (e.g. 5MB, 100KB)")
+	return nil
+}
+
+// validatePortSpec accepts what SocksPort/ControlPort take before any flags:
+// "auto", a port, "addr:port" or "unix:/path"
+func validatePortSpec(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return errors.New("missing port")
+	}
+	spec := fields[0]
+	if spec == "auto" || strings.HasPrefix(spec, "unix:/") {
+		return nil
+	}
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		spec = spec[i+1:]
+	}
+	if n, err := strconv.Atoi(spec); err != nil || n < 0 || n > 65535 {
+		return errors.New("invalid port")
+	}
+	return nil
 }
 
 // ValidatePortMapping checks that the value is of form: "80 127.0.0.1:8080"
+// or "80 unix:/path/to/socket"
 func ValidatePortMapping(value string) error {
 	parts := strings.Fields(value)
 	if len(parts) != 2 {
@@ -36,6 +105,12 @@ func ValidatePortMapping(value string) error {
 	if _, err := strconv.Atoi(parts[0]); err != nil {
 		return errors.New("invalid virtual port")
 	}
+	if path, ok := strings.CutPrefix(parts[1], "unix:"); ok {
+		if !strings.HasPrefix(path, "/") {
+			return errors.New("unix socket target must be an absolute path")
+		}
+		return nil
+	}
 	addrParts := strings.Split(parts[1], ":")
 	if len(addrParts) != 2 {
 		return errors.New("invalid address format")
@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+func TestValidateBandwidthUsesTorSpellings(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"5 MBytes", false},
+		{"800 KBits", false},
+		{"1 GB", false},
+		{"5 MB/s", true},
+		{"2 m", true},
+		{"fast", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateBandwidth(tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateBandwidth(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+	if err := ValidateByteSize("2 m"); err != nil {
+		t.Errorf("ValidateByteSize(\"2 m\") = %v; tor accepts m for sizes", err)
+	}
+}
@@ -0,0 +1,241 @@
+// File: internal/instance/instance.go
+// Purpose: Tor instances on this host (default + Debian tor-instance-create + explicit), each with its own torrc, control port, unit and data dir
+
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"tor-admin/internal/config"
+	"tor-admin/internal/torctl"
+)
+
+// DefaultID names the instance configured through TORRC_PATH/TOR_CONTROL_ADDR
+const DefaultID = "default"
+
+// InstancesDir is where Debian's tor-instance-create puts per-instance torrcs
+var InstancesDir = "/etc/tor/instances"
+
+// Instance is one tor daemon
+type Instance struct {
+	ID          string `json:"id"`
+	Torrc       string `json:"torrc"`
+	ControlAddr string `json:"control_addr"` // "127.0.0.1:9051" or "unix:/run/tor/control"
+	Password    string `json:"password,omitempty"`
+	Unit        string `json:"unit"`
+	DataDir     string `json:"data_dir"`
+}
+
+// Dial connects and authenticates to the instance's control port
+func (i *Instance) Dial() (*torctl.Conn, error) {
+	return torctl.DialAuth(i.ControlAddr, i.Password)
+}
+
+// Public is the instance without its control password, for API responses
+func (i *Instance) Public() Instance {
+	p := *i
+	p.Password = ""
+	return p
+}
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+var (
+	mu        sync.RWMutex
+	instances = map[string]*Instance{}
+)
+
+// Default describes the instance set by the environment
+func Default() *Instance {
+	addr := os.Getenv("TOR_CONTROL_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:9051"
+	}
+	unit := os.Getenv("TOR_SERVICE_NAME")
+	if unit == "" {
+		unit = "tor"
+	}
+	inst := &Instance{
+		ID:          DefaultID,
+		Torrc:       config.TorrcPath(),
+		ControlAddr: addr,
+		Password:    os.Getenv("TOR_CONTROL_PASSWORD"),
+		Unit:        unit,
+	}
+	fillFromTorrc(inst, "/var/lib/tor")
+	return inst
+}
+
+// Load discovers instances and merges the explicit ones stored at path (a
+// JSON array of Instance); explicit entries override discovered fields
+func Load(path string) error {
+	found := map[string]*Instance{DefaultID: Default()}
+	for _, inst := range discoverDebian(InstancesDir) {
+		found[inst.ID] = inst
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		var explicit []Instance
+		if err := json.Unmarshal(data, &explicit); err != nil {
+			return err
+		}
+		for _, e := range explicit {
+			if !idPattern.MatchString(e.ID) {
+				return errors.New("invalid instance id " + e.ID)
+			}
+			inst := merge(found[e.ID], e)
+			if inst.Torrc == "" {
+				return errors.New("instance " + e.ID + " has no torrc")
+			}
+			found[e.ID] = inst
+		}
+	}
+
+	mu.Lock()
+	instances = found
+	mu.Unlock()
+	return nil
+}
+
+// discoverDebian finds /etc/tor/instances/<name>/torrc, which Debian runs as
+// tor@<name> with its data in /var/lib/tor-instances/<name>
+func discoverDebian(dir string) []*Instance {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var out []*Instance
+	for _, e := range entries {
+		name := e.Name()
+		torrc := filepath.Join(dir, name, "torrc")
+		if !e.IsDir() || name == DefaultID || !idPattern.MatchString(name) {
+			continue
+		}
+		if _, err := os.Stat(torrc); err != nil {
+			continue
+		}
+		inst := &Instance{ID: name, Torrc: torrc, Unit: "tor@" + name}
+		fillFromTorrc(inst, "/var/lib/tor-instances/"+name)
+		if inst.ControlAddr == "" {
+			// Debian's instance defaults put a control socket here
+			inst.ControlAddr = "unix:/run/tor-instances/" + name + "/control"
+		}
+		out = append(out, inst)
+	}
+	return out
+}
+
+// merge applies the non-empty fields of e over the discovered instance;
+// what is still missing comes from the instance's torrc
+func merge(base *Instance, e Instance) *Instance {
+	m := Instance{ID: e.ID, Unit: "tor@" + e.ID}
+	if base != nil {
+		m = *base
+	}
+	if e.Torrc != "" && e.Torrc != m.Torrc {
+		// A different torrc names its own control port and data dir
+		m.Torrc, m.ControlAddr, m.DataDir = e.Torrc, "", ""
+	}
+	if e.ControlAddr != "" {
+		m.ControlAddr = e.ControlAddr
+	}
+	if e.Password != "" {
+		m.Password = e.Password
+	}
+	if e.Unit != "" {
+		m.Unit = e.Unit
+	}
+	if e.DataDir != "" {
+		m.DataDir = e.DataDir
+	}
+	fillFromTorrc(&m, "/var/lib/tor-"+e.ID)
+	if m.ControlAddr == "" {
+		m.ControlAddr = "127.0.0.1:9051"
+	}
+	return &m
+}
+
+// fillFromTorrc takes the control address and data directory from the
+// torrc when the instance does not set them
+func fillFromTorrc(inst *Instance, dataDir string) {
+	if tc, err := config.LoadTorrc(inst.Torrc); err == nil {
+		if v, ok := tc.Get("ControlSocket"); ok && inst.ControlAddr == "" {
+			inst.ControlAddr = controlAddr(v, true)
+		}
+		if v, ok := tc.Get("ControlPort"); ok && inst.ControlAddr == "" {
+			inst.ControlAddr = controlAddr(v, false)
+		}
+		if v, ok := tc.Get("DataDirectory"); ok && inst.DataDir == "" {
+			inst.DataDir = strings.TrimSpace(v)
+		}
+	}
+	if inst.DataDir == "" {
+		inst.DataDir = dataDir
+	}
+}
+
+// controlAddr turns a ControlPort/ControlSocket value into a dialable address
+func controlAddr(v string, socket bool) string {
+	fields := strings.Fields(v) // drop flags like GroupWritable
+	if len(fields) == 0 || fields[0] == "0" || strings.EqualFold(fields[0], "auto") {
+		return ""
+	}
+	addr := fields[0]
+	switch {
+	case socket || strings.HasPrefix(addr, "/"):
+		return "unix:" + strings.TrimPrefix(addr, "unix:")
+	case strings.HasPrefix(addr, "unix:"):
+		return addr
+	case !strings.Contains(addr, ":"):
+		return "127.0.0.1:" + addr
+	}
+	return addr
+}
+
+// Get returns the instance with id; "" means the default instance
+func Get(id string) (*Instance, error) {
+	if id == "" {
+		id = DefaultID
+	}
+	mu.RLock()
+	inst, ok := instances[id]
+	mu.RUnlock()
+	if ok {
+		return inst, nil
+	}
+	if id == DefaultID {
+		return Default(), nil
+	}
+	return nil, errors.New("unknown tor instance " + id)
+}
+
+// List returns all instances, the default first
+func List() []*Instance {
+	mu.RLock()
+	out := make([]*Instance, 0, len(instances))
+	for _, inst := range instances {
+		out = append(out, inst)
+	}
+	mu.RUnlock()
+	if len(out) == 0 {
+		return []*Instance{Default()}
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if (out[a].ID == DefaultID) != (out[b].ID == DefaultID) {
+			return out[a].ID == DefaultID
+		}
+		return out[a].ID < out[b].ID
+	})
+	return out
+}
@@ -0,0 +1,92 @@
+// File: internal/metrics/metrics.go
+// Purpose: tor-admin's own counters, HTTP instrumentation and the /metrics handler
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"tor-admin/internal/instance"
+)
+
+var (
+	HTTPRequests   = NewCounter("tor_admin_http_requests_total", "HTTP requests served by tor-admin.", "method", "code")
+	LoginFailures  = NewCounter("tor_admin_login_failures_total", "Failed login attempts.")
+	ConfigSaves    = NewCounter("tor_admin_config_saves_total", "torrc saves performed by tor-admin.", "result")
+	ServiceActions = NewCounter("tor_admin_service_actions_total", "Service control actions run by tor-admin.", "action", "result")
+)
+
+// Result maps an error to the "result" label value
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush keeps streaming handlers (server-sent events) working through the wrapper
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Instrument counts every request passing through next by method and status code
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		HTTPRequests.Inc(r.Method, strconv.Itoa(rec.status))
+	})
+}
+
+// Handler serves tor-admin counters followed by the metrics of the tor
+// instance named by ?instance= (the default one when absent), read over its
+// control port. If tor is unreachable only tor_up 0 is reported for it.
+// When tor's MetricsPort is configured its families are relayed as well.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inst, err := instance.Get(r.URL.Query().Get("instance"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WriteCounters(w)
+
+		seen := map[string]bool{}
+		if ctl, err := inst.Dial(); err != nil {
+			_ = WriteFamily(w, Family{Name: "tor_up", Help: "Whether tor's control port answered.", Type: "gauge", Samples: []Sample{{Value: 0}}})
+			seen["tor_up"] = true
+		} else {
+			for _, f := range CollectTor(ctl) {
+				_ = WriteFamily(w, f)
+				seen[f.Name] = true
+			}
+			ctl.Close()
+		}
+
+		if url := MetricsPortURL(inst.Torrc); url != "" {
+			families, err := Scrape(r.Context(), url)
+			if err != nil {
+				return
+			}
+			for _, f := range families {
+				if !seen[f.Name] {
+					_ = WriteFamily(w, f)
+				}
+			}
+		}
+	})
+}
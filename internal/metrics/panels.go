@@ -0,0 +1,106 @@
+// File: internal/metrics/panels.go
+// Purpose: Summarize MetricsPort samples into dashboard panels (onion services, relay load, DoS)
+
+package metrics
+
+import (
+	"sort"
+	"strings"
+)
+
+// OnionPanel is per-onion-service activity reported by MetricsPort
+type OnionPanel struct {
+	Onion              string  `json:"onion"`
+	Introductions      float64 `json:"introductions"`
+	IntroRejected      float64 `json:"intro_rejected"`
+	Rendezvous         float64 `json:"rendezvous"`
+	RendezvousErrors   float64 `json:"rendezvous_errors"`
+	PoWSuggestedEffort float64 `json:"pow_suggested_effort"`
+}
+
+// Dashboard groups MetricsPort samples for the UI
+type Dashboard struct {
+	Onions    []OnionPanel       `json:"onions"`
+	RelayLoad map[string]float64 `json:"relay_load"`
+	DoS       map[string]float64 `json:"dos"`
+}
+
+// BuildDashboard extracts the panels tor-admin displays from a MetricsPort scrape
+func BuildDashboard(families []Family) Dashboard {
+	d := Dashboard{RelayLoad: map[string]float64{}, DoS: map[string]float64{}}
+
+	onions := map[string]*OnionPanel{}
+	for _, m := range []struct {
+		name  string
+		field func(*OnionPanel) *float64
+	}{
+		{"tor_hs_intro_num_total", func(p *OnionPanel) *float64 { return &p.Introductions }},
+		{"tor_hs_intro_rejected_intro_req_count", func(p *OnionPanel) *float64 { return &p.IntroRejected }},
+		{"tor_hs_rdv_num_total", func(p *OnionPanel) *float64 { return &p.Rendezvous }},
+		{"tor_hs_rdv_error_count", func(p *OnionPanel) *float64 { return &p.RendezvousErrors }},
+		{"tor_hs_pow_suggested_effort", func(p *OnionPanel) *float64 { return &p.PoWSuggestedEffort }},
+	} {
+		for onion, v := range SumBy(families, m.name, "onion") {
+			p, ok := onions[onion]
+			if !ok {
+				p = &OnionPanel{Onion: onion}
+				onions[onion] = p
+			}
+			*m.field(p) += v
+		}
+	}
+	for _, p := range onions {
+		d.Onions = append(d.Onions, *p)
+	}
+	sort.Slice(d.Onions, func(i, j int) bool { return d.Onions[i].Onion < d.Onions[j].Onion })
+
+	for action, v := range SumBy(families, "tor_relay_load_onionskins_total", "action") {
+		d.RelayLoad["onionskins_"+action] += v
+	}
+	for side, v := range SumBy(families, "tor_relay_load_global_rate_limit_reached_total", "side") {
+		d.RelayLoad["rate_limit_"+side] += v
+	}
+	for state, v := range SumBy(families, "tor_relay_load_socket_total", "state") {
+		d.RelayLoad["sockets_"+state] += v
+	}
+	for _, v := range SumBy(families, "tor_relay_load_oom_bytes_total", "") {
+		d.RelayLoad["oom_bytes"] += v
+	}
+	for _, v := range SumBy(families, "tor_relay_load_tcp_exhaustion_total", "") {
+		d.RelayLoad["tcp_exhaustion"] += v
+	}
+	for typ, v := range SumBy(families, "tor_relay_dos_total", "type") {
+		d.DoS[typ] += v
+	}
+	return d
+}
+
+// OnionDetail returns one service's panel plus introduction rejections by
+// reason. onion may be given with or without the ".onion" suffix.
+func OnionDetail(families []Family, onion string) (OnionPanel, map[string]float64) {
+	onion = strings.TrimSuffix(onion, ".onion")
+	panel := OnionPanel{Onion: onion}
+	for _, p := range BuildDashboard(families).Onions {
+		if strings.TrimSuffix(p.Onion, ".onion") == onion {
+			panel = p
+		}
+	}
+	reasons := map[string]float64{}
+	if f, ok := Lookup(families, "tor_hs_intro_rejected_intro_req_count"); ok {
+		for _, s := range f.Samples {
+			var sOnion, reason string
+			for _, l := range s.Labels {
+				switch l.Name {
+				case "onion":
+					sOnion = l.Value
+				case "reason":
+					reason = l.Value
+				}
+			}
+			if s.Name == "" && strings.TrimSuffix(sOnion, ".onion") == onion {
+				reasons[reason] += s.Value
+			}
+		}
+	}
+	return panel, reasons
+}
@@ -0,0 +1,166 @@
+// File: internal/metrics/registry.go
+// Purpose: Tiny counter registry and Prometheus text exposition writer
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Counter is a monotonically increasing value split by label values
+type Counter struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64 // key: label values joined by \xff
+}
+
+var (
+	regMu    sync.Mutex
+	counters []*Counter
+)
+
+// NewCounter creates and registers a counter
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: map[string]float64{}}
+	regMu.Lock()
+	counters = append(counters, c)
+	regMu.Unlock()
+	return c
+}
+
+// Inc adds one to the series identified by labelValues (in label order)
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v to the series identified by labelValues
+func (c *Counter) Add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) samples() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]Sample, 0, len(keys))
+	for _, k := range keys {
+		s := Sample{Value: c.values[k]}
+		if len(c.labels) > 0 {
+			vals := strings.Split(k, "\xff")
+			for i, l := range c.labels {
+				if i < len(vals) {
+					s.Labels = append(s.Labels, Label{l, vals[i]})
+				}
+			}
+		}
+		out = append(out, s)
+	}
+	if len(out) == 0 && len(c.labels) == 0 {
+		out = append(out, Sample{})
+	}
+	return out
+}
+
+// Label is one name="value" pair
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one series value within a metric family
+type Sample struct {
+	Name   string // full series name when it differs from the family (histogram _bucket/_sum/_count)
+	Labels []Label
+	Value  float64
+}
+
+// Family is a named metric with HELP/TYPE metadata and its samples
+type Family struct {
+	Name    string
+	Help    string
+	Type    string // "counter", "gauge" or "untyped"
+	Samples []Sample
+}
+
+// WriteCounters writes every registered counter
+func WriteCounters(w io.Writer) error {
+	regMu.Lock()
+	cs := append([]*Counter(nil), counters...)
+	regMu.Unlock()
+	for _, c := range cs {
+		if err := WriteFamily(w, Family{Name: c.name, Help: c.help, Type: "counter", Samples: c.samples()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFamily writes one metric family in Prometheus text format 0.0.4
+func WriteFamily(w io.Writer, f Family) error {
+	if len(f.Samples) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	if f.Help != "" {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+	}
+	fmt.Fprintf(&b, "# TYPE %s %s\n", f.Name, f.Type)
+	for _, s := range f.Samples {
+		if s.Name != "" {
+			b.WriteString(s.Name)
+		} else {
+			b.WriteString(f.Name)
+		}
+		if len(s.Labels) > 0 {
+			b.WriteByte('{')
+			for i, l := range s.Labels {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				fmt.Fprintf(&b, "%s=\"%s\"", l.Name, escapeLabel(l.Value))
+			}
+			b.WriteByte('}')
+		}
+		b.WriteByte(' ')
+		b.WriteString(formatValue(s.Value))
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,212 @@
+// File: internal/metrics/scrape.go
+// Purpose: Scrape and parse Prometheus text exposed by tor's own MetricsPort
+
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"tor-admin/internal/config"
+)
+
+// MetricsPortURL returns the URL of tor's MetricsPort from TOR_METRICS_URL,
+// falling back to the MetricsPort line in the torrc. Empty means not configured.
+func MetricsPortURL(torrcPath string) string {
+	if u := os.Getenv("TOR_METRICS_URL"); u != "" {
+		return u
+	}
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return ""
+	}
+	v, ok := tc.Get("MetricsPort")
+	if !ok {
+		return ""
+	}
+	addr := strings.Fields(v)[0] // drop flags such as "prometheus"
+	if _, err := strconv.Atoi(addr); err == nil {
+		addr = "127.0.0.1:" + addr
+	}
+	return "http://" + addr + "/metrics"
+}
+
+// Scrape fetches url and parses the exposition
+func Scrape(ctx context.Context, url string) ([]Family, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics port returned %s", resp.Status)
+	}
+	return Parse(resp.Body)
+}
+
+// Parse reads Prometheus text format 0.0.4. Histogram and summary series
+// (_bucket, _sum, _count) are grouped under their declared family.
+func Parse(r io.Reader) ([]Family, error) {
+	var out []Family
+	index := map[string]int{}
+	family := func(name string) *Family {
+		if i, ok := index[name]; ok {
+			return &out[i]
+		}
+		index[name] = len(out)
+		out = append(out, Family{Name: name, Type: "untyped"})
+		return &out[len(out)-1]
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) >= 4 && fields[1] == "HELP" {
+				family(fields[2]).Help = fields[3]
+			} else if len(fields) >= 4 && fields[1] == "TYPE" {
+				family(fields[2]).Type = fields[3]
+			}
+			continue
+		}
+		name, s, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		famName := name
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			base := strings.TrimSuffix(name, suffix)
+			if i, ok := index[base]; ok && base != name && (out[i].Type == "histogram" || out[i].Type == "summary") {
+				famName, s.Name = base, name
+				break
+			}
+		}
+		f := family(famName)
+		f.Samples = append(f.Samples, s)
+	}
+	return out, sc.Err()
+}
+
+func parseSample(line string) (string, Sample, error) {
+	var s Sample
+	end := strings.IndexAny(line, "{ ")
+	if end <= 0 {
+		return "", s, errors.New("missing sample value")
+	}
+	name, rest := line[:end], line[end:]
+	if rest[0] == '{' {
+		rest = rest[1:]
+		for {
+			rest = strings.TrimLeft(rest, " ,")
+			if strings.HasPrefix(rest, "}") {
+				rest = rest[1:]
+				break
+			}
+			eq := strings.IndexByte(rest, '=')
+			if eq < 0 || len(rest) < eq+2 || rest[eq+1] != '"' {
+				return "", s, errors.New("malformed label set")
+			}
+			label := strings.TrimSpace(rest[:eq])
+			val, n, ok := unescapeLabel(rest[eq+2:])
+			if !ok {
+				return "", s, errors.New("unterminated label value")
+			}
+			s.Labels = append(s.Labels, Label{label, val})
+			rest = rest[eq+2+n:]
+		}
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", s, errors.New("missing sample value")
+	}
+	v, err := parseValue(fields[0])
+	if err != nil {
+		return "", s, err
+	}
+	s.Value = v
+	return name, s, nil
+}
+
+// unescapeLabel decodes a label value up to its closing quote, reporting the bytes consumed
+func unescapeLabel(s string) (string, int, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				if s[i] == 'n' {
+					b.WriteByte('\n')
+				} else {
+					b.WriteByte(s[i])
+				}
+			}
+		case '"':
+			return b.String(), i + 1, true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, false
+}
+
+func parseValue(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf":
+		return strconv.ParseFloat("+Inf", 64)
+	case "-Inf":
+		return strconv.ParseFloat("-Inf", 64)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Lookup returns the named family from a scrape, if present
+func Lookup(families []Family, name string) (Family, bool) {
+	for _, f := range families {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Family{}, false
+}
+
+// SumBy totals a family's samples grouped by one label's value ("" for all)
+func SumBy(families []Family, name, label string) map[string]float64 {
+	out := map[string]float64{}
+	f, ok := Lookup(families, name)
+	if !ok {
+		return out
+	}
+	for _, s := range f.Samples {
+		if s.Name != "" {
+			continue // skip histogram/summary parts
+		}
+		key := ""
+		for _, l := range s.Labels {
+			if l.Name == label {
+				key = l.Value
+			}
+		}
+		out[key] += s.Value
+	}
+	return out
+}
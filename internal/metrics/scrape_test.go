@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const fixtureOnion = "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd"
+
+// metricsPort serves the recorded MetricsPort exposition in testdata
+func metricsPort(t *testing.T) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "metricsport.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestScrape(t *testing.T) {
+	families, err := Scrape(context.Background(), metricsPort(t).URL+"/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 16 {
+		t.Errorf("got %d families, want 16", len(families))
+	}
+
+	f, ok := Lookup(families, "tor_hs_rend_circ_build_time")
+	if !ok {
+		t.Fatal("histogram family missing")
+	}
+	if f.Type != "histogram" || f.Help != "The rendezvous circuit build time in milliseconds" {
+		t.Errorf("histogram metadata = %q %q", f.Type, f.Help)
+	}
+	var names []string
+	for _, s := range f.Samples {
+		names = append(names, s.Name)
+	}
+	want := []string{
+		"tor_hs_rend_circ_build_time_bucket", "tor_hs_rend_circ_build_time_bucket", "tor_hs_rend_circ_build_time_bucket",
+		"tor_hs_rend_circ_build_time_sum", "tor_hs_rend_circ_build_time_count",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("histogram series = %v", names)
+	}
+	if _, ok := Lookup(families, "tor_hs_rend_circ_build_time_bucket"); ok {
+		t.Error("_bucket series parsed as its own family")
+	}
+	if got := f.Samples[2].Labels[1]; got != (Label{"le", "+Inf"}) {
+		t.Errorf("+Inf bucket label = %v", got)
+	}
+
+	if got := SumBy(families, "tor_relay_load_onionskins_total", "action"); got["processed"] != 60323 || got["dropped"] != 14 {
+		t.Errorf("onionskins by action = %v", got)
+	}
+	if got := SumBy(families, "tor_relay_traffic_bytes", "direction"); got["written"] != 8.902117e9 {
+		t.Errorf("traffic by direction = %v", got)
+	}
+	// histogram parts are not summed as plain samples
+	if got := SumBy(families, "tor_hs_rend_circ_build_time", ""); len(got) != 0 {
+		t.Errorf("histogram SumBy = %v", got)
+	}
+}
+
+func TestScrapeErrors(t *testing.T) {
+	srv := metricsPort(t)
+	if _, err := Scrape(context.Background(), srv.URL+"/nope"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("404 scrape error = %v", err)
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# TYPE x counter\nx{a=\"1\" 2\n"))
+	}))
+	defer bad.Close()
+	if _, err := Scrape(context.Background(), bad.URL); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("malformed scrape error = %v", err)
+	}
+}
+
+func TestParseLabelEscapes(t *testing.T) {
+	families, err := Parse(strings.NewReader(`x{path="C:\\tor",msg="say \"hi\"\nbye"} -Inf` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := families[0].Samples[0]
+	if s.Labels[0].Value != `C:\tor` || s.Labels[1].Value != "say \"hi\"\nbye" {
+		t.Errorf("labels = %q", s.Labels)
+	}
+	var b bytes.Buffer
+	if err := WriteFamily(&b, families[0]); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parse(&b)
+	if err != nil || !reflect.DeepEqual(again, families) {
+		t.Errorf("round trip = %+v, %v", again, err)
+	}
+}
+
+func TestBuildDashboard(t *testing.T) {
+	families, err := Scrape(context.Background(), metricsPort(t).URL+"/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := BuildDashboard(families)
+	want := []OnionPanel{{
+		Onion:              fixtureOnion,
+		Introductions:      412,
+		IntroRejected:      9,
+		Rendezvous:         398,
+		RendezvousErrors:   3,
+		PoWSuggestedEffort: 120,
+	}}
+	if !reflect.DeepEqual(d.Onions, want) {
+		t.Errorf("onions = %+v", d.Onions)
+	}
+	if d.RelayLoad["sockets_opened"] != 1423 || d.RelayLoad["oom_bytes"] != 4096 || d.RelayLoad["rate_limit_write"] != 11 {
+		t.Errorf("relay load = %v", d.RelayLoad)
+	}
+	if d.DoS["conn_rejected"] != 19 || d.DoS["marked_addresses"] != 6 {
+		t.Errorf("dos = %v", d.DoS)
+	}
+
+	_, reasons := OnionDetail(families, fixtureOnion+".onion")
+	if !reflect.DeepEqual(reasons, map[string]float64{"bad_auth_key": 0, "invalid_introduce2": 7, "replay": 2}) {
+		t.Errorf("rejection reasons = %v", reasons)
+	}
+}
+
+func TestMetricsPortURL(t *testing.T) {
+	t.Setenv("TOR_METRICS_URL", "")
+	torrc := filepath.Join(t.TempDir(), "torrc")
+	for line, want := range map[string]string{
+		"MetricsPort 9035":                      "http://127.0.0.1:9035/metrics",
+		"MetricsPort 127.0.0.1:9035 prometheus": "http://127.0.0.1:9035/metrics",
+		"SocksPort 9050":                        "",
+	} {
+		if err := os.WriteFile(torrc, []byte(line+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if got := MetricsPortURL(torrc); got != want {
+			t.Errorf("%q: MetricsPortURL = %q, want %q", line, got, want)
+		}
+	}
+	t.Setenv("TOR_METRICS_URL", "http://10.0.0.1:9035/metrics")
+	if got := MetricsPortURL(torrc); got != "http://10.0.0.1:9035/metrics" {
+		t.Errorf("env override = %q", got)
+	}
+}
+
+// closedAddr returns a local address nothing is listening on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestHandlerRelaysMetricsPort(t *testing.T) {
+	srv := metricsPort(t)
+	t.Setenv("TOR_METRICS_URL", srv.URL+"/metrics")
+	t.Setenv("TOR_CONTROL_ADDR", closedAddr(t))
+	t.Setenv("TORRC_PATH", "/nonexistent/torrc")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics?instance=nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown instance: status %d, want 404", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type = %q", ct)
+	}
+	exported, err := Parse(rec.Body)
+	if err != nil {
+		t.Fatalf("re-exported text does not parse: %v", err)
+	}
+	up, ok := Lookup(exported, "tor_up")
+	if !ok || up.Samples[0].Value != 0 {
+		t.Errorf("tor_up = %+v", up)
+	}
+	if _, ok := Lookup(exported, "tor_admin_login_failures_total"); !ok {
+		t.Error("own counters missing")
+	}
+
+	scraped, err := Scrape(context.Background(), srv.URL+"/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range scraped {
+		got, ok := Lookup(exported, want.Name)
+		if !ok {
+			t.Errorf("%s not re-exported", want.Name)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s re-exported as %+v, want %+v", want.Name, got, want)
+		}
+	}
+}
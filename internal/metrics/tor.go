@@ -0,0 +1,131 @@
+// File: internal/metrics/tor.go
+// Purpose: Gather tor daemon metrics over the control port
+
+package metrics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"tor-admin/internal/torctl"
+)
+
+// CollectTor queries the control port and returns metric families. Keys
+// tor refuses to answer (e.g. relay-only ones on a client) are skipped.
+func CollectTor(ctl *torctl.Conn) []Family {
+	gauge := func(name, help string, samples ...Sample) Family {
+		return Family{Name: name, Help: help, Type: "gauge", Samples: samples}
+	}
+	counter := func(name, help string, v float64) Family {
+		return Family{Name: name, Help: help, Type: "counter", Samples: []Sample{{Value: v}}}
+	}
+	out := []Family{gauge("tor_up", "Whether tor's control port answered.", Sample{Value: 1})}
+
+	if info, err := ctl.GetInfo("traffic/read", "traffic/written"); err == nil {
+		out = append(out,
+			counter("tor_traffic_read_bytes_total", "Bytes read by tor since it started.", parseFloat(info["traffic/read"])),
+			counter("tor_traffic_written_bytes_total", "Bytes written by tor since it started.", parseFloat(info["traffic/written"])),
+		)
+	}
+	if info, err := ctl.GetInfo("circuit-status"); err == nil {
+		out = append(out, gauge("tor_circuits", "Open circuits by status.", countByField(info["circuit-status"], 1, "status")...))
+	}
+	if info, err := ctl.GetInfo("stream-status"); err == nil {
+		out = append(out, gauge("tor_streams", "Open streams by status.", countByField(info["stream-status"], 1, "status")...))
+	}
+	if info, err := ctl.GetInfo("status/bootstrap-phase"); err == nil {
+		kv := torctl.ParseKeyValues(info["status/bootstrap-phase"])
+		out = append(out, gauge("tor_bootstrap_progress", "Bootstrap progress percentage (0-100).", Sample{Value: parseFloat(kv["PROGRESS"])}))
+	}
+	if info, err := ctl.GetInfo("accounting/enabled"); err == nil {
+		enabled := info["accounting/enabled"] == "1"
+		out = append(out, gauge("tor_accounting_enabled", "Whether AccountingMax is set.", Sample{Value: boolFloat(enabled)}))
+		if enabled {
+			if acct, err := ctl.GetInfo("accounting/bytes", "accounting/bytes-left"); err == nil {
+				used := strings.Fields(acct["accounting/bytes"])
+				left := strings.Fields(acct["accounting/bytes-left"])
+				if len(used) == 2 && len(left) == 2 {
+					out = append(out,
+						gauge("tor_accounting_bytes", "Bytes counted in the current accounting period.",
+							Sample{Labels: []Label{{"direction", "read"}}, Value: parseFloat(used[0])},
+							Sample{Labels: []Label{{"direction", "written"}}, Value: parseFloat(used[1])}),
+						gauge("tor_accounting_bytes_left", "Bytes left before hibernating.",
+							Sample{Labels: []Label{{"direction", "read"}}, Value: parseFloat(left[0])},
+							Sample{Labels: []Label{{"direction", "written"}}, Value: parseFloat(left[1])}),
+					)
+				}
+			}
+		}
+	}
+
+	var onions []Sample
+	if conf, err := ctl.GetConf("HiddenServiceDir"); err == nil {
+		onions = append(onions, Sample{Labels: []Label{{"kind", "configured"}}, Value: float64(len(conf["HiddenServiceDir"]))})
+	}
+	for _, kind := range []string{"current", "detached"} {
+		if info, err := ctl.GetInfo("onions/" + kind); err == nil {
+			onions = append(onions, Sample{Labels: []Label{{"kind", "ephemeral_" + kind}}, Value: float64(len(nonEmptyLines(info["onions/"+kind])))})
+		}
+	}
+	if len(onions) > 0 {
+		out = append(out, gauge("tor_onion_services", "Onion services by kind.", onions...))
+	}
+
+	if info, err := ctl.GetInfo("fingerprint"); err == nil && info["fingerprint"] != "" {
+		if ns, err := ctl.GetInfo("ns/id/" + info["fingerprint"]); err == nil {
+			var flags []Sample
+			for _, line := range nonEmptyLines(ns["ns/id/"+info["fingerprint"]]) {
+				if strings.HasPrefix(line, "s ") {
+					for _, f := range strings.Fields(line)[1:] {
+						flags = append(flags, Sample{Labels: []Label{{"flag", f}}, Value: 1})
+					}
+				}
+			}
+			out = append(out, gauge("tor_relay_flag", "Flags assigned to this relay in the consensus.", flags...))
+		}
+	}
+	return out
+}
+
+// countByField counts lines of a status listing by their n-th field
+func countByField(listing string, n int, label string) []Sample {
+	counts := map[string]int{}
+	for _, line := range nonEmptyLines(listing) {
+		if f := strings.Fields(line); len(f) > n {
+			counts[f[n]]++
+		}
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	samples := make([]Sample, 0, len(keys))
+	for _, k := range keys {
+		samples = append(samples, Sample{Labels: []Label{{label, strings.ToLower(k)}}, Value: float64(counts[k])})
+	}
+	return samples
+}
+
+func nonEmptyLines(s string) []string {
+	var out []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
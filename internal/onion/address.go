@@ -0,0 +1,55 @@
+// File: internal/onion/address.go
+// Purpose: Encode and validate v3 onion addresses (ed25519 key, checksum, version)
+
+package onion
+
+import (
+	"crypto/ed25519"
+	"encoding/base32"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const addressVersion = 3
+
+var addressEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func addressChecksum(pub []byte) []byte {
+	h := sha3.New256()
+	h.Write([]byte(".onion checksum"))
+	h.Write(pub)
+	h.Write([]byte{addressVersion})
+	return h.Sum(nil)[:2]
+}
+
+// AddressFromPublicKey returns the 56-character v3 address (without ".onion")
+func AddressFromPublicKey(pub ed25519.PublicKey) string {
+	raw := make([]byte, 0, 35)
+	raw = append(raw, pub...)
+	raw = append(raw, addressChecksum(pub)...)
+	raw = append(raw, addressVersion)
+	return strings.ToLower(addressEncoding.EncodeToString(raw))
+}
+
+// ParseAddress validates a v3 onion address and returns its ed25519 public key
+func ParseAddress(addr string) (ed25519.PublicKey, error) {
+	addr = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(addr)), ".onion")
+	if len(addr) != 56 {
+		return nil, errors.New("onion address must be 56 characters")
+	}
+	raw, err := addressEncoding.DecodeString(strings.ToUpper(addr))
+	if err != nil || len(raw) != 35 {
+		return nil, errors.New("onion address is not valid base32")
+	}
+	pub, sum, version := raw[:32], raw[32:34], raw[34]
+	if version != addressVersion {
+		return nil, errors.New("only v3 onion addresses are supported")
+	}
+	want := addressChecksum(pub)
+	if sum[0] != want[0] || sum[1] != want[1] {
+		return nil, errors.New("onion address checksum mismatch")
+	}
+	return ed25519.PublicKey(pub), nil
+}
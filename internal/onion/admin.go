@@ -0,0 +1,136 @@
+// File: internal/onion/admin.go
+// Purpose: Provision a client-authorized onion service that serves tor-admin itself
+
+package onion
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tor-admin/internal/config"
+	"tor-admin/internal/instance"
+)
+
+// adminClientName is the authorized_clients entry for the admin's own key
+const adminClientName = "tor-admin"
+
+// adminHostnameTimeout bounds the wait for tor to publish the admin service
+var adminHostnameTimeout = 30 * time.Second
+
+// AdminOnion is the provisioned admin service
+type AdminOnion struct {
+	Dir        string
+	Hostname   string
+	ClientAuth string // "<onion>:descriptor:x25519:<key>" for the admin's ClientOnionAuthDir
+}
+
+// ProvisionAdmin makes sure dir is a HiddenServiceDir of inst mapping port 80
+// to target ("unix:/run/tor-admin.sock" or "127.0.0.1:8080") with client
+// authorization on, reloads that tor when anything changed and waits for the
+// hostname. The client private key is kept in keyPath so it can be shown on
+// every start.
+func ProvisionAdmin(inst *instance.Instance, dir, target, keyPath string) (*AdminOnion, error) {
+	changed := false
+	tc, err := config.LoadTorrc(inst.Torrc)
+	if err != nil {
+		return nil, err
+	}
+	port := "80 " + target
+	if block, ok := tc.HiddenService(dir); !ok {
+		if err := tc.AddHiddenService(dir, []string{port}); err != nil {
+			return nil, err
+		}
+		changed = true
+	} else if ports := block.GetAll("HiddenServicePort"); len(ports) != 1 || ports[0] != port {
+		if err := tc.UnsetHiddenServiceOption(dir, "HiddenServicePort"); err != nil {
+			return nil, err
+		}
+		if err := tc.SetHiddenServiceOption(dir, "HiddenServicePort", port); err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+	if changed {
+		if err := tc.Save(inst.Torrc); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := loadOrCreateClientKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	authDir := filepath.Join(dir, "authorized_clients")
+	authPath := filepath.Join(authDir, adminClientName+".auth")
+	want := ServerAuthLine(key.Public) + "\n"
+	if have, err := os.ReadFile(authPath); err != nil || string(have) != want {
+		if err := os.MkdirAll(authDir, 0700); err != nil {
+			return nil, err
+		}
+		_ = os.Chmod(dir, 0700)
+		matchOwner(dir, filepath.Dir(dir))
+		matchOwner(authDir, filepath.Dir(dir))
+		if err := os.WriteFile(authPath, []byte(want), 0600); err != nil {
+			return nil, err
+		}
+		matchOwner(authPath, filepath.Dir(dir))
+		changed = true
+	}
+
+	if changed {
+		ctl, err := inst.Dial()
+		if err != nil {
+			return nil, errors.New("torrc updated but tor " + inst.ID + " could not be reloaded: " + err.Error())
+		}
+		_, err = ctl.Signal("RELOAD")
+		ctl.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := waitForHostname(dir, adminHostnameTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminOnion{Dir: dir, Hostname: hostname, ClientAuth: ClientAuthLine(hostname, key.Private)}, nil
+}
+
+func loadOrCreateClientKey(path string) (*ClientKey, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		return ClientKeyFromPrivate(string(data))
+	}
+	key, err := GenerateClientKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return key, os.WriteFile(path, []byte(key.Private+"\n"), 0600)
+}
+
+func waitForHostname(dir string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, "hostname")); err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+		if time.Now().After(deadline) {
+			return "", errors.New("tor did not create " + filepath.Join(dir, "hostname") + " in time")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// ShareSocket lets tor connect to a Unix socket by giving it the group of
+// tor's data directory (the parent of dir) and mode 0660
+func ShareSocket(sock, dir string) {
+	if _, gid, ok := fileOwner(filepath.Dir(dir)); ok {
+		_ = os.Chown(sock, -1, gid)
+	}
+	_ = os.Chmod(sock, 0660)
+}
@@ -0,0 +1,110 @@
+package onion
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tor-admin/internal/config"
+	"tor-admin/internal/instance"
+	"tor-admin/internal/torctl/torctltest"
+)
+
+func TestProvisionAdmin(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "data", "admin")
+	keyPath := filepath.Join(root, "state", "admin-client.key")
+	torrc := filepath.Join(root, "torrc")
+	if err := os.WriteFile(torrc, []byte("SocksPort 9050\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := adminHostnameTimeout
+	adminHostnameTimeout = time.Second
+	t.Cleanup(func() { adminHostnameTimeout = old })
+
+	// like tor, create the service keys on reload
+	srv := torctltest.New(t)
+	srv.Handle("SIGNAL", func(args string) string {
+		if args == "RELOAD" {
+			if _, err := os.Stat(filepath.Join(dir, "hostname")); err != nil {
+				if _, err := WriteServiceKeys(dir, bytes.Repeat([]byte{7}, 32)); err != nil {
+					return "551 " + err.Error() + "\r\n"
+				}
+			}
+		}
+		return "250 OK\r\n"
+	})
+	inst := &instance.Instance{ID: "test", Torrc: torrc, ControlAddr: srv.Addr}
+	ports := func() []string {
+		t.Helper()
+		tc, err := config.LoadTorrc(torrc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, ok := tc.HiddenService(dir)
+		if !ok {
+			t.Fatal("admin HiddenServiceDir missing from the torrc")
+		}
+		return b.GetAll("HiddenServicePort")
+	}
+
+	first, err := ProvisionAdmin(inst, dir, "unix:/run/tor-admin.sock", keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Commands(); len(got) != 1 || got[0] != "SIGNAL RELOAD" {
+		t.Errorf("first run sent %q, want one reload", got)
+	}
+	if p := ports(); len(p) != 1 || p[0] != "80 unix:/run/tor-admin.sock" {
+		t.Errorf("HiddenServicePort = %q", p)
+	}
+	cred, err := ParseCredential(first.ClientAuth)
+	if err != nil || cred.Address+".onion" != first.Hostname {
+		t.Fatalf("ClientAuth %q: %+v, %v", first.ClientAuth, cred, err)
+	}
+	key, err := ClientKeyFromPrivate(cred.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth, _ := os.ReadFile(filepath.Join(dir, "authorized_clients", adminClientName+".auth")); string(auth) != ServerAuthLine(key.Public)+"\n" {
+		t.Errorf("authorized client = %q, want the stored key's public half", auth)
+	}
+
+	// nothing to change: no reload, the same key and an untouched torrc
+	before, _ := os.ReadFile(torrc)
+	srv.Reset()
+	again, err := ProvisionAdmin(inst, dir, "unix:/run/tor-admin.sock", keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Commands(); len(got) != 0 {
+		t.Errorf("unchanged run sent %q", got)
+	}
+	if *again != *first {
+		t.Errorf("second run = %+v, want %+v", again, first)
+	}
+	if after, _ := os.ReadFile(torrc); !bytes.Equal(after, before) {
+		t.Errorf("torrc rewritten:\n%s", after)
+	}
+
+	// a new target replaces the port mapping and reloads once more
+	srv.Reset()
+	if _, err := ProvisionAdmin(inst, dir, "127.0.0.1:8080", keyPath); err != nil {
+		t.Fatal(err)
+	}
+	if p := ports(); len(p) != 1 || p[0] != "80 127.0.0.1:8080" {
+		t.Errorf("HiddenServicePort after retarget = %q", p)
+	}
+	if got := srv.Commands(); len(got) != 1 || got[0] != "SIGNAL RELOAD" {
+		t.Errorf("retarget sent %q, want one reload", got)
+	}
+
+	// a change that cannot be reloaded names the instance
+	down := &instance.Instance{ID: "down", Torrc: torrc, ControlAddr: "127.0.0.1:1"}
+	if _, err := ProvisionAdmin(down, dir, "127.0.0.1:9090", keyPath); err == nil || !strings.Contains(err.Error(), "tor down") {
+		t.Errorf("unreachable tor: err = %v", err)
+	}
+}
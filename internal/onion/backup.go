@@ -0,0 +1,403 @@
+// File: internal/onion/backup.go
+// Purpose: Passphrase-encrypted export and verified restore of onion service keys and config
+
+package onion
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+
+	"tor-admin/internal/config"
+)
+
+// Archive layout: magic | salt | nonce | XChaCha20-Poly1305(JSON manifest).
+// The magic, salt and nonce are authenticated as additional data.
+var backupMagic = []byte("TORADMIN-HSBACKUP-1\n")
+
+const (
+	backupSaltSize = 16
+	scryptN        = 1 << 15
+	scryptR        = 8
+	scryptP        = 1
+
+	// MinPassphrase is the shortest accepted backup passphrase
+	MinPassphrase = 12
+)
+
+// backupFiles are copied from each HiddenServiceDir (plus authorized_clients/*.auth)
+var backupFiles = []string{"hs_ed25519_secret_key", "hs_ed25519_public_key", "hostname"}
+
+// BackupFile is one file inside a service directory
+type BackupFile struct {
+	Path   string `json:"path"` // relative to the service directory
+	SHA256 string `json:"sha256"`
+	Data   []byte `json:"data"`
+}
+
+// BackupService is one exported onion service
+type BackupService struct {
+	Dir      string       `json:"dir"`
+	Hostname string       `json:"hostname"`
+	Torrc    []string     `json:"torrc"` // the HiddenServiceDir block
+	Files    []BackupFile `json:"files"`
+}
+
+// Backup is the decrypted archive manifest
+type Backup struct {
+	Version  int             `json:"version"`
+	Created  time.Time       `json:"created"`
+	Services []BackupService `json:"services"`
+}
+
+// ServiceSummary describes a service in an archive without its key material
+type ServiceSummary struct {
+	Dir      string   `json:"dir"`
+	Hostname string   `json:"hostname"`
+	Torrc    []string `json:"torrc"`
+	Files    []string `json:"files"`
+}
+
+// Summary lists what an archive contains
+func (b *Backup) Summary() []ServiceSummary {
+	out := []ServiceSummary{}
+	for _, s := range b.Services {
+		sum := ServiceSummary{Dir: s.Dir, Hostname: s.Hostname, Torrc: s.Torrc}
+		for _, f := range s.Files {
+			sum.Files = append(sum.Files, f.Path)
+		}
+		out = append(out, sum)
+	}
+	return out
+}
+
+// Export collects the given HiddenServiceDirs (all when dirs is empty) and
+// seals them with passphrase
+func Export(torrcPath string, dirs []string, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinPassphrase {
+		return nil, fmt.Errorf("passphrase must be at least %d characters", MinPassphrase)
+	}
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return nil, err
+	}
+	want := map[string]bool{}
+	for _, d := range dirs {
+		want[d] = true
+	}
+	b := Backup{Version: 1, Created: time.Now().UTC()}
+	for _, block := range tc.HiddenServices() {
+		if len(want) > 0 && !want[block.Dir] {
+			continue
+		}
+		delete(want, block.Dir)
+		s, err := exportService(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", block.Dir, err)
+		}
+		b.Services = append(b.Services, *s)
+	}
+	for d := range want {
+		return nil, fmt.Errorf("%s is not a configured HiddenServiceDir", d)
+	}
+	if len(b.Services) == 0 {
+		return nil, errors.New("no onion services to export")
+	}
+	plain, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	return seal(plain, passphrase)
+}
+
+func exportService(block config.HiddenServiceBlock) (*BackupService, error) {
+	s := &BackupService{Dir: block.Dir, Torrc: block.Lines()}
+	names := append([]string(nil), backupFiles...)
+	clients, _ := filepath.Glob(filepath.Join(block.Dir, "authorized_clients", "*.auth"))
+	for _, c := range clients {
+		names = append(names, filepath.Join("authorized_clients", filepath.Base(c)))
+	}
+	for _, name := range names {
+		path := filepath.Join(block.Dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		s.Files = append(s.Files, BackupFile{Path: filepath.ToSlash(name), SHA256: hex.EncodeToString(sum[:]), Data: data})
+		if name == "hostname" {
+			s.Hostname = strings.TrimSpace(string(data))
+		}
+	}
+	return s, nil
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+}
+
+func seal(plain []byte, passphrase string) ([]byte, error) {
+	header := make([]byte, len(backupMagic)+backupSaltSize+chacha20poly1305.NonceSizeX)
+	copy(header, backupMagic)
+	if _, err := rand.Read(header[len(backupMagic):]); err != nil {
+		return nil, err
+	}
+	salt := header[len(backupMagic) : len(backupMagic)+backupSaltSize]
+	nonce := header[len(backupMagic)+backupSaltSize:]
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(header, nonce, plain, header), nil
+}
+
+// Open decrypts an archive and verifies every service in it: the AEAD tag,
+// each file's checksum, and that hostname matches the public key
+func Open(archive []byte, passphrase string) (*Backup, error) {
+	headerLen := len(backupMagic) + backupSaltSize + chacha20poly1305.NonceSizeX
+	if len(archive) < headerLen || !bytes.HasPrefix(archive, backupMagic) {
+		return nil, errors.New("not a tor-admin onion backup")
+	}
+	header := archive[:headerLen]
+	salt := header[len(backupMagic) : len(backupMagic)+backupSaltSize]
+	nonce := header[len(backupMagic)+backupSaltSize:]
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, archive[headerLen:], header)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted archive")
+	}
+	var b Backup
+	if err := json.Unmarshal(plain, &b); err != nil {
+		return nil, err
+	}
+	if b.Version != 1 {
+		return nil, fmt.Errorf("unsupported backup version %d", b.Version)
+	}
+	for _, s := range b.Services {
+		if err := verifyService(s); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Dir, err)
+		}
+	}
+	return &b, nil
+}
+
+func verifyService(s BackupService) error {
+	if !filepath.IsAbs(s.Dir) || len(s.Torrc) == 0 || s.Torrc[0] != "HiddenServiceDir "+s.Dir {
+		return errors.New("invalid service entry")
+	}
+	files := map[string][]byte{}
+	for _, f := range s.Files {
+		sum := sha256.Sum256(f.Data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return fmt.Errorf("checksum mismatch for %s", f.Path)
+		}
+		clean := filepath.Clean(filepath.FromSlash(f.Path))
+		if filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+			return fmt.Errorf("unsafe path %s", f.Path)
+		}
+		files[f.Path] = f.Data
+	}
+	for _, name := range backupFiles {
+		if _, ok := files[name]; !ok {
+			return fmt.Errorf("missing %s", name)
+		}
+	}
+	pub := files["hs_ed25519_public_key"]
+	if !bytes.HasPrefix(pub, publicKeyHeader) || len(pub) != len(publicKeyHeader)+32 {
+		return errors.New("hs_ed25519_public_key has an unexpected format")
+	}
+	sec := files["hs_ed25519_secret_key"]
+	if !bytes.HasPrefix(sec, secretKeyHeader) || len(sec) != len(secretKeyHeader)+64 {
+		return errors.New("hs_ed25519_secret_key has an unexpected format")
+	}
+	derived, err := publicFromExpanded(sec[len(secretKeyHeader):])
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(derived, pub[len(publicKeyHeader):]) {
+		return errors.New("hs_ed25519_secret_key does not match the public key")
+	}
+	if AddressFromPublicKey(pub[len(publicKeyHeader):])+".onion" != s.Hostname {
+		return errors.New("hostname does not match the public key")
+	}
+	return verifyTorrcLines(s.Torrc)
+}
+
+// verifyTorrcLines accepts only single-line HiddenService* options after the
+// leading HiddenServiceDir, so an archive cannot smuggle other torrc settings
+func verifyTorrcLines(lines []string) error {
+	for i, l := range lines {
+		if strings.ContainsAny(l, "\r\n") {
+			return errors.New("torrc line contains a line break")
+		}
+		if i == 0 {
+			continue
+		}
+		key, _, _ := strings.Cut(strings.TrimSpace(l), " ")
+		if len(key) < len("HiddenService") || !strings.EqualFold(key[:len("HiddenService")], "HiddenService") ||
+			strings.EqualFold(key, "HiddenServiceDir") {
+			return fmt.Errorf("unexpected torrc line %q", l)
+		}
+	}
+	return nil
+}
+
+// RestoreOptions selects what to restore from an archive
+type RestoreOptions struct {
+	Dirs      []string // services to restore; empty restores all
+	Overwrite bool     // replace keys already present in a directory
+	DataDir   string   // tor's DataDirectory; new services must live under it
+	AnyDir    bool     // also allow directories outside DataDir that the torrc does not list
+}
+
+// Restore verifies the archive, writes each service's files with 0700/0600
+// permissions owned like the parent directory, and appends missing torrc
+// blocks. Unless opts.AnyDir is set, a service must already be configured in
+// the torrc or live under opts.DataDir. If a later service fails, the ones
+// already restored are still saved to the torrc so keys and config agree.
+// The caller reloads tor.
+func Restore(torrcPath string, archive []byte, passphrase string, opts RestoreOptions) ([]string, error) {
+	b, err := Open(archive, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	want := map[string]bool{}
+	for _, d := range opts.Dirs {
+		want[d] = true
+	}
+	var selected []BackupService
+	for _, s := range b.Services {
+		if len(want) == 0 || want[s.Dir] {
+			selected = append(selected, s)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no matching services in archive")
+	}
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check everything up front so a refusal leaves nothing half-restored
+	for _, s := range selected {
+		if _, configured := tc.HiddenService(s.Dir); !configured && !opts.AnyDir && !underDir(s.Dir, opts.DataDir) {
+			return nil, fmt.Errorf("%s is neither configured in the torrc nor under the DataDirectory %s", s.Dir, opts.DataDir)
+		}
+		if _, err := os.Stat(filepath.Join(s.Dir, "hs_ed25519_secret_key")); err == nil && !opts.Overwrite {
+			return nil, fmt.Errorf("%s already holds an onion key", s.Dir)
+		}
+	}
+
+	var restored []string
+	var restoreErr error
+	for _, s := range selected {
+		if err := restoreFiles(s); err != nil {
+			restoreErr = fmt.Errorf("%s: %w", s.Dir, err)
+			break
+		}
+		if _, ok := tc.HiddenService(s.Dir); !ok {
+			if err := tc.RestoreHiddenService(s.Torrc); err != nil {
+				restoreErr = fmt.Errorf("%s: %w", s.Dir, err)
+				break
+			}
+		}
+		restored = append(restored, s.Hostname)
+	}
+	if len(restored) == 0 {
+		return nil, restoreErr
+	}
+	if err := tc.Save(torrcPath); err != nil {
+		return restored, errors.Join(restoreErr, err)
+	}
+	return restored, restoreErr
+}
+
+// underDir reports whether path lies strictly inside dir
+func underDir(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// restoreFiles writes a service's files; on failure it removes the files it
+// created so the directory is left as it was found (overwritten keys aside)
+func restoreFiles(s BackupService) (err error) {
+	var created []string
+	defer func() {
+		if err != nil {
+			for i := len(created) - 1; i >= 0; i-- {
+				_ = os.Remove(created[i])
+			}
+		}
+	}()
+	mkdir := func(dir, ownerOf string) error {
+		if err := mkdirAllTracked(dir, &created); err != nil {
+			return err
+		}
+		matchOwner(dir, ownerOf)
+		return nil
+	}
+	if err := mkdir(s.Dir, filepath.Dir(s.Dir)); err != nil {
+		return err
+	}
+	_ = os.Chmod(s.Dir, 0700)
+	for _, f := range s.Files {
+		path := filepath.Join(s.Dir, filepath.FromSlash(f.Path))
+		if sub := filepath.Dir(path); sub != s.Dir {
+			if err := mkdir(sub, s.Dir); err != nil {
+				return err
+			}
+		}
+		if _, statErr := os.Stat(path); errors.Is(statErr, os.ErrNotExist) {
+			created = append(created, path)
+		}
+		if err := os.WriteFile(path, f.Data, 0600); err != nil {
+			return err
+		}
+		_ = os.Chmod(path, 0600)
+		matchOwner(path, s.Dir)
+	}
+	return nil
+}
+
+// mkdirAllTracked is os.MkdirAll(dir, 0700) that records each directory it creates
+func mkdirAllTracked(dir string, created *[]string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if parent := filepath.Dir(dir); parent != dir {
+		if err := mkdirAllTracked(parent, created); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	*created = append(*created, dir)
+	return nil
+}
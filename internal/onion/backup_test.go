@@ -0,0 +1,203 @@
+package onion
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tor-admin/internal/config"
+)
+
+const testPassphrase = "correct horse battery"
+
+// backupFixture writes two services under <tmp>/data, exports them, and
+// removes the directories and torrc blocks again so they can be restored
+func backupFixture(t *testing.T) (root, torrc string, archive []byte, hosts []string) {
+	t.Helper()
+	root = t.TempDir()
+	torrc = filepath.Join(root, "torrc")
+	var conf strings.Builder
+	for i, name := range []string{"hs1", "hs2"} {
+		dir := filepath.Join(root, "data", name)
+		host, err := WriteServiceKeys(dir, bytes.Repeat([]byte{byte(i + 1)}, 32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hosts = append(hosts, host)
+		fmt.Fprintf(&conf, "HiddenServiceDir %s\nHiddenServicePort 80 127.0.0.1:%d\n", dir, 8080+i)
+	}
+	if err := os.WriteFile(torrc, []byte(conf.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := Export(torrc, nil, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(root, "data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(torrc, []byte("SocksPort 9050\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return root, torrc, archive, hosts
+}
+
+func TestRestoreDirRestriction(t *testing.T) {
+	root, torrc, archive, hosts := backupFixture(t)
+
+	_, err := Restore(torrc, archive, testPassphrase, RestoreOptions{DataDir: filepath.Join(root, "elsewhere")})
+	if err == nil || !strings.Contains(err.Error(), "DataDirectory") {
+		t.Fatalf("restore outside DataDir: err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "data")); !os.IsNotExist(err) {
+		t.Fatal("refused restore wrote files")
+	}
+	// a sibling whose name merely starts with the DataDirectory is outside it
+	if _, err := Restore(torrc, archive, testPassphrase, RestoreOptions{DataDir: filepath.Join(root, "da")}); err == nil {
+		t.Fatal("restore into a prefix sibling was allowed")
+	}
+
+	restored, err := Restore(torrc, archive, testPassphrase, RestoreOptions{DataDir: filepath.Join(root, "data")})
+	if err != nil || strings.Join(restored, ",") != strings.Join(hosts, ",") {
+		t.Fatalf("restore under DataDir = %v, %v", restored, err)
+	}
+}
+
+func TestRestoreAnyDirAndConfigured(t *testing.T) {
+	root, torrc, archive, _ := backupFixture(t)
+	hs1 := filepath.Join(root, "data", "hs1")
+
+	// a directory the torrc already lists is allowed without AnyDir
+	if err := os.WriteFile(torrc, []byte("HiddenServiceDir "+hs1+"\nHiddenServicePort 80 127.0.0.1:8080\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(torrc, archive, testPassphrase, RestoreOptions{Dirs: []string{hs1}}); err != nil {
+		t.Fatalf("restore of a configured dir: %v", err)
+	}
+	if _, err := Restore(torrc, archive, testPassphrase, RestoreOptions{}); err == nil {
+		t.Fatal("restore of an unlisted dir without DataDir was allowed")
+	}
+	if _, err := Restore(torrc, archive, testPassphrase, RestoreOptions{Overwrite: true, AnyDir: true}); err != nil {
+		t.Fatalf("restore with AnyDir: %v", err)
+	}
+}
+
+func TestRestorePartialFailure(t *testing.T) {
+	root, torrc, archive, hosts := backupFixture(t)
+	hs1, hs2 := filepath.Join(root, "data", "hs1"), filepath.Join(root, "data", "hs2")
+
+	// hs2/hostname is a directory, so writing that file fails after the keys
+	if err := os.MkdirAll(filepath.Join(hs2, "hostname"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := Restore(torrc, archive, testPassphrase, RestoreOptions{DataDir: filepath.Join(root, "data")})
+	if err == nil || !strings.Contains(err.Error(), hs2) {
+		t.Fatalf("err = %v, want a failure for %s", err, hs2)
+	}
+	if len(restored) != 1 || restored[0] != hosts[0] {
+		t.Fatalf("restored = %v, want [%s]", restored, hosts[0])
+	}
+
+	tc, err := config.LoadTorrc(torrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tc.HiddenService(hs1); !ok {
+		t.Error("torrc was not saved for the service that was restored")
+	}
+	if _, ok := tc.HiddenService(hs2); ok {
+		t.Error("torrc lists the service that failed")
+	}
+	if _, err := os.Stat(filepath.Join(hs1, "hs_ed25519_secret_key")); err != nil {
+		t.Errorf("restored key missing: %v", err)
+	}
+	for _, name := range []string{"hs_ed25519_secret_key", "hs_ed25519_public_key"} {
+		if _, err := os.Stat(filepath.Join(hs2, name)); !os.IsNotExist(err) {
+			t.Errorf("%s of the failed service was left behind", name)
+		}
+	}
+}
+
+func TestVerifyService(t *testing.T) {
+	_, _, archive, _ := backupFixture(t)
+	b, err := Open(archive, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second service's secret key, so it is well-formed but belongs elsewhere
+	var otherSecret []byte
+	for _, f := range b.Services[1].Files {
+		if f.Path == "hs_ed25519_secret_key" {
+			otherSecret = f.Data
+		}
+	}
+	setFile := func(s *BackupService, path string, data []byte) {
+		for i := range s.Files {
+			if s.Files[i].Path == path {
+				sum := sha256.Sum256(data)
+				s.Files[i] = BackupFile{Path: path, SHA256: hex.EncodeToString(sum[:]), Data: data}
+			}
+		}
+	}
+	tests := []struct {
+		name    string
+		mutate  func(s *BackupService)
+		wantErr string
+	}{
+		{name: "as exported"},
+		{
+			name:    "secret key of another service",
+			mutate:  func(s *BackupService) { setFile(s, "hs_ed25519_secret_key", otherSecret) },
+			wantErr: "does not match the public key",
+		},
+		{
+			name:    "truncated secret key",
+			mutate:  func(s *BackupService) { setFile(s, "hs_ed25519_secret_key", otherSecret[:40]) },
+			wantErr: "unexpected format",
+		},
+		{
+			name:    "line break inside a torrc line",
+			mutate:  func(s *BackupService) { s.Torrc[1] = "HiddenServicePort 80 127.0.0.1:8080\nSocksPort 0.0.0.0:9050" },
+			wantErr: "line break",
+		},
+		{
+			name:    "non hidden service option",
+			mutate:  func(s *BackupService) { s.Torrc = append(s.Torrc, "ControlPort 0.0.0.0:9051") },
+			wantErr: "unexpected torrc line",
+		},
+		{
+			name:    "second HiddenServiceDir",
+			mutate:  func(s *BackupService) { s.Torrc = append(s.Torrc, "hiddenservicedir /tmp/x") },
+			wantErr: "unexpected torrc line",
+		},
+		{
+			name:   "option names are case-insensitive",
+			mutate: func(s *BackupService) { s.Torrc = append(s.Torrc, "hiddenservicemaxstreams 10") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := b.Services[0]
+			s.Torrc = append([]string(nil), s.Torrc...)
+			s.Files = append([]BackupFile(nil), s.Files...)
+			if tt.mutate != nil {
+				tt.mutate(&s)
+			}
+			err := verifyService(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyService: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifyService error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
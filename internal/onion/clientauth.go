@@ -0,0 +1,174 @@
+// File: internal/onion/clientauth.go
+// Purpose: v3 onion client authorization keys (x25519) and authorized_clients files
+
+package onion
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// keyEncoding is tor's unpadded upper-case base32, 52 characters for a 32-byte key
+var keyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var clientNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ClientKey is an x25519 keypair, both halves base32 encoded
+type ClientKey struct {
+	Public  string `json:"public_key"`
+	Private string `json:"private_key"`
+}
+
+// GenerateClientKey creates a new x25519 keypair for client authorization
+func GenerateClientKey() (*ClientKey, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientKey{
+		Public:  keyEncoding.EncodeToString(priv.PublicKey().Bytes()),
+		Private: keyEncoding.EncodeToString(priv.Bytes()),
+	}, nil
+}
+
+// ClientKeyFromPrivate derives the keypair from a base32 private key
+func ClientKeyFromPrivate(private string) (*ClientKey, error) {
+	raw, err := DecodeKey(private)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientKey{
+		Public:  keyEncoding.EncodeToString(priv.PublicKey().Bytes()),
+		Private: keyEncoding.EncodeToString(priv.Bytes()),
+	}, nil
+}
+
+// DecodeKey decodes a 52-character base32 x25519 key. Tor itself writes
+// lower case, so either case is accepted.
+func DecodeKey(s string) ([]byte, error) {
+	raw, err := keyEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(s)))
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("key must be 32 bytes of unpadded base32")
+	}
+	return raw, nil
+}
+
+// ServerAuthLine is the content of authorized_clients/<name>.auth
+func ServerAuthLine(public string) string {
+	return "descriptor:x25519:" + public
+}
+
+// ClientAuthLine is the content of the client's <name>.auth_private file
+func ClientAuthLine(onionAddr, private string) string {
+	return strings.TrimSuffix(onionAddr, ".onion") + ":descriptor:x25519:" + private
+}
+
+// ParseServerAuth extracts the public key, in upper case, from a .auth file line
+func ParseServerAuth(line string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(line), ":")
+	if len(parts) != 3 || parts[0] != "descriptor" || parts[1] != "x25519" {
+		return "", errors.New("expected descriptor:x25519:<base32>")
+	}
+	raw, err := DecodeKey(parts[2])
+	if err != nil {
+		return "", err
+	}
+	return keyEncoding.EncodeToString(raw), nil
+}
+
+// AuthorizedClient is one authorized_clients/*.auth file
+type AuthorizedClient struct {
+	Name      string    `json:"name"`
+	PublicKey string    `json:"public_key"`
+	Modified  time.Time `json:"modified"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ListClients reads the authorized clients of the service in hsDir
+func ListClients(hsDir string) ([]AuthorizedClient, error) {
+	paths, err := filepath.Glob(filepath.Join(hsDir, "authorized_clients", "*.auth"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	out := []AuthorizedClient{}
+	for _, p := range paths {
+		c := AuthorizedClient{Name: strings.TrimSuffix(filepath.Base(p), ".auth")}
+		if fi, err := os.Stat(p); err == nil {
+			c.Modified = fi.ModTime()
+		}
+		data, err := os.ReadFile(p)
+		if err == nil {
+			c.PublicKey, err = ParseServerAuth(string(data))
+		}
+		if err != nil {
+			c.Error = err.Error()
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// NewClient is returned once when a client is added; the private key is not kept
+type NewClient struct {
+	Name       string `json:"name"`
+	PublicKey  string `json:"public_key"`
+	ClientFile string `json:"client_file"` // contents of <name>.auth_private
+	Filename   string `json:"filename"`
+}
+
+// AddClient generates a keypair, writes the server-side .auth file and
+// returns the matching client file. Tor must be reloaded to pick it up.
+func AddClient(hsDir, name string) (*NewClient, error) {
+	if !clientNamePattern.MatchString(name) {
+		return nil, errors.New("client name must be 1-64 letters, digits, '-' or '_'")
+	}
+	hostname, err := os.ReadFile(filepath.Join(hsDir, "hostname"))
+	if err != nil {
+		return nil, errors.New("service has no hostname yet; start tor once to create its keys")
+	}
+	dir := filepath.Join(hsDir, "authorized_clients")
+	path := filepath.Join(dir, name+".auth")
+	if _, err := os.Stat(path); err == nil {
+		return nil, errors.New("a client with that name already exists")
+	}
+	key, err := GenerateClientKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	matchOwner(dir, hsDir)
+	if err := os.WriteFile(path, []byte(ServerAuthLine(key.Public)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	matchOwner(path, hsDir)
+	onion := strings.TrimSpace(string(hostname))
+	return &NewClient{
+		Name:       name,
+		PublicKey:  key.Public,
+		ClientFile: ClientAuthLine(onion, key.Private) + "\n",
+		Filename:   name + ".auth_private",
+	}, nil
+}
+
+// RevokeClient deletes a client's .auth file. Tor must be reloaded afterwards.
+func RevokeClient(hsDir, name string) error {
+	if !clientNamePattern.MatchString(name) {
+		return errors.New("invalid client name")
+	}
+	return os.Remove(filepath.Join(hsDir, "authorized_clients", name+".auth"))
+}
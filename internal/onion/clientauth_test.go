@@ -0,0 +1,127 @@
+package onion
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// x25519 vectors from RFC 7748 section 6.1, keys in tor's base32
+var clientKeyTests = []struct {
+	name    string
+	private string // hex
+	public  string // base32
+}{
+	{"alice", "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a", "QUQPACMJGCTVI5ELPXOLIPXXLIG36OQNEY4BV5HLUSUY5KU3JZVA"},
+	{"bob", "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb", "32PNW7L3PXA3JU23MHBOZZBVG47YGQ6ILN4GOTNN7R7BI34IFNHQ"},
+}
+
+const testOnion = "2gzyxa5ihm7nsggfxnu52rck2vv4rvmdlkiu3zzui5du4xyclen53wid.onion"
+
+func TestClientKeyFromPrivate(t *testing.T) {
+	for _, tt := range clientKeyTests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, _ := hex.DecodeString(tt.private)
+			private := keyEncoding.EncodeToString(raw)
+			key, err := ClientKeyFromPrivate(private)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.Public != tt.public || key.Private != private {
+				t.Errorf("key = %+v, want public %s", key, tt.public)
+			}
+
+			server := ServerAuthLine(key.Public)
+			if want := "descriptor:x25519:" + tt.public; server != want {
+				t.Errorf("ServerAuthLine = %q, want %q", server, want)
+			}
+			if got, err := ParseServerAuth(server + "\n"); err != nil || got != tt.public {
+				t.Errorf("ParseServerAuth = %q, %v", got, err)
+			}
+
+			client := ClientAuthLine(testOnion, key.Private)
+			if want := strings.TrimSuffix(testOnion, ".onion") + ":descriptor:x25519:" + private; client != want {
+				t.Errorf("ClientAuthLine = %q, want %q", client, want)
+			}
+			cred, err := ParseCredential(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cred.PrivateKey != private || cred.Address != strings.TrimSuffix(testOnion, ".onion") {
+				t.Errorf("ParseCredential = %+v", cred)
+			}
+		})
+	}
+}
+
+func TestGenerateClientKey(t *testing.T) {
+	key, err := GenerateClientKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key.Public) != 52 || len(key.Private) != 52 {
+		t.Fatalf("key lengths = %d/%d, want 52", len(key.Public), len(key.Private))
+	}
+	again, err := ClientKeyFromPrivate(key.Private)
+	if err != nil || *again != *key {
+		t.Errorf("re-derived %+v, %v; want %+v", again, err, key)
+	}
+}
+
+func TestDecodeKey(t *testing.T) {
+	alice := clientKeyTests[0].public
+	tests := []struct {
+		name  string
+		input string
+		ok    bool
+	}{
+		{"upper case", alice, true},
+		{"lower case", strings.ToLower(alice), true},
+		{"surrounding space", " " + alice + "\n", true},
+		{"padded", alice + "====", false},
+		{"too short", alice[:51], false},
+		{"too long", alice + "AAAAAAAA", false},
+		{"31 bytes", keyEncoding.EncodeToString(make([]byte, 31)), false},
+		{"not base32", strings.Replace(alice, "Q", "1", 1), false},
+		{"empty", "", false},
+	}
+	want, _ := hex.DecodeString("8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := DecodeKey(tt.input)
+			if tt.ok {
+				if err != nil || hex.EncodeToString(raw) != hex.EncodeToString(want) {
+					t.Errorf("DecodeKey = %x, %v", raw, err)
+				}
+			} else if err == nil {
+				t.Errorf("DecodeKey(%q) accepted", tt.input)
+			}
+		})
+	}
+}
+
+func TestParseServerAuth(t *testing.T) {
+	alice := clientKeyTests[0].public
+	tests := []struct {
+		line string
+		want string // "" means an error
+	}{
+		{"descriptor:x25519:" + alice, alice},
+		{"descriptor:x25519:" + strings.ToLower(alice), alice},
+		{"descriptor:x25519:" + alice + "====", ""},
+		{"descriptor:x25519:" + alice[:40], ""},
+		{"descriptor:ed25519:" + alice, ""},
+		{"x25519:" + alice, ""},
+		{testOnion + ":descriptor:x25519:" + alice, ""},
+	}
+	for _, tt := range tests {
+		got, err := ParseServerAuth(tt.line)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ParseServerAuth(%q) accepted", tt.line)
+			}
+		} else if err != nil || got != tt.want {
+			t.Errorf("ParseServerAuth(%q) = %q, %v", tt.line, got, err)
+		}
+	}
+}
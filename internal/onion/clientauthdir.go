@@ -0,0 +1,134 @@
+// File: internal/onion/clientauthdir.go
+// Purpose: Manage ClientOnionAuthDir credentials (.auth_private) for reaching restricted onion services
+
+package onion
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"tor-admin/internal/config"
+)
+
+// Credential is a parsed .auth_private line
+type Credential struct {
+	Address    string // 56 characters, without ".onion"
+	PrivateKey string // base32 x25519 key
+}
+
+// ParseCredential validates "<onion-addr>:descriptor:x25519:<base32-key>",
+// including the onion address checksum
+func ParseCredential(line string) (*Credential, error) {
+	parts := strings.Split(strings.TrimSpace(line), ":")
+	if len(parts) != 4 || parts[1] != "descriptor" || parts[2] != "x25519" {
+		return nil, errors.New("expected <onion-address>:descriptor:x25519:<base32-key>")
+	}
+	if _, err := ParseAddress(parts[0]); err != nil {
+		return nil, err
+	}
+	if _, err := DecodeKey(parts[3]); err != nil {
+		return nil, err
+	}
+	return &Credential{Address: strings.TrimSuffix(strings.ToLower(parts[0]), ".onion"), PrivateKey: strings.ToUpper(parts[3])}, nil
+}
+
+// KeyBase64 is the private key in the encoding ONION_CLIENT_AUTH_ADD expects
+func (c *Credential) KeyBase64() string {
+	raw, _ := DecodeKey(c.PrivateKey)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// ClientAuthDir returns ClientOnionAuthDir from the torrc
+func ClientAuthDir(torrcPath string) (string, error) {
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return "", err
+	}
+	dir, ok := tc.Get("ClientOnionAuthDir")
+	if !ok || dir == "" {
+		return "", errors.New("ClientOnionAuthDir is not set in the torrc")
+	}
+	return dir, nil
+}
+
+// StoredCredential describes one file in ClientOnionAuthDir without its private key
+type StoredCredential struct {
+	Name      string `json:"name"`
+	Address   string `json:"address,omitempty"`
+	PublicKey string `json:"public_key,omitempty"` // to match against the service's authorized_clients
+	Error     string `json:"error,omitempty"`
+}
+
+// ListCredentials reads every *.auth_private file in dir
+func ListCredentials(dir string) ([]StoredCredential, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.auth_private"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	out := []StoredCredential{}
+	for _, p := range paths {
+		s := StoredCredential{Name: strings.TrimSuffix(filepath.Base(p), ".auth_private")}
+		var cred *Credential
+		data, err := os.ReadFile(p)
+		if err == nil {
+			cred, err = ParseCredential(string(data))
+		}
+		if err == nil {
+			var key *ClientKey
+			if key, err = ClientKeyFromPrivate(cred.PrivateKey); err == nil {
+				s.Address, s.PublicKey = cred.Address+".onion", key.Public
+			}
+		}
+		if err != nil {
+			s.Error = err.Error()
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// ImportCredential validates content and stores it as <name>.auth_private
+func ImportCredential(dir, name, content string) (*Credential, error) {
+	if !clientNamePattern.MatchString(name) {
+		return nil, errors.New("name must be 1-64 letters, digits, '-' or '_'")
+	}
+	cred, err := ParseCredential(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, name+".auth_private")
+	line := cred.Address + ":descriptor:x25519:" + cred.PrivateKey + "\n"
+	if err := os.WriteFile(path, []byte(line), 0600); err != nil {
+		return nil, err
+	}
+	matchOwner(path, dir)
+	return cred, nil
+}
+
+// ReadCredential loads a stored credential by name
+func ReadCredential(dir, name string) (*Credential, error) {
+	if !clientNamePattern.MatchString(name) {
+		return nil, errors.New("invalid credential name")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".auth_private"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCredential(string(data))
+}
+
+// DeleteCredential removes <name>.auth_private from dir
+func DeleteCredential(dir, name string) error {
+	if !clientNamePattern.MatchString(name) {
+		return errors.New("invalid credential name")
+	}
+	return os.Remove(filepath.Join(dir, name+".auth_private"))
+}
@@ -0,0 +1,94 @@
+package onion
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCredential(t *testing.T) {
+	raw, _ := hex.DecodeString(clientKeyTests[0].private)
+	key := keyEncoding.EncodeToString(raw)
+	addr := strings.TrimSuffix(testOnion, ".onion")
+	// one public-key character changed, so the checksum no longer matches
+	tampered := addr[:10] + "a" + addr[11:]
+	if tampered == addr {
+		tampered = addr[:10] + "b" + addr[11:]
+	}
+	tests := []struct {
+		name    string
+		line    string
+		wantErr string
+	}{
+		{name: "as tor writes it", line: addr + ":descriptor:x25519:" + key},
+		{name: "upper case with .onion and newline", line: strings.ToUpper(testOnion) + ":descriptor:x25519:" + strings.ToLower(key) + "\n"},
+		{name: "checksum mismatch", line: tampered + ":descriptor:x25519:" + key, wantErr: "checksum"},
+		{name: "v2 length", line: "expyuzz4wqqyqhjn:descriptor:x25519:" + key, wantErr: "56 characters"},
+		{name: "wrong auth type", line: addr + ":descriptor:ed25519:" + key, wantErr: "expected"},
+		{name: "missing key", line: addr + ":descriptor:x25519", wantErr: "expected"},
+		{name: "short key", line: addr + ":descriptor:x25519:" + key[:40], wantErr: "32 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := ParseCredential(tt.line)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cred.Address != addr || cred.PrivateKey != key {
+				t.Errorf("credential = %+v, want %s / %s", cred, addr, key)
+			}
+			if cred.KeyBase64() != "dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo=" {
+				t.Errorf("KeyBase64 = %s", cred.KeyBase64())
+			}
+		})
+	}
+}
+
+func TestCredentialDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "auth")
+	raw, _ := hex.DecodeString(clientKeyTests[0].private)
+	key := keyEncoding.EncodeToString(raw)
+	if _, err := ImportCredential(dir, "site", strings.ToUpper(testOnion)+":descriptor:x25519:"+key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportCredential(dir, "../escape", testOnion+":descriptor:x25519:"+key); err == nil {
+		t.Error("a name with a path was accepted")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "site.auth_private"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.TrimSuffix(testOnion, ".onion") + ":descriptor:x25519:" + key + "\n"; string(data) != want {
+		t.Errorf("stored %q, want %q", data, want)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.auth_private"), []byte("garbage\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ListCredentials(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StoredCredential{
+		{Name: "broken", Error: "expected <onion-address>:descriptor:x25519:<base32-key>"},
+		{Name: "site", Address: testOnion, PublicKey: clientKeyTests[0].public},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("ListCredentials = %+v, want %+v", list, want)
+	}
+	if err := DeleteCredential(dir, "site"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadCredential(dir, "site"); err == nil {
+		t.Error("deleted credential is still readable")
+	}
+}
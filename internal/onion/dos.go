@@ -0,0 +1,145 @@
+// File: internal/onion/dos.go
+// Purpose: Per-service onion DoS defenses (proof-of-work, intro point limits, stream caps)
+
+package onion
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"tor-admin/internal/config"
+)
+
+// DoSOption describes one per-HiddenServiceDir DoS knob
+type DoSOption struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // "bool" or "int"
+	Default     string `json:"default"`
+	Min         int64  `json:"min"`
+	Max         int64  `json:"max"`
+	Description string `json:"description"`
+}
+
+// DoSOptions are the knobs tor-admin manages, in display order
+var DoSOptions = []DoSOption{
+	{
+		Name: "HiddenServicePoWDefensesEnabled", Type: "bool", Default: "0", Max: 1,
+		Description: "Ask clients to solve a proof-of-work puzzle when the service is under load. Needs tor 0.4.8+ built with PoW support; honest clients pay a little CPU, floods become expensive.",
+	},
+	{
+		Name: "HiddenServicePoWQueueRate", Type: "int", Default: "250", Max: math.MaxInt32,
+		Description: "Introduction requests per second taken from the PoW priority queue. Lower values shed load sooner and raise the suggested effort faster.",
+	},
+	{
+		Name: "HiddenServicePoWQueueBurst", Type: "int", Default: "2500", Max: math.MaxInt32,
+		Description: "Requests that may be taken from the PoW queue in a burst. Must be at least the queue rate.",
+	},
+	{
+		Name: "HiddenServiceEnableIntroDoSDefense", Type: "bool", Default: "0", Max: 1,
+		Description: "Tell introduction points to rate-limit INTRODUCE2 cells for this service, dropping excess before it reaches you.",
+	},
+	{
+		Name: "HiddenServiceEnableIntroDoSRatePerSec", Type: "int", Default: "25", Max: math.MaxInt32,
+		Description: "Introductions per second each introduction point lets through when the intro defense is on.",
+	},
+	{
+		Name: "HiddenServiceEnableIntroDoSBurstPerSec", Type: "int", Default: "200", Max: math.MaxInt32,
+		Description: "Burst allowed at each introduction point. Must be at least the rate.",
+	},
+	{
+		Name: "HiddenServiceMaxStreams", Type: "int", Default: "0", Max: 65535,
+		Description: "Maximum concurrent streams per rendezvous circuit (0 = unlimited). Caps how much one client can open.",
+	},
+	{
+		Name: "HiddenServiceMaxStreamsCloseCircuit", Type: "bool", Default: "0", Max: 1,
+		Description: "Close the whole circuit instead of refusing the stream when MaxStreams is exceeded.",
+	},
+}
+
+func dosOption(name string) (DoSOption, bool) {
+	for _, o := range DoSOptions {
+		if o.Name == name {
+			return o, true
+		}
+	}
+	return DoSOption{}, false
+}
+
+// ReadDoS returns the options explicitly set in a block
+func ReadDoS(block config.HiddenServiceBlock) map[string]string {
+	out := map[string]string{}
+	for _, o := range DoSOptions {
+		if v, ok := block.Get(o.Name); ok {
+			out[o.Name] = v
+		}
+	}
+	return out
+}
+
+// ValidateDoS checks values (empty means "use tor's default") including
+// burst >= rate for both rate limiters
+func ValidateDoS(values map[string]string) error {
+	effective := map[string]int64{}
+	for _, o := range DoSOptions {
+		n, _ := strconv.ParseInt(o.Default, 10, 64)
+		effective[o.Name] = n
+	}
+	for name, v := range values {
+		o, ok := dosOption(name)
+		if !ok {
+			return fmt.Errorf("%s is not a supported onion DoS option", name)
+		}
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < o.Min || n > o.Max {
+			return fmt.Errorf("%s must be between %d and %d", name, o.Min, o.Max)
+		}
+		effective[name] = n
+	}
+	if effective["HiddenServicePoWQueueBurst"] < effective["HiddenServicePoWQueueRate"] {
+		return errors.New("HiddenServicePoWQueueBurst must be at least HiddenServicePoWQueueRate")
+	}
+	if effective["HiddenServiceEnableIntroDoSBurstPerSec"] < effective["HiddenServiceEnableIntroDoSRatePerSec"] {
+		return errors.New("HiddenServiceEnableIntroDoSBurstPerSec must be at least HiddenServiceEnableIntroDoSRatePerSec")
+	}
+	return nil
+}
+
+// ApplyDoS validates values and writes them into dir's block; an empty value
+// removes the line so tor's default applies. The caller reloads tor.
+func ApplyDoS(torrcPath, dir string, values map[string]string) error {
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return err
+	}
+	block, ok := tc.HiddenService(dir)
+	if !ok {
+		return errors.New("not a configured HiddenServiceDir")
+	}
+	merged := ReadDoS(*block)
+	for name, v := range values {
+		merged[name] = v
+	}
+	if err := ValidateDoS(merged); err != nil {
+		return err
+	}
+	for _, o := range DoSOptions {
+		v, ok := values[o.Name]
+		if !ok {
+			continue
+		}
+		if v == "" {
+			err = tc.UnsetHiddenServiceOption(dir, o.Name)
+		} else {
+			err = tc.SetHiddenServiceOption(dir, o.Name, v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tc.Save(torrcPath)
+}
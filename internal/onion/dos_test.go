@@ -0,0 +1,100 @@
+package onion
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tor-admin/internal/config"
+)
+
+func TestValidateDoS(t *testing.T) {
+	tests := []struct {
+		values  map[string]string
+		wantErr string
+	}{
+		{values: map[string]string{}},
+		{values: map[string]string{"HiddenServicePoWDefensesEnabled": "1", "HiddenServicePoWQueueRate": "100", "HiddenServicePoWQueueBurst": "100"}},
+		{values: map[string]string{"HiddenServiceMaxStreams": "65535", "HiddenServiceMaxStreamsCloseCircuit": "0"}},
+		{values: map[string]string{"HiddenServicePoWQueueRate": ""}},
+		{values: map[string]string{"HiddenServicePoWDefensesEnabled": "2"}, wantErr: "between 0 and 1"},
+		{values: map[string]string{"HiddenServicePoWDefensesEnabled": "true"}, wantErr: "between 0 and 1"},
+		{values: map[string]string{"HiddenServiceMaxStreams": "-1"}, wantErr: "between 0 and 65535"},
+		{values: map[string]string{"HiddenServiceMaxStreams": "65536"}, wantErr: "between 0 and 65535"},
+		{values: map[string]string{"HiddenServicePoWQueueRate": "1.5"}, wantErr: "between"},
+		{values: map[string]string{"HiddenServicePort": "80"}, wantErr: "not a supported"},
+		// burst below the rate, also against tor's default burst of 2500 / 200
+		{values: map[string]string{"HiddenServicePoWQueueRate": "500", "HiddenServicePoWQueueBurst": "499"}, wantErr: "PoWQueueBurst must be at least"},
+		{values: map[string]string{"HiddenServicePoWQueueRate": "3000"}, wantErr: "PoWQueueBurst must be at least"},
+		{values: map[string]string{"HiddenServiceEnableIntroDoSRatePerSec": "201"}, wantErr: "BurstPerSec must be at least"},
+		{values: map[string]string{"HiddenServiceEnableIntroDoSBurstPerSec": "24"}, wantErr: "BurstPerSec must be at least"},
+	}
+	for _, tt := range tests {
+		err := ValidateDoS(tt.values)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ValidateDoS(%v) = %v, want %q", tt.values, err, tt.wantErr)
+		}
+	}
+}
+
+func TestApplyDoS(t *testing.T) {
+	torrc := filepath.Join(t.TempDir(), "torrc")
+	conf := `HiddenServiceDir /var/lib/tor/a
+HiddenServicePort 80 127.0.0.1:8080
+HiddenServicePoWQueueRate 100
+HiddenServiceDir /var/lib/tor/b
+HiddenServicePort 80 127.0.0.1:8081
+`
+	if err := os.WriteFile(torrc, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	read := func(dir string) map[string]string {
+		t.Helper()
+		tc, err := config.LoadTorrc(torrc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, ok := tc.HiddenService(dir)
+		if !ok {
+			t.Fatalf("%s lost from the torrc", dir)
+		}
+		return ReadDoS(*b)
+	}
+
+	// the burst is checked against the rate already in the torrc
+	if err := ApplyDoS(torrc, "/var/lib/tor/a", map[string]string{"HiddenServicePoWQueueBurst": "50"}); err == nil {
+		t.Fatal("burst below the configured rate was accepted")
+	}
+	err := ApplyDoS(torrc, "/var/lib/tor/a", map[string]string{
+		"HiddenServicePoWDefensesEnabled": "1",
+		"HiddenServicePoWQueueBurst":      "200",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"HiddenServicePoWDefensesEnabled": "1", "HiddenServicePoWQueueRate": "100", "HiddenServicePoWQueueBurst": "200"}
+	if got := read("/var/lib/tor/a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("a = %v, want %v", got, want)
+	}
+	if got := read("/var/lib/tor/b"); len(got) != 0 {
+		t.Errorf("b changed: %v", got)
+	}
+
+	// "" restores tor's default by removing the line; the default rate of 250
+	// would exceed the burst of 200, so both go back together
+	if err := ApplyDoS(torrc, "/var/lib/tor/a", map[string]string{"HiddenServicePoWQueueRate": ""}); err == nil {
+		t.Fatal("default rate above the configured burst was accepted")
+	}
+	if err := ApplyDoS(torrc, "/var/lib/tor/a", map[string]string{"HiddenServicePoWQueueRate": "", "HiddenServicePoWQueueBurst": ""}); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]string{"HiddenServicePoWDefensesEnabled": "1"}
+	if got := read("/var/lib/tor/a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("after reset a = %v, want %v", got, want)
+	}
+	if err := ApplyDoS(torrc, "/var/lib/tor/c", map[string]string{"HiddenServiceMaxStreams": "10"}); err == nil {
+		t.Error("unknown HiddenServiceDir was accepted")
+	}
+}
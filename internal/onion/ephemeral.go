@@ -0,0 +1,287 @@
+// File: internal/onion/ephemeral.go
+// Purpose: Create, list and remove ephemeral onion services, optionally persisting their keys
+
+package onion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tor-admin/internal/config"
+	"tor-admin/internal/instance"
+	"tor-admin/internal/torctl"
+)
+
+// PortMapping maps a virtual onion port to a local target ("127.0.0.1:8080")
+type PortMapping struct {
+	VirtualPort int    `json:"virtual_port"`
+	Target      string `json:"target,omitempty"`
+}
+
+func (p PortMapping) arg() string {
+	if p.Target == "" {
+		return strconv.Itoa(p.VirtualPort)
+	}
+	return strconv.Itoa(p.VirtualPort) + "," + p.Target
+}
+
+// CreateRequest is the API payload for a new ephemeral service
+type CreateRequest struct {
+	PrivateKey             string        `json:"private_key,omitempty"` // ED25519-V3:<base64>; empty for a new key
+	Ports                  []PortMapping `json:"ports"`
+	Detach                 bool          `json:"detach"`
+	DiscardPK              bool          `json:"discard_pk"`
+	MaxStreams             int           `json:"max_streams,omitempty"`
+	MaxStreamsCloseCircuit bool          `json:"max_streams_close_circuit,omitempty"`
+	ClientAuth             []string      `json:"client_auth,omitempty"`
+	Persist                bool          `json:"persist"` // re-create after tor restarts
+}
+
+// Service is a created ephemeral service as tor-admin remembers it
+type Service struct {
+	ServiceID  string        `json:"service_id"`
+	PrivateKey string        `json:"private_key,omitempty"`
+	Ports      []PortMapping `json:"ports"`
+	Flags      []string      `json:"flags,omitempty"`
+	MaxStreams int           `json:"max_streams,omitempty"`
+	ClientAuth []string      `json:"client_auth,omitempty"`
+	Persist    bool          `json:"persist"`
+	Created    time.Time     `json:"created"`
+}
+
+func (s Service) request() torctl.AddOnionRequest {
+	req := torctl.AddOnionRequest{PrivateKey: s.PrivateKey, Flags: s.Flags, MaxStreams: s.MaxStreams, ClientAuth: s.ClientAuth}
+	for _, p := range s.Ports {
+		req.Ports = append(req.Ports, p.arg())
+	}
+	return req
+}
+
+// Manager owns a long-lived control connection to one tor instance so
+// non-detached services stay up while tor-admin runs, and restores persisted
+// services whenever that tor restarts
+type Manager struct {
+	path string // JSON file with persisted services (contains private keys)
+	inst *instance.Instance
+
+	mu       sync.Mutex
+	conn     *torctl.Conn
+	services map[string]Service // by service ID
+}
+
+// NewManager loads inst's persisted services from path
+func NewManager(path string, inst *instance.Instance) (*Manager, error) {
+	m := &Manager{path: path, inst: inst, services: map[string]Service{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Service
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	for _, s := range list {
+		m.services[s.ServiceID] = s
+	}
+	return m, nil
+}
+
+// Run keeps the control connection open, restoring persisted services after
+// every (re)connect, until ctx ends
+func (m *Manager) Run(ctx context.Context) {
+	for {
+		conn, err := m.inst.Dial()
+		if err == nil {
+			m.mu.Lock()
+			m.conn = conn
+			m.mu.Unlock()
+			m.restore()
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-conn.Done():
+			}
+			m.mu.Lock()
+			m.conn = nil
+			m.mu.Unlock()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// restore re-adds persisted services that tor no longer knows about
+func (m *Manager) restore() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, detached, err := m.conn.Onions()
+	if err != nil {
+		log.Printf("onion restore %s: %v", m.inst.ID, err)
+		return
+	}
+	live := map[string]bool{}
+	for _, id := range append(current, detached...) {
+		live[id] = true
+	}
+	for id, s := range m.services {
+		if !s.Persist || live[id] {
+			continue
+		}
+		if _, err := m.conn.AddOnion(s.request()); err != nil {
+			log.Printf("onion restore %s: %s: %v", m.inst.ID, id, err)
+			continue
+		}
+		log.Printf("onion restore %s: re-created %s.onion", m.inst.ID, id)
+	}
+}
+
+// Create adds a new ephemeral service
+func (m *Manager) Create(req CreateRequest) (*Service, error) {
+	if len(req.Ports) == 0 {
+		return nil, errors.New("at least one port mapping is required")
+	}
+	for _, p := range req.Ports {
+		if p.VirtualPort < 1 || p.VirtualPort > 65535 {
+			return nil, errors.New("virtual port must be 1-65535")
+		}
+		if p.Target != "" {
+			if err := config.ValidatePortMapping(fmt.Sprintf("%d %s", p.VirtualPort, p.Target)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.Persist && req.DiscardPK && req.PrivateKey == "" {
+		return nil, errors.New("cannot persist a service whose new key is discarded")
+	}
+
+	s := Service{Ports: req.Ports, MaxStreams: req.MaxStreams, ClientAuth: req.ClientAuth, Persist: req.Persist, Created: time.Now().UTC()}
+	if req.Detach {
+		s.Flags = append(s.Flags, "Detach")
+	}
+	if req.DiscardPK {
+		s.Flags = append(s.Flags, "DiscardPK")
+	}
+	if req.MaxStreamsCloseCircuit {
+		s.Flags = append(s.Flags, "MaxStreamsCloseCircuit")
+	}
+	s.PrivateKey = req.PrivateKey
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conn == nil {
+		return nil, errors.New("control port not connected")
+	}
+	res, err := m.conn.AddOnion(s.request())
+	if err != nil {
+		return nil, err
+	}
+	s.ServiceID = res.ServiceID
+	if res.PrivateKey != "" {
+		s.PrivateKey = res.PrivateKey
+	}
+	// A re-created service must not discard the key we kept for it
+	s.Flags = without(s.Flags, "DiscardPK")
+	m.services[s.ServiceID] = s
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Listing is what tor reports plus what tor-admin knows about each service
+type Listing struct {
+	Current  []string  `json:"current"`
+	Detached []string  `json:"detached"`
+	Known    []Service `json:"known"`
+}
+
+// List returns live ephemeral services; private keys are not included
+func (m *Manager) List() (*Listing, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conn == nil {
+		return nil, errors.New("control port not connected")
+	}
+	current, detached, err := m.conn.Onions()
+	if err != nil {
+		return nil, err
+	}
+	l := &Listing{Current: nonNil(current), Detached: nonNil(detached), Known: []Service{}}
+	for _, s := range m.services {
+		s.PrivateKey = ""
+		l.Known = append(l.Known, s)
+	}
+	return l, nil
+}
+
+// Remove deletes a service from tor and forgets it. A service tor does not
+// know (552 Unknown Onion Service) is forgotten as well.
+func (m *Manager) Remove(serviceID string) error {
+	serviceID = strings.TrimSuffix(serviceID, ".onion")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conn == nil {
+		return errors.New("control port not connected")
+	}
+	err := m.conn.DelOnion(serviceID)
+	var re *torctl.ReplyError
+	if errors.As(err, &re) && re.Status == 552 {
+		err = nil // tor no longer has it (e.g. after a restart); forget it anyway
+	}
+	if err != nil {
+		return err
+	}
+	delete(m.services, serviceID)
+	return m.save()
+}
+
+// save writes services that keep their key; the caller holds m.mu
+func (m *Manager) save() error {
+	list := []Service{}
+	for _, s := range m.services {
+		if s.Persist {
+			list = append(list, s)
+		}
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, data, 0600)
+}
+
+func without(list []string, drop string) []string {
+	out := list[:0:0]
+	for _, s := range list {
+		if s != drop {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func nonNil(vs []string) []string {
+	if vs == nil {
+		return []string{}
+	}
+	return vs
+}
@@ -0,0 +1,57 @@
+package onion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tor-admin/internal/instance"
+	"tor-admin/internal/torctl"
+	"tor-admin/internal/torctl/torctltest"
+)
+
+func TestManagerRemove(t *testing.T) {
+	srv := torctltest.New(t)
+	srv.Handle("DEL_ONION", func(args string) string {
+		switch args {
+		case "gone":
+			return "552 Unknown Onion Service id\r\n"
+		case "busy":
+			return "551 Internal error\r\n"
+		}
+		return "250 OK\r\n"
+	})
+	path := filepath.Join(t.TempDir(), "ephemeral.json")
+	m, err := NewManager(path, &instance.Instance{ID: "default", ControlAddr: srv.Addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.conn, err = torctl.DialAuth(srv.Addr, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer m.conn.Close()
+	for _, id := range []string{"live", "gone", "busy"} {
+		m.services[id] = Service{ServiceID: id, Persist: true}
+	}
+
+	if err := m.Remove("live.onion"); err != nil {
+		t.Errorf("Remove(live): %v", err)
+	}
+	if err := m.Remove("gone"); err != nil {
+		t.Errorf("Remove(gone) = %v, want the 552 treated as removed", err)
+	}
+	if err := m.Remove("busy"); err == nil {
+		t.Error("Remove(busy) should report tor's error")
+	}
+	if _, ok := m.services["gone"]; ok {
+		t.Error("unknown service is still remembered")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); strings.Contains(got, "gone") || strings.Contains(got, "live") || !strings.Contains(got, "busy") {
+		t.Errorf("persisted services = %s, want only busy", got)
+	}
+}
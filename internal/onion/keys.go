@@ -0,0 +1,88 @@
+// File: internal/onion/keys.go
+// Purpose: Write and read tor's on-disk v3 onion service key files
+
+package onion
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"filippo.io/edwards25519"
+)
+
+var (
+	secretKeyHeader = []byte("== ed25519v1-secret: type0 ==\x00\x00\x00")
+	publicKeyHeader = []byte("== ed25519v1-public: type0 ==\x00\x00\x00")
+)
+
+// expandSeed turns an ed25519 seed into tor's 64-byte expanded secret key
+// (clamped scalar followed by the hash prefix)
+func expandSeed(seed []byte) []byte {
+	h := sha512.Sum512(seed)
+	h[0] &= 248
+	h[31] &= 127
+	h[31] |= 64
+	return h[:]
+}
+
+// publicFromExpanded derives the public key from tor's expanded secret key
+// by multiplying the base point by its scalar half
+func publicFromExpanded(expanded []byte) ([]byte, error) {
+	if len(expanded) != 64 {
+		return nil, errors.New("invalid expanded secret key")
+	}
+	s, err := edwards25519.NewScalar().SetBytesWithClamping(expanded[:32])
+	if err != nil {
+		return nil, err
+	}
+	return new(edwards25519.Point).ScalarBaseMult(s).Bytes(), nil
+}
+
+// WriteServiceKeys creates dir (0700) with hs_ed25519_secret_key,
+// hs_ed25519_public_key and hostname for the key derived from seed.
+// dir must not already hold a key.
+func WriteServiceKeys(dir string, seed []byte) (string, error) {
+	if len(seed) != ed25519.SeedSize {
+		return "", errors.New("invalid ed25519 seed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hs_ed25519_secret_key")); err == nil {
+		return "", errors.New("directory already contains an onion key")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	_ = os.Chmod(dir, 0700)
+	matchOwner(dir, filepath.Dir(dir))
+
+	pub := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	addr := AddressFromPublicKey(pub) + ".onion"
+	files := map[string][]byte{
+		"hs_ed25519_secret_key": append(append([]byte{}, secretKeyHeader...), expandSeed(seed)...),
+		"hs_ed25519_public_key": append(append([]byte{}, publicKeyHeader...), pub...),
+		"hostname":              []byte(addr + "\n"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return "", err
+		}
+		matchOwner(path, dir)
+	}
+	return addr, nil
+}
+
+// ReadPublicKey loads hs_ed25519_public_key from a service directory
+func ReadPublicKey(dir string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "hs_ed25519_public_key"))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, publicKeyHeader) || len(data) != len(publicKeyHeader)+ed25519.PublicKeySize {
+		return nil, errors.New("hs_ed25519_public_key has an unexpected format")
+	}
+	return ed25519.PublicKey(data[len(publicKeyHeader):]), nil
+}
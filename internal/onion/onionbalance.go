@@ -0,0 +1,137 @@
+// File: internal/onion/onionbalance.go
+// Purpose: Generate OnionBalance v3 config and mark tor-admin managed services as its backends
+
+package onion
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tor-admin/internal/config"
+)
+
+// BalanceBackend is one backend instance behind the frontend address
+type BalanceBackend struct {
+	Name    string `json:"name"`
+	Dir     string `json:"dir"`
+	Address string `json:"address"`
+}
+
+// FrontendKey is the frontend identity in a form OnionBalance can load
+type FrontendKey struct {
+	Address  string `json:"address"`
+	Filename string `json:"filename"` // "<address>.key", referenced from config.yaml
+	Data     []byte `json:"data"`
+}
+
+// GenerateFrontendKey creates a new frontend identity as a PKCS#8 PEM key,
+// the format onionbalance-config writes
+func GenerateFrontendKey() (*FrontendKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	addr := AddressFromPublicKey(pub)
+	return &FrontendKey{
+		Address:  addr + ".onion",
+		Filename: addr + ".key",
+		Data:     pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+	}, nil
+}
+
+// FrontendKeyFromDir exports an existing service's hs_ed25519_secret_key,
+// which OnionBalance accepts as-is
+func FrontendKeyFromDir(dir string) (*FrontendKey, error) {
+	pub, err := ReadPublicKey(dir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "hs_ed25519_secret_key"))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(string(data), string(secretKeyHeader)) || len(data) != len(secretKeyHeader)+64 {
+		return nil, errors.New("hs_ed25519_secret_key has an unexpected format")
+	}
+	addr := AddressFromPublicKey(pub)
+	return &FrontendKey{Address: addr + ".onion", Filename: addr + ".key", Data: data}, nil
+}
+
+// BalanceBackends resolves and validates the backend services in dirs
+func BalanceBackends(torrcPath string, dirs []string, frontend string) ([]BalanceBackend, error) {
+	if len(dirs) == 0 {
+		return nil, errors.New("at least one backend is required")
+	}
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var out []BalanceBackend
+	for i, dir := range dirs {
+		if _, ok := tc.HiddenService(dir); !ok {
+			return nil, fmt.Errorf("%s is not a configured HiddenServiceDir", dir)
+		}
+		hostname, err := os.ReadFile(filepath.Join(dir, "hostname"))
+		if err != nil {
+			return nil, fmt.Errorf("%s has no hostname yet; start tor once to create its keys", dir)
+		}
+		addr := strings.TrimSpace(string(hostname))
+		if _, err := ParseAddress(addr); err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		if addr == frontend {
+			return nil, fmt.Errorf("%s is the frontend and cannot also be a backend", dir)
+		}
+		if seen[addr] {
+			return nil, fmt.Errorf("%s is listed twice", addr)
+		}
+		seen[addr] = true
+		out = append(out, BalanceBackend{Name: fmt.Sprintf("node%d", i+1), Dir: dir, Address: addr})
+	}
+	return out, nil
+}
+
+// BalanceConfig renders OnionBalance's config.yaml for one frontend
+func BalanceConfig(key *FrontendKey, backends []BalanceBackend) string {
+	var b strings.Builder
+	b.WriteString("services:\n")
+	b.WriteString("- instances:\n")
+	for _, be := range backends {
+		fmt.Fprintf(&b, "  - address: %s\n    name: %s\n", be.Address, be.Name)
+	}
+	fmt.Fprintf(&b, "  key: %s\n", key.Filename)
+	return b.String()
+}
+
+// EnableBalanceInstances sets HiddenServiceOnionbalanceInstance in each
+// backend block and writes the ob_config file tor reads the frontend from.
+// The caller reloads tor.
+func EnableBalanceInstances(torrcPath string, backends []BalanceBackend, frontend string) error {
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return err
+	}
+	for _, be := range backends {
+		if err := tc.SetHiddenServiceOption(be.Dir, "HiddenServiceOnionbalanceInstance", "1"); err != nil {
+			return err
+		}
+		path := filepath.Join(be.Dir, "ob_config")
+		if err := os.WriteFile(path, []byte("MasterOnionAddress "+frontend+"\n"), 0600); err != nil {
+			return err
+		}
+		matchOwner(path, be.Dir)
+	}
+	return tc.Save(torrcPath)
+}
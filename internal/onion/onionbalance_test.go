@@ -0,0 +1,108 @@
+package onion
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tor-admin/internal/config"
+)
+
+func TestGenerateFrontendKey(t *testing.T) {
+	key, err := GenerateFrontendKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(key.Data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatalf("not a PKCS#8 PEM key: %q", key.Data)
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := AddressFromPublicKey(priv.(ed25519.PrivateKey).Public().(ed25519.PublicKey))
+	if key.Address != addr+".onion" || key.Filename != addr+".key" {
+		t.Errorf("key = %s / %s, want %s", key.Address, key.Filename, addr)
+	}
+}
+
+func TestOnionBalance(t *testing.T) {
+	root := t.TempDir()
+	torrc := filepath.Join(root, "torrc")
+	var conf strings.Builder
+	var dirs, hosts []string
+	for i, name := range []string{"front", "node-a", "node-b", "unlisted"} {
+		dir := filepath.Join(root, name)
+		host, err := WriteServiceKeys(dir, bytes.Repeat([]byte{byte(i + 1)}, 32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dirs, hosts = append(dirs, dir), append(hosts, host)
+		if name != "unlisted" {
+			fmt.Fprintf(&conf, "HiddenServiceDir %s\nHiddenServicePort 80 127.0.0.1:%d\n", dir, 8080+i)
+		}
+	}
+	if err := os.WriteFile(torrc, []byte(conf.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := FrontendKeyFromDir(dirs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := os.ReadFile(filepath.Join(dirs[0], "hs_ed25519_secret_key"))
+	if key.Address != hosts[0] || key.Filename != strings.TrimSuffix(hosts[0], ".onion")+".key" || !bytes.Equal(key.Data, secret) {
+		t.Errorf("frontend key = %s / %s", key.Address, key.Filename)
+	}
+
+	for _, bad := range [][]string{nil, {dirs[0]}, {dirs[1], dirs[1]}, {dirs[3]}} {
+		if _, err := BalanceBackends(torrc, bad, key.Address); err == nil {
+			t.Errorf("BalanceBackends(%v) was accepted", bad)
+		}
+	}
+	backends, err := BalanceBackends(torrc, dirs[1:3], key.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "services:\n" +
+		"- instances:\n" +
+		"  - address: " + hosts[1] + "\n    name: node1\n" +
+		"  - address: " + hosts[2] + "\n    name: node2\n" +
+		"  key: " + key.Filename + "\n"
+	if got := BalanceConfig(key, backends); got != want {
+		t.Errorf("config.yaml =\n%s\nwant\n%s", got, want)
+	}
+
+	if err := EnableBalanceInstances(torrc, backends, key.Address); err != nil {
+		t.Fatal(err)
+	}
+	tc, err := config.LoadTorrc(torrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, dir := range dirs[:3] {
+		b, _ := tc.HiddenService(dir)
+		v, ok := b.Get("HiddenServiceOnionbalanceInstance")
+		ob, obErr := os.ReadFile(filepath.Join(dir, "ob_config"))
+		if i == 0 {
+			if ok || obErr == nil {
+				t.Errorf("frontend was marked as a backend")
+			}
+			continue
+		}
+		if !ok || v != "1" {
+			t.Errorf("%s: HiddenServiceOnionbalanceInstance = %q, %v", dir, v, ok)
+		}
+		if string(ob) != "MasterOnionAddress "+key.Address+"\n" {
+			t.Errorf("%s: ob_config = %q, %v", dir, ob, obErr)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+// File: internal/onion/owner_other.go
+// Purpose: File ownership stub for platforms without Unix uids
+
+//go:build !unix
+
+package onion
+
+func fileOwner(path string) (int, int, bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,23 @@
+// File: internal/onion/owner_unix.go
+// Purpose: Look up file ownership on Unix systems
+
+//go:build unix
+
+package onion
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileOwner(path string) (int, int, bool) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
@@ -0,0 +1,64 @@
+// File: internal/onion/service.go
+// Purpose: List onion services configured in the torrc together with their on-disk state
+
+package onion
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tor-admin/internal/config"
+)
+
+// HiddenService describes one HiddenServiceDir block
+type HiddenService struct {
+	Dir      string   `json:"dir"`
+	Hostname string   `json:"hostname,omitempty"` // empty until tor has created the keys
+	Ports    []string `json:"ports"`
+	Clients  int      `json:"clients"` // authorized_clients/*.auth files
+}
+
+// Services reads the torrc and each service's hostname file
+func Services(torrcPath string) ([]HiddenService, error) {
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return nil, err
+	}
+	out := []HiddenService{}
+	for _, b := range tc.HiddenServices() {
+		s := HiddenService{Dir: b.Dir, Ports: b.GetAll("HiddenServicePort")}
+		if s.Ports == nil {
+			s.Ports = []string{}
+		}
+		if data, err := os.ReadFile(filepath.Join(b.Dir, "hostname")); err == nil {
+			s.Hostname = strings.TrimSpace(string(data))
+		}
+		if clients, err := ListClients(b.Dir); err == nil {
+			s.Clients = len(clients)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// ConfiguredDir checks that dir is a HiddenServiceDir in the torrc, so API
+// callers can only touch directories tor already owns
+func ConfiguredDir(torrcPath, dir string) error {
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return err
+	}
+	if _, ok := tc.HiddenService(dir); !ok {
+		return errors.New("not a configured HiddenServiceDir")
+	}
+	return nil
+}
+
+// matchOwner gives path the owner of dir so tor (often a different user) can read it
+func matchOwner(path, dir string) {
+	if uid, gid, ok := fileOwner(dir); ok {
+		_ = os.Lchown(path, uid, gid)
+	}
+}
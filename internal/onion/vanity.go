@@ -0,0 +1,247 @@
+// File: internal/onion/vanity.go
+// Purpose: Background search for v3 onion addresses with a chosen prefix
+
+package onion
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxVanityPrefix bounds the search; each extra character is 32x more work
+const MaxVanityPrefix = 10
+
+var vanityPrefixPattern = regexp.MustCompile(`^[a-z2-7]+$`)
+
+// Vanity job states
+const (
+	VanityRunning   = "running"
+	VanityFound     = "found"
+	VanityCancelled = "cancelled"
+	VanityImported  = "imported"
+)
+
+type vanityJob struct {
+	id       string
+	prefix   string
+	started  time.Time
+	workers  int
+	attempts atomic.Uint64
+	cancel   context.CancelFunc
+
+	mu       sync.Mutex
+	state    string
+	finished time.Time
+	seed     []byte // found key, kept in memory only until imported
+	address  string
+	dir      string
+}
+
+// VanityStatus is a snapshot of one job
+type VanityStatus struct {
+	ID          string    `json:"id"`
+	Prefix      string    `json:"prefix"`
+	State       string    `json:"state"`
+	Workers     int       `json:"workers"`
+	Started     time.Time `json:"started"`
+	Attempts    uint64    `json:"attempts"`
+	KeysPerSec  float64   `json:"keys_per_sec"`
+	Expected    float64   `json:"expected_attempts"`
+	Probability float64   `json:"probability"`           // chance a match would have been found by now
+	ETASeconds  float64   `json:"eta_seconds,omitempty"` // expected time to a match from now
+	Address     string    `json:"address,omitempty"`
+	Dir         string    `json:"dir,omitempty"`
+}
+
+// Vanity runs and tracks vanity address searches
+type Vanity struct {
+	mu   sync.Mutex
+	jobs map[string]*vanityJob
+}
+
+// NewVanity creates an empty job runner
+func NewVanity() *Vanity {
+	return &Vanity{jobs: map[string]*vanityJob{}}
+}
+
+// Start launches a search on every CPU core. Only one search runs at a time.
+func (v *Vanity) Start(prefix string) (*VanityStatus, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if !vanityPrefixPattern.MatchString(prefix) {
+		return nil, errors.New("prefix may only contain a-z and 2-7")
+	}
+	if len(prefix) > MaxVanityPrefix {
+		return nil, errors.New("prefix is too long to find in reasonable time")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, j := range v.jobs {
+		if j.status().State == VanityRunning {
+			return nil, errors.New("a vanity search is already running")
+		}
+	}
+	idBytes := make([]byte, 6)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &vanityJob{
+		id:      hex.EncodeToString(idBytes),
+		prefix:  prefix,
+		started: time.Now(),
+		workers: runtime.NumCPU(),
+		cancel:  cancel,
+		state:   VanityRunning,
+	}
+	v.jobs[j.id] = j
+	for i := 0; i < j.workers; i++ {
+		go j.search(ctx)
+	}
+	st := j.status()
+	return &st, nil
+}
+
+// search tries consecutive seeds from a random starting point until a match or cancel
+func (j *vanityJob) search(ctx context.Context) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		j.stop(VanityCancelled)
+		return
+	}
+	// only the first 5 bits per prefix character need encoding to compare
+	n := (len(j.prefix)*5 + 7) / 8
+	buf := make([]byte, addressEncoding.EncodedLen(n))
+	want := strings.ToUpper(j.prefix)
+	const batch = 1024
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		for i := 0; i < batch; i++ {
+			binary.LittleEndian.PutUint64(seed[24:], binary.LittleEndian.Uint64(seed[24:])+1)
+			pub := ed25519.NewKeyFromSeed(seed)[32:]
+			addressEncoding.Encode(buf, pub[:n])
+			if string(buf[:len(want)]) == want {
+				j.attempts.Add(uint64(i + 1))
+				j.found(seed)
+				return
+			}
+		}
+		j.attempts.Add(batch)
+	}
+}
+
+func (j *vanityJob) found(seed []byte) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state != VanityRunning {
+		return
+	}
+	j.seed = append([]byte(nil), seed...)
+	pub := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	j.address = AddressFromPublicKey(pub) + ".onion"
+	j.state = VanityFound
+	j.finished = time.Now()
+	j.cancel()
+}
+
+func (j *vanityJob) stop(state string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state == VanityRunning {
+		j.state = state
+		j.finished = time.Now()
+	}
+	j.cancel()
+}
+
+func (j *vanityJob) status() VanityStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := VanityStatus{
+		ID: j.id, Prefix: j.prefix, State: j.state, Workers: j.workers, Started: j.started,
+		Attempts: j.attempts.Load(), Address: j.address, Dir: j.dir,
+		Expected: math.Pow(32, float64(len(j.prefix))),
+	}
+	end := time.Now()
+	if !j.finished.IsZero() {
+		end = j.finished
+	}
+	if secs := end.Sub(j.started).Seconds(); secs > 0 {
+		st.KeysPerSec = float64(st.Attempts) / secs
+	}
+	// Each try succeeds independently, so the expected remaining work is always
+	// the full expectation; progress is the chance of having succeeded already
+	st.Probability = 1 - math.Exp(-float64(st.Attempts)/st.Expected)
+	if st.State == VanityRunning && st.KeysPerSec > 0 {
+		st.ETASeconds = st.Expected / st.KeysPerSec
+	}
+	return st
+}
+
+// List returns all jobs, newest first
+func (v *Vanity) List() []VanityStatus {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	out := []VanityStatus{}
+	for _, j := range v.jobs {
+		out = append(out, j.status())
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Started.After(out[b].Started) })
+	return out
+}
+
+// Cancel stops a running job, or forgets a finished one along with its key
+func (v *Vanity) Cancel(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	j, ok := v.jobs[id]
+	if !ok {
+		return errors.New("no such vanity job")
+	}
+	if j.status().State == VanityRunning {
+		j.stop(VanityCancelled)
+		return nil
+	}
+	delete(v.jobs, id)
+	return nil
+}
+
+// Import writes the found key into dir as a new onion service directory and
+// returns the address. The caller adds the HiddenServiceDir block to the torrc.
+func (v *Vanity) Import(id, dir string) (string, error) {
+	v.mu.Lock()
+	j, ok := v.jobs[id]
+	v.mu.Unlock()
+	if !ok {
+		return "", errors.New("no such vanity job")
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state != VanityFound {
+		return "", errors.New("job has no unimported key")
+	}
+	addr, err := WriteServiceKeys(dir, j.seed)
+	if err != nil {
+		return "", err
+	}
+	for i := range j.seed {
+		j.seed[i] = 0
+	}
+	j.seed, j.state, j.dir = nil, VanityImported, dir
+	return addr, nil
+}
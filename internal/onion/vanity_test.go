@@ -0,0 +1,84 @@
+package onion
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddressRoundTrip(t *testing.T) {
+	pub, err := ParseAddress(testOnion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := AddressFromPublicKey(pub) + ".onion"; got != testOnion {
+		t.Errorf("AddressFromPublicKey = %s, want %s", got, testOnion)
+	}
+}
+
+func TestVanityPrefix(t *testing.T) {
+	v := NewVanity()
+	for _, bad := range []string{"", "ab1", "abc.", strings.Repeat("a", MaxVanityPrefix+1)} {
+		if _, err := v.Start(bad); err == nil {
+			t.Errorf("Start(%q) was accepted", bad)
+		}
+	}
+
+	st, err := v.Start(" AB ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		var job *VanityStatus
+		for _, s := range v.List() {
+			if s.ID == st.ID {
+				job = &s
+			}
+		}
+		if job.State == VanityFound {
+			st = job
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no match after %d attempts", job.Attempts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.HasPrefix(st.Address, "ab") || !strings.HasSuffix(st.Address, ".onion") {
+		t.Fatalf("found %s, want an ab… address", st.Address)
+	}
+
+	dir := filepath.Join(t.TempDir(), "hs")
+	addr, err := v.Import(st.ID, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != st.Address {
+		t.Errorf("imported %s, found %s", addr, st.Address)
+	}
+	pub, err := ReadPublicKey(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := AddressFromPublicKey(pub) + ".onion"; got != addr {
+		t.Errorf("public key gives %s, want %s", got, addr)
+	}
+	secret, err := os.ReadFile(filepath.Join(dir, "hs_ed25519_secret_key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	derived, err := publicFromExpanded(secret[len(secretKeyHeader):])
+	if err != nil || !ed25519.PublicKey(derived).Equal(pub) {
+		t.Errorf("secret key does not belong to the public key: %v", err)
+	}
+	if host, _ := os.ReadFile(filepath.Join(dir, "hostname")); string(host) != addr+"\n" {
+		t.Errorf("hostname = %q", host)
+	}
+	if _, err := v.Import(st.ID, filepath.Join(t.TempDir(), "again")); err == nil {
+		t.Error("a key was imported twice")
+	}
+}
@@ -0,0 +1,242 @@
+// File: internal/proxy/proxy.go
+// Purpose: Reverse proxy sites that sit behind a HiddenServicePort and advertise it with Onion-Location
+
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var siteNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Site is one proxied website tied to an onion service
+type Site struct {
+	Name        string `json:"name"`
+	Listen      string `json:"listen"`       // e.g. "127.0.0.1:8081"; the HiddenServicePort target
+	Upstream    string `json:"upstream"`     // e.g. "http://127.0.0.1:3000"
+	Dir         string `json:"dir"`          // HiddenServiceDir whose hostname is advertised
+	OnionScheme string `json:"onion_scheme"` // scheme in the Onion-Location URL, "http" by default
+}
+
+// Validate checks the site's fields
+func (s Site) Validate() error {
+	if !siteNamePattern.MatchString(s.Name) {
+		return errors.New("name must be 1-64 letters, digits, '-' or '_'")
+	}
+	if _, _, err := net.SplitHostPort(s.Listen); err != nil {
+		return errors.New("listen must be host:port")
+	}
+	u, err := url.Parse(s.Upstream)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("upstream must be an http(s) URL")
+	}
+	if s.Dir == "" {
+		return errors.New("dir is required")
+	}
+	if s.OnionScheme != "" && s.OnionScheme != "http" && s.OnionScheme != "https" {
+		return errors.New("onion_scheme must be http or https")
+	}
+	return nil
+}
+
+// onionHost reads the service's current address; empty until tor creates it
+func (s Site) onionHost() string {
+	data, err := os.ReadFile(filepath.Join(s.Dir, "hostname"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Handler forwards to the upstream and adds Onion-Location to responses for
+// requests that did not arrive over the onion address. An unreachable
+// upstream is answered with 502.
+func Handler(s Site) (http.Handler, error) {
+	upstream, err := url.Parse(s.Upstream)
+	if err != nil {
+		return nil, err
+	}
+	scheme := s.OnionScheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	rp := httputil.NewSingleHostReverseProxy(upstream)
+	rp.ModifyResponse = func(resp *http.Response) error {
+		host := resp.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.HasSuffix(host, ".onion") {
+			return nil
+		}
+		if onion := s.onionHost(); onion != "" {
+			resp.Header.Set("Onion-Location", scheme+"://"+onion+resp.Request.URL.RequestURI())
+		}
+		return nil
+	}
+	rp.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log.Printf("proxy %s: %s %s: %v", s.Name, r.Method, r.URL.Path, err)
+		http.Error(w, "Upstream unavailable", http.StatusBadGateway)
+	}
+	return rp, nil
+}
+
+// Manager stores sites and runs one listener per site
+type Manager struct {
+	path string // JSON file with the site list
+
+	mu      sync.Mutex
+	sites   map[string]Site
+	servers map[string]*http.Server
+	errs    map[string]string // listener failures by site name
+}
+
+// NewManager loads sites stored at path
+func NewManager(path string) (*Manager, error) {
+	m := &Manager{path: path, sites: map[string]Site{}, servers: map[string]*http.Server{}, errs: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Site
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	for _, s := range list {
+		m.sites[s.Name] = s
+	}
+	return m, nil
+}
+
+// Start launches listeners for every stored site
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sites {
+		m.start(s)
+	}
+}
+
+// start runs one site's listener; the caller holds m.mu
+func (m *Manager) start(s Site) {
+	h, err := Handler(s)
+	if err == nil {
+		var ln net.Listener
+		if ln, err = net.Listen("tcp", s.Listen); err == nil {
+			srv := &http.Server{Handler: h, ReadHeaderTimeout: 30 * time.Second}
+			m.servers[s.Name] = srv
+			delete(m.errs, s.Name)
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Printf("proxy %s: %v", s.Name, err)
+				}
+			}()
+			log.Printf("🔁 proxy %s listening on %s → %s", s.Name, s.Listen, s.Upstream)
+			return
+		}
+	}
+	m.errs[s.Name] = err.Error()
+	log.Printf("proxy %s: %v", s.Name, err)
+}
+
+// stop shuts a site's listener down; the caller holds m.mu
+func (m *Manager) stop(name string) {
+	if srv, ok := m.servers[name]; ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = srv.Shutdown(ctx)
+		cancel()
+		delete(m.servers, name)
+	}
+	delete(m.errs, name)
+}
+
+// SiteStatus is a site with its listener state and advertised address
+type SiteStatus struct {
+	Site
+	Onion   string `json:"onion,omitempty"`
+	Running bool   `json:"running"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Sites lists stored sites by name
+func (m *Manager) Sites() []SiteStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []SiteStatus{}
+	for name, s := range m.sites {
+		_, running := m.servers[name]
+		out = append(out, SiteStatus{Site: s, Onion: s.onionHost(), Running: running, Error: m.errs[name]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Put adds or replaces a site and restarts its listener
+func (m *Manager) Put(s Site) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, other := range m.sites {
+		if name != s.Name && other.Listen == s.Listen {
+			return errors.New("another site already listens on " + s.Listen)
+		}
+	}
+	m.stop(s.Name)
+	m.sites[s.Name] = s
+	if err := m.save(); err != nil {
+		return err
+	}
+	m.start(s)
+	if e := m.errs[s.Name]; e != "" {
+		return errors.New("saved, but the listener failed: " + e)
+	}
+	return nil
+}
+
+// Delete stops and forgets a site
+func (m *Manager) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sites[name]; !ok {
+		return errors.New("no such site")
+	}
+	m.stop(name)
+	delete(m.sites, name)
+	return m.save()
+}
+
+// save writes the site list; the caller holds m.mu
+func (m *Manager) save() error {
+	list := make([]Site, 0, len(m.sites))
+	for _, s := range m.sites {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, data, 0600)
+}
@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOnion = "2gzyxa5ihm7nsggfxnu52rck2vv4rvmdlkiu3zzui5du4xyclen53wid.onion"
+
+// upstream echoes what it received so tests can see what was forwarded
+func upstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Seen-Host", r.Host)
+		w.Header().Set("X-Seen-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Seen-Custom", r.Header.Get("X-Custom"))
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// serviceDir returns a HiddenServiceDir holding hostname, or none when empty
+func serviceDir(t *testing.T, hostname string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if hostname != "" {
+		if err := os.WriteFile(filepath.Join(dir, "hostname"), []byte(hostname+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func serve(t *testing.T, s Site, method, target, host string, body io.Reader) *http.Response {
+	t.Helper()
+	h, err := Handler(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, target, body)
+	req.Host = host
+	req.Header.Set("X-Custom", "kept")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+func TestHandlerForwards(t *testing.T) {
+	up := upstream(t)
+	site := Site{Name: "blog", Listen: "127.0.0.1:0", Upstream: up.URL, Dir: serviceDir(t, testOnion)}
+
+	resp := serve(t, site, http.MethodPost, "/posts/1?draft=yes", "blog.example.org", strings.NewReader("hello"))
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("status = %d, want upstream's 418", resp.StatusCode)
+	}
+	if string(body) != "POST /posts/1?draft=yes hello" {
+		t.Errorf("upstream saw %q", body)
+	}
+	if got := resp.Header.Get("X-Seen-Host"); got != "blog.example.org" {
+		t.Errorf("upstream Host = %q, want the original host", got)
+	}
+	if got := resp.Header.Get("X-Seen-Forwarded-For"); got != "192.0.2.1" {
+		t.Errorf("X-Forwarded-For = %q", got)
+	}
+	if got := resp.Header.Get("X-Seen-Custom"); got != "kept" {
+		t.Errorf("request header not forwarded: %q", got)
+	}
+}
+
+func TestHandlerOnionLocation(t *testing.T) {
+	up := upstream(t)
+	tests := []struct {
+		name   string
+		scheme string
+		dir    string
+		host   string
+		want   string
+	}{
+		{"clearnet request", "", serviceDir(t, testOnion), "blog.example.org", "http://" + testOnion + "/a/b?c=d"},
+		{"clearnet request with port", "", serviceDir(t, testOnion), "blog.example.org:8443", "http://" + testOnion + "/a/b?c=d"},
+		{"https onion scheme", "https", serviceDir(t, testOnion), "blog.example.org", "https://" + testOnion + "/a/b?c=d"},
+		{"request over the onion", "", serviceDir(t, testOnion), testOnion, ""},
+		{"request over the onion with port", "", serviceDir(t, testOnion), testOnion + ":80", ""},
+		{"no hostname yet", "", serviceDir(t, ""), "blog.example.org", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := Site{Name: "blog", Listen: "127.0.0.1:0", Upstream: up.URL, Dir: tt.dir, OnionScheme: tt.scheme}
+			resp := serve(t, site, http.MethodGet, "/a/b?c=d", tt.host, nil)
+			if got := resp.Header.Get("Onion-Location"); got != tt.want {
+				t.Errorf("Onion-Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerUpstreamDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	site := Site{Name: "blog", Listen: "127.0.0.1:0", Upstream: "http://" + addr, Dir: serviceDir(t, testOnion)}
+	resp := serve(t, site, http.MethodGet, "/", "blog.example.org", nil)
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", resp.StatusCode)
+	}
+	if !strings.Contains(string(body), "Upstream unavailable") {
+		t.Errorf("body = %q", body)
+	}
+	if got := resp.Header.Get("Onion-Location"); got != "" {
+		t.Errorf("error response advertised %q", got)
+	}
+}
+
+func TestManager(t *testing.T) {
+	up := upstream(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen := ln.Addr().String()
+	ln.Close()
+
+	path := filepath.Join(t.TempDir(), "proxy.json")
+	m, err := NewManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	site := Site{Name: "blog", Listen: listen, Upstream: up.URL, Dir: serviceDir(t, testOnion)}
+	if err := m.Put(site); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Delete("blog") })
+
+	resp, err := http.Get("http://" + listen + "/hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot || resp.Header.Get("Onion-Location") != "http://"+testOnion+"/hi" {
+		t.Errorf("proxied response = %d, Onion-Location %q", resp.StatusCode, resp.Header.Get("Onion-Location"))
+	}
+
+	other := site
+	other.Name = "shop"
+	if err := m.Put(other); err == nil {
+		t.Error("second site on the same listen address was accepted")
+	}
+	if sites := m.Sites(); len(sites) != 1 || !sites[0].Running || sites[0].Onion != testOnion {
+		t.Errorf("Sites = %+v", sites)
+	}
+
+	reloaded, err := NewManager(path)
+	if err != nil || len(reloaded.Sites()) != 1 {
+		t.Fatalf("reloaded sites = %v, %v", reloaded.Sites(), err)
+	}
+
+	if err := m.Delete("blog"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := http.Get("http://" + listen + "/hi"); err == nil {
+		t.Error("listener still up after Delete")
+	}
+	if err := m.Delete("blog"); err == nil {
+		t.Error("deleting a missing site succeeded")
+	}
+}
@@ -0,0 +1,318 @@
+// File: internal/scheduler/scheduler.go
+// Purpose: Apply the weekly bandwidth schedule live via SETCONF, optionally persisting to torrc
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"tor-admin/internal/bandwidth"
+	"tor-admin/internal/config"
+	"tor-admin/internal/instance"
+	"tor-admin/internal/metrics"
+)
+
+// stored is the schedule file: the schedule plus the torrc values it
+// displaced, so turning it off can put them back. A nil baseline value means
+// the torrc did not set the option; Written holds what a persisting schedule
+// last wrote, so restore leaves lines edited since then alone.
+type stored struct {
+	bandwidth.Schedule
+	Baseline map[string]*string `json:"baseline,omitempty"`
+	Written  map[string]string  `json:"written,omitempty"`
+}
+
+// Scheduler owns the stored schedule and pushes the active rate to one tor instance
+type Scheduler struct {
+	path string // JSON file holding the schedule
+	inst *instance.Instance
+
+	applyMu sync.Mutex // serializes Apply/restore so the ticker and the API cannot interleave
+
+	mu       sync.Mutex
+	schedule bandwidth.Schedule
+	baseline map[string]*string
+	written  map[string]string
+	applied  [2]string // rate/burst last pushed to tor
+}
+
+// New loads the schedule stored at path, if any
+func New(path string, inst *instance.Instance) (*Scheduler, error) {
+	s := &Scheduler{path: path, inst: inst}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st stored
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	s.schedule, s.baseline, s.written = st.Schedule, st.Baseline, st.Written
+	return s, nil
+}
+
+// Get returns a copy of the current schedule
+func (s *Scheduler) Get() bandwidth.Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.schedule
+}
+
+// Set validates and stores a new schedule; the next Apply pushes it to tor.
+// Disabling a schedule (or switching between relay and client rates) puts the
+// torrc's own values back right away.
+func (s *Scheduler) Set(sched bandwidth.Schedule) error {
+	if err := sched.Validate(); err != nil {
+		return err
+	}
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	s.mu.Lock()
+	prev := s.schedule
+	s.schedule = sched
+	s.applied = [2]string{}
+	s.mu.Unlock()
+	if err := s.save(); err != nil {
+		return err
+	}
+	if !sched.Enabled || sched.Relay != prev.Relay {
+		return s.restore()
+	}
+	return nil
+}
+
+// Delete restores the torrc's values and removes the stored schedule
+func (s *Scheduler) Delete() error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	if err := s.restore(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.schedule = bandwidth.Schedule{}
+	s.applied = [2]string{}
+	s.mu.Unlock()
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Scheduler) save() error {
+	s.mu.Lock()
+	st := stored{Schedule: s.schedule, Baseline: s.baseline, Written: s.written}
+	s.mu.Unlock()
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+// Run applies the schedule at the top of every minute until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		if err := s.Apply(time.Now()); err != nil {
+			log.Printf("bandwidth schedule (%s): %v", s.inst.ID, err)
+		}
+		wait := time.Until(time.Now().Truncate(time.Minute).Add(time.Minute))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Apply pushes the rate in effect at now, skipping the round trip when it
+// has not changed since the last successful push
+func (s *Scheduler) Apply(now time.Time) error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	s.mu.Lock()
+	sched := s.schedule
+	last := s.applied
+	s.mu.Unlock()
+	if !sched.Enabled {
+		return nil
+	}
+	rate, burst, err := canonicalRates(sched.At(now))
+	if err != nil {
+		return err
+	}
+	if last == [2]string{rate, burst} {
+		return nil
+	}
+
+	rateKey, burstKey := "BandwidthRate", "BandwidthBurst"
+	if sched.Relay {
+		rateKey, burstKey = "RelayBandwidthRate", "RelayBandwidthBurst"
+	}
+	tc, err := config.LoadTorrc(s.inst.Torrc)
+	if err != nil {
+		return err
+	}
+	if err := s.recordBaseline(tc, rateKey, burstKey); err != nil {
+		return err
+	}
+
+	ctl, err := s.inst.Dial()
+	if err != nil {
+		return err
+	}
+	defer ctl.Close()
+	if err := ctl.SetConf(map[string]string{rateKey: rate, burstKey: burst}); err != nil {
+		return err
+	}
+	log.Printf("bandwidth schedule (%s): %s=%s %s=%s", s.inst.ID, rateKey, rate, burstKey, burst)
+
+	if sched.Persist {
+		tc.Set(rateKey, rate)
+		tc.Set(burstKey, burst)
+		err = tc.Save(s.inst.Torrc)
+		metrics.ConfigSaves.Inc(metrics.Result(err))
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.written = map[string]string{rateKey: rate, burstKey: burst}
+		s.mu.Unlock()
+		if err := s.save(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.applied = [2]string{rate, burst}
+	s.mu.Unlock()
+	return nil
+}
+
+// canonicalRates rewrites a scheduled rate and burst in tor's own spelling
+// ("5 mb" becomes "5 MBytes") before they reach SETCONF or the torrc
+func canonicalRates(rate, burst string) (string, string, error) {
+	r, err := bandwidth.ParseRate(rate)
+	if err != nil {
+		return "", "", err
+	}
+	b, err := bandwidth.ParseRate(burst)
+	if err != nil {
+		return "", "", err
+	}
+	return r.String(), b.String(), nil
+}
+
+// recordBaseline remembers the torrc's values for keys the first time the
+// schedule takes them over
+func (s *Scheduler) recordBaseline(tc *config.TorConfig, keys ...string) error {
+	s.mu.Lock()
+	added := false
+	for _, k := range keys {
+		if _, ok := s.baseline[k]; ok {
+			continue
+		}
+		if s.baseline == nil {
+			s.baseline = map[string]*string{}
+		}
+		var v *string
+		if val, ok := tc.Get(k); ok {
+			v = &val
+		}
+		s.baseline[k] = v
+		added = true
+	}
+	s.mu.Unlock()
+	if !added {
+		return nil
+	}
+	return s.save()
+}
+
+// restore puts the displaced torrc values back, live and (where a persisting
+// schedule overwrote them) on disk; called with applyMu held
+func (s *Scheduler) restore() error {
+	s.mu.Lock()
+	baseline, written := s.baseline, s.written
+	s.mu.Unlock()
+	if len(baseline) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(baseline))
+	for k := range baseline {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ctl, err := s.inst.Dial()
+	if err != nil {
+		return err
+	}
+	defer ctl.Close()
+	set := map[string]string{}
+	var reset []string
+	for _, k := range keys {
+		if v := baseline[k]; v != nil {
+			set[k] = *v
+		} else {
+			reset = append(reset, k)
+		}
+	}
+	if len(set) > 0 {
+		if err := ctl.SetConf(set); err != nil {
+			return err
+		}
+	}
+	if len(reset) > 0 {
+		if err := ctl.ResetConf(reset...); err != nil {
+			return err
+		}
+	}
+
+	tc, err := config.LoadTorrc(s.inst.Torrc)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, k := range keys {
+		cur, ok := tc.Get(k)
+		if w, wrote := written[k]; !wrote || !ok || cur != w {
+			continue // not ours, or edited since the schedule wrote it
+		}
+		switch v := baseline[k]; {
+		case v == nil && ok:
+			tc.Unset(k)
+			changed = true
+		case v != nil && (!ok || cur != *v):
+			tc.Set(k, *v)
+			changed = true
+		}
+	}
+	if changed {
+		err = tc.Save(s.inst.Torrc)
+		metrics.ConfigSaves.Inc(metrics.Result(err))
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("bandwidth schedule (%s): restored torrc values for %v", s.inst.ID, keys)
+
+	s.mu.Lock()
+	s.baseline, s.written = nil, nil
+	s.mu.Unlock()
+	return s.save()
+}
@@ -0,0 +1,245 @@
+package scheduler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tor-admin/internal/bandwidth"
+	"tor-admin/internal/config"
+	"tor-admin/internal/instance"
+	"tor-admin/internal/torctl/torctltest"
+)
+
+// monday is 2026-10-19, a Monday, in UTC
+func monday(hour int) time.Time {
+	return time.Date(2026, 10, 19, hour, 30, 0, 0, time.UTC)
+}
+
+type fixture struct {
+	tor   *torctltest.Server
+	torrc string
+	path  string
+	sched *Scheduler
+}
+
+func newFixture(t *testing.T, torrc string) *fixture {
+	t.Helper()
+	dir := t.TempDir()
+	f := &fixture{tor: torctltest.New(t), torrc: filepath.Join(dir, "torrc"), path: filepath.Join(dir, "schedule.json")}
+	if err := os.WriteFile(f.torrc, []byte(torrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inst := &instance.Instance{ID: "test", Torrc: f.torrc, ControlAddr: f.tor.Addr}
+	s, err := New(f.path, inst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.sched = s
+	return f
+}
+
+// set stores sched, which restores the baseline when it turns the schedule off
+func (f *fixture) set(t *testing.T, sched bandwidth.Schedule) {
+	t.Helper()
+	if err := f.sched.Set(sched); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fixture) apply(t *testing.T, now time.Time) {
+	t.Helper()
+	if err := f.sched.Apply(now); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fixture) expectCommands(t *testing.T, want ...string) {
+	t.Helper()
+	if got := f.tor.Commands(); !reflect.DeepEqual(got, want) && !(len(got) == 0 && len(want) == 0) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	f.tor.Reset()
+}
+
+func (f *fixture) torrcValue(t *testing.T, key string) (string, bool) {
+	t.Helper()
+	tc, err := config.LoadTorrc(f.torrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tc.Get(key)
+}
+
+func eveningSchedule() bandwidth.Schedule {
+	return bandwidth.Schedule{
+		Enabled:      true,
+		DefaultRate:  "5 mb",
+		DefaultBurst: "10 megabytes",
+		Entries: []bandwidth.ScheduleEntry{
+			{Days: []string{"mon"}, StartHour: 18, EndHour: 24, Rate: "800 kbits", Burst: "1 mbits"},
+		},
+	}
+}
+
+func TestApplySendsCanonicalRatesOnlyWhenChanged(t *testing.T) {
+	f := newFixture(t, "SocksPort 9050\n")
+	f.set(t, eveningSchedule())
+	f.expectCommands(t)
+
+	f.apply(t, monday(9))
+	f.expectCommands(t, `SETCONF BandwidthBurst="10 MBytes" BandwidthRate="5 MBytes"`)
+
+	f.apply(t, monday(10))
+	f.expectCommands(t)
+
+	f.apply(t, monday(19))
+	f.expectCommands(t, `SETCONF BandwidthBurst="1 MBits" BandwidthRate="800 KBits"`)
+
+	if _, ok := f.torrcValue(t, "BandwidthRate"); ok {
+		t.Error("torrc written without Persist")
+	}
+}
+
+func TestApplyDisabledDoesNothing(t *testing.T) {
+	f := newFixture(t, "")
+	sched := eveningSchedule()
+	sched.Enabled = false
+	f.set(t, sched)
+	f.apply(t, monday(9))
+	f.expectCommands(t)
+}
+
+func TestApplyPersistWritesTorrc(t *testing.T) {
+	f := newFixture(t, "SocksPort 9050\nBandwidthRate 1 MBytes\n")
+	sched := eveningSchedule()
+	sched.Persist = true
+	f.set(t, sched)
+	f.apply(t, monday(20))
+	f.expectCommands(t, `SETCONF BandwidthBurst="1 MBits" BandwidthRate="800 KBits"`)
+
+	for key, want := range map[string]string{"BandwidthRate": "800 KBits", "BandwidthBurst": "1 MBits", "SocksPort": "9050"} {
+		if got, _ := f.torrcValue(t, key); got != want {
+			t.Errorf("torrc %s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestDisableRestoresBaseline(t *testing.T) {
+	f := newFixture(t, "BandwidthRate 1 MBytes\n")
+	sched := eveningSchedule()
+	sched.Persist = true
+	f.set(t, sched)
+	f.apply(t, monday(9))
+	f.tor.Reset()
+
+	sched.Enabled = false
+	f.set(t, sched)
+	f.expectCommands(t, `SETCONF BandwidthRate="1 MBytes"`, "RESETCONF BandwidthBurst")
+	if got, _ := f.torrcValue(t, "BandwidthRate"); got != "1 MBytes" {
+		t.Errorf("torrc BandwidthRate = %q, want the original 1 MBytes", got)
+	}
+	if got, ok := f.torrcValue(t, "BandwidthBurst"); ok {
+		t.Errorf("torrc BandwidthBurst = %q, want it removed again", got)
+	}
+
+	// Nothing left to restore the second time
+	f.set(t, sched)
+	f.expectCommands(t)
+}
+
+func TestRestoreKeepsLinesEditedSinceApply(t *testing.T) {
+	f := newFixture(t, "BandwidthRate 1 MBytes\n")
+	sched := eveningSchedule()
+	sched.Persist = true
+	f.set(t, sched)
+	f.apply(t, monday(9))
+	f.tor.Reset()
+
+	tc, err := config.LoadTorrc(f.torrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.Set("BandwidthRate", "3 MBytes")
+	if err := tc.Save(f.torrc); err != nil {
+		t.Fatal(err)
+	}
+
+	sched.Enabled = false
+	f.set(t, sched)
+	if got, _ := f.torrcValue(t, "BandwidthRate"); got != "3 MBytes" {
+		t.Errorf("torrc BandwidthRate = %q, want the hand-edited 3 MBytes", got)
+	}
+	if _, ok := f.torrcValue(t, "BandwidthBurst"); ok {
+		t.Error("BandwidthBurst written by the schedule was not removed")
+	}
+}
+
+func TestDeleteRestoresAndRemovesFile(t *testing.T) {
+	f := newFixture(t, "BandwidthBurst 2 MBytes\n")
+	f.set(t, eveningSchedule())
+	f.apply(t, monday(9))
+	f.tor.Reset()
+	if _, err := os.Stat(f.path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.sched.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	f.expectCommands(t, `SETCONF BandwidthBurst="2 MBytes"`, "RESETCONF BandwidthRate")
+	if _, err := os.Stat(f.path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("schedule file still present: %v", err)
+	}
+	if got := f.sched.Get(); got.Enabled || len(got.Entries) > 0 {
+		t.Errorf("schedule after delete = %+v", got)
+	}
+}
+
+func TestSwitchingToRelayRatesRestoresClientKeys(t *testing.T) {
+	f := newFixture(t, "BandwidthRate 1 MBytes\nBandwidthBurst 1 MBytes\n")
+	sched := eveningSchedule()
+	f.set(t, sched)
+	f.apply(t, monday(9))
+	f.tor.Reset()
+
+	sched.Relay = true
+	f.set(t, sched)
+	f.expectCommands(t, `SETCONF BandwidthBurst="1 MBytes" BandwidthRate="1 MBytes"`)
+
+	f.apply(t, monday(9))
+	f.expectCommands(t, `SETCONF RelayBandwidthBurst="10 MBytes" RelayBandwidthRate="5 MBytes"`)
+
+	sched.Relay = false
+	f.set(t, sched)
+	f.expectCommands(t, "RESETCONF RelayBandwidthBurst RelayBandwidthRate")
+}
+
+func TestBaselineSurvivesReload(t *testing.T) {
+	f := newFixture(t, "BandwidthRate 1 MBytes\n")
+	f.set(t, eveningSchedule())
+	f.apply(t, monday(9))
+	f.tor.Reset()
+
+	reloaded, err := New(f.path, f.sched.inst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	f.expectCommands(t, `SETCONF BandwidthRate="1 MBytes"`, "RESETCONF BandwidthBurst")
+}
+
+func TestSetRejectsInvalidSchedule(t *testing.T) {
+	f := newFixture(t, "")
+	sched := eveningSchedule()
+	sched.DefaultRate = "5 MB/s"
+	if err := f.sched.Set(sched); err == nil || !strings.Contains(err.Error(), "unknown bandwidth unit") {
+		t.Errorf("Set = %v, want an unknown unit error", err)
+	}
+}
@@ -1,14 +1,13 @@
 // File: internal/services/control.go
-// Purpose: Control the Tor service via systemctl or launchctl
+// Purpose: Control the Tor service through the detected init system
 
 package services
 
 import (
-	"bytes"
 	"errors"
-	"os/exec"
-	"runtime"
-	"strings"
+	"fmt"
+
+	"tor-admin/internal/metrics"
 )
 
 type Action string
@@ -17,67 +16,47 @@ const (
 	Start   Action = "start"
 	Stop    Action = "stop"
 	Restart Action = "restart"
+	Reload  Action = "reload"
 	Status  Action = "status"
 )
 
+// RunServiceAction runs action through the service's manager and
+// returns a one-line summary plus the raw output
 func RunServiceAction(serviceName string, action Action) (string, error) {
-	switch runtime.GOOS {
-	case "linux":
-		return runSystemctl(serviceName, action)
-	case "darwin":
-		return runLaunchctl(serviceName, action)
-	default:
-		return "", errors.New("unsupported OS")
+	st, err := ServiceAction(serviceName, action)
+	if st == nil {
+		return "", err
+	}
+	out := fmt.Sprintf("%s (%s): %s", st.Service, st.Manager, st.State)
+	if st.PID != 0 {
+		out += fmt.Sprintf(" pid %d", st.PID)
+	}
+	if st.Output != "" {
+		out += "\n" + st.Output
 	}
+	return out, err
 }
 
-func runSystemctl(service string, action Action) (string, error) {
-	cmd := exec.Command("systemctl", string(action), service)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	err := cmd.Run()
-	return out.String(), err
+// ServiceAction is RunServiceAction returning the structured status
+func ServiceAction(serviceName string, action Action) (*ServiceStatus, error) {
+	st, err := runServiceAction(serviceName, action)
+	metrics.ServiceActions.Inc(string(action), metrics.Result(err))
+	return st, err
 }
 
-func runLaunchctl(service string, action Action) (string, error) {
-	var cmd *exec.Cmd
+func runServiceAction(serviceName string, action Action) (*ServiceStatus, error) {
+	mgr, err := managerFor(serviceName)
+	if err != nil {
+		return nil, err
+	}
 	switch action {
-	case Start:
-		cmd = exec.Command("launchctl", "start", service)
-	case Stop:
-		cmd = exec.Command("launchctl", "stop", service)
-	case Restart:
-		// Launchctl has no restart; emulate it
-		stop := exec.Command("launchctl", "stop", service)
-		start := exec.Command("launchctl", "start", service)
-		var out1, out2 bytes.Buffer
-		stop.Stdout, stop.Stderr = &out1, &out1
-		start.Stdout, start.Stderr = &out2, &out2
-		err1 := stop.Run()
-		err2 := start.Run()
-		return out1.String() + "\n" + out2.String(), combineErr(err1, err2)
 	case Status:
-		cmd = exec.Command("launchctl", "list")
+		return mgr.Status(serviceName)
+	case Start, Stop, Restart, Reload:
+		return mgr.Run(serviceName, action)
 	default:
-		return "", errors.New("unsupported action")
-	}
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	err := cmd.Run()
-
-	if action == Status {
-		lines := strings.Split(out.String(), "\n")
-		for _, line := range lines {
-			if strings.Contains(line, service) {
-				return line, nil
-			}
-		}
-		return "Not found", nil
+		return nil, errors.New("unsupported action")
 	}
-
-	return out.String(), err
 }
 
 func combineErr(e1, e2 error) error {
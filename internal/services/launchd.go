@@ -0,0 +1,63 @@
+// File: internal/services/launchd.go
+// Purpose: launchd backend using launchctl
+
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+type launchdBackend struct {
+	run commandRunner
+}
+
+func (b *launchdBackend) Name() string { return "launchd" }
+
+// Status finds the label in `launchctl list`, whose lines read "PID Status Label"
+func (b *launchdBackend) Status(service string) (*ServiceStatus, error) {
+	out, err := b.run("launchctl", "list")
+	if err != nil {
+		return nil, err
+	}
+	st := &ServiceStatus{Manager: b.Name(), Service: service, State: StateUnknown, Detail: "not loaded"}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[2] != service {
+			continue
+		}
+		st.Output = line
+		st.Detail = "loaded"
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			st.State, st.PID = StateRunning, pid
+		} else {
+			st.State = StateStopped
+		}
+		// Negative statuses are the signal that killed the last run
+		if code, err := strconv.Atoi(fields[1]); err == nil {
+			st.LastExit = intPtr(code)
+			if st.State == StateStopped && code != 0 {
+				st.State = StateFailed
+			}
+		}
+		break
+	}
+	return st, nil
+}
+
+func (b *launchdBackend) Run(service string, action Action) (*ServiceStatus, error) {
+	return runThenStatus(b, service, func() (string, error) {
+		switch action {
+		case Start, Stop:
+			return b.run("launchctl", string(action), service)
+		case Restart:
+			// launchctl has no restart; emulate it
+			out1, err1 := b.run("launchctl", "stop", service)
+			out2, err2 := b.run("launchctl", "start", service)
+			return out1 + "\n" + out2, combineErr(err1, err2)
+		default:
+			return "", errors.New("unsupported action")
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package services
+
+import "testing"
+
+const launchctlList = `PID	Status	Label
+-	0	com.apple.SafariHistoryServiceAgent
+812	0	org.torproject.tor
+-	-15	org.example.killed
+-	1	org.example.crashed
+-	0	org.example.idle
+`
+
+func TestLaunchdStatus(t *testing.T) {
+	tests := []struct {
+		label string
+		statusCase
+	}{
+		{"org.torproject.tor", statusCase{state: StateRunning, detail: "loaded", pid: 812, exit: intPtr(0)}},
+		{"org.example.idle", statusCase{state: StateStopped, detail: "loaded", exit: intPtr(0)}},
+		{"org.example.crashed", statusCase{state: StateFailed, detail: "loaded", exit: intPtr(1)}},
+		{"org.example.killed", statusCase{state: StateFailed, detail: "loaded", exit: intPtr(-15)}},
+		{"org.example.missing", statusCase{state: StateUnknown, detail: "not loaded"}},
+		// a label that only appears as a substring of another must not match
+		{"torproject.tor", statusCase{state: StateUnknown, detail: "not loaded"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.label, func(t *testing.T) {
+			stub := &stubRunner{replies: map[string]stubReply{"launchctl list": {out: launchctlList}}}
+			st, err := (&launchdBackend{run: stub.run}).Status(tc.label)
+			checkStatus(t, tc.statusCase, st, err)
+		})
+	}
+
+	stub := &stubRunner{replies: map[string]stubReply{"launchctl list": {err: exitErr(t, 1)}}}
+	if _, err := (&launchdBackend{run: stub.run}).Status("org.torproject.tor"); err == nil {
+		t.Error("launchctl failure was not reported")
+	}
+}
+
+func TestLaunchdRun(t *testing.T) {
+	replies := map[string]stubReply{
+		"launchctl list":                     {out: launchctlList},
+		"launchctl start org.torproject.tor": {},
+		"launchctl stop org.torproject.tor":  {},
+	}
+	newBackend := func(s *stubRunner) ServiceManager { return &launchdBackend{run: s.run} }
+	checkActions(t, newBackend, "org.torproject.tor", replies, []actionCase{
+		{action: Start, argv: []string{"launchctl start org.torproject.tor", "launchctl list"}},
+		{action: Stop, argv: []string{"launchctl stop org.torproject.tor", "launchctl list"}},
+		{action: Restart, argv: []string{"launchctl stop org.torproject.tor", "launchctl start org.torproject.tor", "launchctl list"}},
+		{action: Reload, argv: []string{"launchctl list"}, err: true},
+	})
+}
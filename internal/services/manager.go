@@ -0,0 +1,215 @@
+// File: internal/services/manager.go
+// Purpose: ServiceManager interface, common status model and init system auto-detection
+
+package services
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is a service state normalized across init systems
+type State string
+
+const (
+	StateRunning  State = "running"
+	StateStopped  State = "stopped"
+	StateFailed   State = "failed"
+	StateStarting State = "starting"
+	StateStopping State = "stopping"
+	StateUnknown  State = "unknown"
+)
+
+// ServiceStatus is what every backend reports
+type ServiceStatus struct {
+	Manager  string     `json:"manager"`
+	Service  string     `json:"service"`
+	State    State      `json:"state"`
+	Detail   string     `json:"detail,omitempty"` // the init system's own state words
+	PID      int        `json:"pid,omitempty"`
+	Since    *time.Time `json:"since,omitempty"` // when the current state began
+	Restarts int        `json:"restarts,omitempty"`
+	LastExit *int       `json:"last_exit,omitempty"`
+	Output   string     `json:"output,omitempty"` // raw command output
+}
+
+// Uptime is how long the service has been running, zero when it is not
+func (s *ServiceStatus) Uptime(now time.Time) time.Duration {
+	if s.State != StateRunning || s.Since == nil {
+		return 0
+	}
+	return now.Sub(*s.Since)
+}
+
+// ServiceManager controls services through one init system
+type ServiceManager interface {
+	Name() string
+	Status(service string) (*ServiceStatus, error)
+	// Run performs start, stop, restart or reload and returns the new status
+	Run(service string, action Action) (*ServiceStatus, error)
+}
+
+// commandRunner runs a command and returns combined output; backends take one
+// so they can be driven by stub commands
+type commandRunner func(name string, args ...string) (string, error)
+
+func execRunner(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.String(), err
+}
+
+// exitCode extracts a process exit status from a runner error
+func exitCode(err error) (int, bool) {
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitCode(), true
+	}
+	return 0, false
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// errorOutput turns command output into an error, nil when there is none
+func errorOutput(out string) error {
+	if out = strings.TrimSpace(out); out == "" {
+		return nil
+	}
+	return errors.New(out)
+}
+
+// runThenStatus runs an action command and reports the status that follows
+// it; the action's own output wins over the status output
+func runThenStatus(m ServiceManager, service string, run func() (string, error)) (*ServiceStatus, error) {
+	out, err := run()
+	st, stErr := m.Status(service)
+	if st == nil {
+		st = &ServiceStatus{Manager: m.Name(), Service: service, State: StateUnknown}
+	}
+	if out = strings.TrimSpace(out); out != "" {
+		st.Output = out
+	}
+	if err != nil {
+		return st, err
+	}
+	return st, stErr
+}
+
+// pidFromFiles reads the first pidfile that exists among the usual locations
+func pidFromFiles(service string) int {
+	for _, p := range []string{
+		"/run/" + service + ".pid",
+		"/run/" + service + "/" + service + ".pid",
+		"/var/run/" + service + ".pid",
+		"/var/run/" + service + "/" + service + ".pid",
+	} {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil && pid > 0 {
+			return pid
+		}
+	}
+	return 0
+}
+
+// managers maps SERVICE_MANAGER values to backend constructors
+var managers = map[string]func() ServiceManager{
+	"systemd":     func() ServiceManager { return &systemdBackend{} },
+	"systemctl":   func() ServiceManager { return &systemctlBackend{run: execRunner} },
+	"launchd":     func() ServiceManager { return &launchdBackend{run: execRunner} },
+	"openrc":      func() ServiceManager { return &openrcBackend{run: execRunner} },
+	"runit":       func() ServiceManager { return newRunitBackend(execRunner) },
+	"s6":          func() ServiceManager { return newS6Backend(execRunner) },
+	"sysv":        func() ServiceManager { return &sysvBackend{run: execRunner, dir: "/etc/init.d"} },
+	"supervisord": func() ServiceManager { return &supervisordBackend{run: execRunner, now: time.Now} },
+}
+
+var (
+	inUseMu sync.Mutex
+	inUse   ServiceManager
+)
+
+// Owner is implemented by managers that only handle some units, such as the
+// Supervisor that runs a single tor instance
+type Owner interface {
+	Owns(service string) bool
+}
+
+// Use sends actions to m ahead of the init system, e.g. the built-in
+// Supervisor; when m is an Owner only the units it owns go to it
+func Use(m ServiceManager) {
+	inUseMu.Lock()
+	inUse = m
+	inUseMu.Unlock()
+}
+
+// managerFor returns the manager set with Use when it handles service,
+// otherwise the host's init system
+func managerFor(service string) (ServiceManager, error) {
+	inUseMu.Lock()
+	m := inUse
+	inUseMu.Unlock()
+	if m != nil {
+		if o, ok := m.(Owner); !ok || o.Owns(service) {
+			return m, nil
+		}
+	}
+	return Detect()
+}
+
+// Detect picks the init system: SERVICE_MANAGER when set, otherwise the
+// first one whose runtime markers are present
+func Detect() (ServiceManager, error) {
+	if name := os.Getenv("SERVICE_MANAGER"); name != "" {
+		mk, ok := managers[name]
+		if !ok {
+			return nil, errors.New("unknown SERVICE_MANAGER " + name)
+		}
+		return mk(), nil
+	}
+	if runtime.GOOS == "darwin" {
+		return managers["launchd"](), nil
+	}
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("unsupported OS")
+	}
+	switch {
+	case exists("/run/systemd/system"):
+		return managers["systemd"](), nil
+	case exists("/run/openrc") && inPath("rc-service"):
+		return managers["openrc"](), nil
+	case inPath("s6-svc") && exists(s6ScanDir()):
+		return managers["s6"](), nil
+	case inPath("sv") && exists(runitServiceDir()):
+		return managers["runit"](), nil
+	case inPath("supervisorctl"):
+		return managers["supervisord"](), nil
+	case exists("/etc/init.d"):
+		return managers["sysv"](), nil
+	}
+	return nil, errors.New("no supported init system found")
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func inPath(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testNow is the clock backends with uptime parsing are given
+var testNow = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+type stubReply struct {
+	out string
+	err error
+}
+
+// stubRunner replays canned output per command line and records every call
+type stubRunner struct {
+	replies map[string]stubReply
+	calls   []string
+}
+
+func (s *stubRunner) run(name string, args ...string) (string, error) {
+	argv := strings.Join(append([]string{name}, args...), " ")
+	s.calls = append(s.calls, argv)
+	r, ok := s.replies[argv]
+	if !ok {
+		return "", fmt.Errorf("unexpected command %q", argv)
+	}
+	return r.out, r.err
+}
+
+// exitErr returns a real *exec.ExitError carrying code
+func exitErr(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		t.Fatalf("sh exit %d: %v", code, err)
+	}
+	return err
+}
+
+// statusCase is one status output and what a backend should make of it
+type statusCase struct {
+	name   string
+	reply  stubReply
+	state  State
+	detail string
+	pid    int
+	since  time.Duration // how long before testNow; 0 means no Since
+	exit   *int
+	err    bool
+}
+
+func checkStatus(t *testing.T, tc statusCase, st *ServiceStatus, err error) {
+	t.Helper()
+	if tc.err {
+		if err == nil {
+			t.Errorf("status = %+v, want an error", st)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.State != tc.state || st.Detail != tc.detail || st.PID != tc.pid {
+		t.Errorf("state/detail/pid = %s/%q/%d, want %s/%q/%d", st.State, st.Detail, st.PID, tc.state, tc.detail, tc.pid)
+	}
+	switch {
+	case tc.since == 0 && st.Since != nil:
+		t.Errorf("Since = %v, want none", st.Since)
+	case tc.since != 0 && (st.Since == nil || !st.Since.Equal(testNow.Add(-tc.since))):
+		t.Errorf("Since = %v, want %v", st.Since, testNow.Add(-tc.since))
+	}
+	switch {
+	case tc.exit == nil && st.LastExit != nil:
+		t.Errorf("LastExit = %d, want none", *st.LastExit)
+	case tc.exit != nil && (st.LastExit == nil || *st.LastExit != *tc.exit):
+		t.Errorf("LastExit = %v, want %d", st.LastExit, *tc.exit)
+	}
+}
+
+// actionCase is the command lines one action should run, status included
+type actionCase struct {
+	action Action
+	argv   []string
+	err    bool
+}
+
+func checkActions(t *testing.T, newBackend func(*stubRunner) ServiceManager, service string, replies map[string]stubReply, cases []actionCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(string(tc.action), func(t *testing.T) {
+			stub := &stubRunner{replies: replies}
+			_, err := newBackend(stub).Run(service, tc.action)
+			if (err != nil) != tc.err {
+				t.Errorf("err = %v, want error %v", err, tc.err)
+			}
+			if strings.Join(stub.calls, "\n") != strings.Join(tc.argv, "\n") {
+				t.Errorf("ran %q, want %q", stub.calls, tc.argv)
+			}
+		})
+	}
+}
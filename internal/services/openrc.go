@@ -0,0 +1,49 @@
+// File: internal/services/openrc.go
+// Purpose: OpenRC backend using rc-service (Alpine, Gentoo)
+
+package services
+
+import (
+	"strings"
+)
+
+type openrcBackend struct {
+	run commandRunner
+}
+
+func (b *openrcBackend) Name() string { return "openrc" }
+
+// Status parses " * status: started"; rc-service exits non-zero for every
+// state but started, so the text decides
+func (b *openrcBackend) Status(service string) (*ServiceStatus, error) {
+	out, err := b.run("rc-service", service, "status")
+	st := &ServiceStatus{Manager: b.Name(), Service: service, State: StateUnknown, Output: strings.TrimSpace(out)}
+	_, word, found := strings.Cut(out, "status:")
+	if !found {
+		if err != nil {
+			return nil, combineErr(err, errorOutput(out))
+		}
+		return st, nil
+	}
+	st.Detail = strings.TrimSpace(word)
+	switch st.Detail {
+	case "started":
+		st.State = StateRunning
+		st.PID = pidFromFiles(service)
+	case "stopped", "inactive":
+		st.State = StateStopped
+	case "crashed":
+		st.State = StateFailed
+	case "starting":
+		st.State = StateStarting
+	case "stopping":
+		st.State = StateStopping
+	}
+	return st, nil
+}
+
+func (b *openrcBackend) Run(service string, action Action) (*ServiceStatus, error) {
+	return runThenStatus(b, service, func() (string, error) {
+		return b.run("rc-service", service, string(action))
+	})
+}
@@ -0,0 +1,38 @@
+package services
+
+import "testing"
+
+func TestOpenRCStatus(t *testing.T) {
+	tests := []statusCase{
+		{name: "started", reply: stubReply{out: " * status: started\n"}, state: StateRunning, detail: "started"},
+		{name: "stopped", reply: stubReply{out: " * status: stopped\n", err: exitErr(t, 3)}, state: StateStopped, detail: "stopped"},
+		{name: "inactive", reply: stubReply{out: " * status: inactive\n", err: exitErr(t, 3)}, state: StateStopped, detail: "inactive"},
+		{name: "crashed", reply: stubReply{out: " * status: crashed\n", err: exitErr(t, 32)}, state: StateFailed, detail: "crashed"},
+		{name: "starting", reply: stubReply{out: " * status: starting\n", err: exitErr(t, 4)}, state: StateStarting, detail: "starting"},
+		{name: "stopping", reply: stubReply{out: " * status: stopping\n", err: exitErr(t, 4)}, state: StateStopping, detail: "stopping"},
+		{name: "no such service", reply: stubReply{out: " * rc-service: service `tor-admin-test' does not exist\n", err: exitErr(t, 1)}, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &stubRunner{replies: map[string]stubReply{"rc-service tor-admin-test status": tc.reply}}
+			st, err := (&openrcBackend{run: stub.run}).Status("tor-admin-test")
+			checkStatus(t, tc, st, err)
+		})
+	}
+}
+
+func TestOpenRCRun(t *testing.T) {
+	replies := map[string]stubReply{
+		"rc-service tor status":  {out: " * status: started\n"},
+		"rc-service tor start":   {out: " * Starting tor ...  [ ok ]\n"},
+		"rc-service tor stop":    {out: " * Stopping tor ...  [ ok ]\n"},
+		"rc-service tor restart": {out: " * Stopping tor ...\n * Starting tor ...\n"},
+		"rc-service tor reload":  {out: " * Reloading tor configuration ...\n", err: exitErr(t, 1)},
+	}
+	checkActions(t, func(s *stubRunner) ServiceManager { return &openrcBackend{run: s.run} }, "tor", replies, []actionCase{
+		{action: Start, argv: []string{"rc-service tor start", "rc-service tor status"}},
+		{action: Stop, argv: []string{"rc-service tor stop", "rc-service tor status"}},
+		{action: Restart, argv: []string{"rc-service tor restart", "rc-service tor status"}},
+		{action: Reload, argv: []string{"rc-service tor reload", "rc-service tor status"}, err: true},
+	})
+}
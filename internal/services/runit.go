@@ -0,0 +1,94 @@
+// File: internal/services/runit.go
+// Purpose: runit backend using sv
+
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runitServiceDir is where sv looks up service names
+func runitServiceDir() string {
+	if dir := os.Getenv("SVDIR"); dir != "" {
+		return dir
+	}
+	if exists("/var/service") && !exists("/etc/service") {
+		return "/var/service" // Void Linux
+	}
+	return "/etc/service"
+}
+
+type runitBackend struct {
+	run commandRunner
+	dir string
+	now func() time.Time
+}
+
+func newRunitBackend(run commandRunner) *runitBackend {
+	return &runitBackend{run: run, dir: runitServiceDir(), now: time.Now}
+}
+
+func (b *runitBackend) Name() string { return "runit" }
+
+func (b *runitBackend) path(service string) string {
+	if filepath.IsAbs(service) {
+		return service
+	}
+	return filepath.Join(b.dir, service)
+}
+
+// svStatus matches "run: /etc/service/tor: (pid 123) 456s" and
+// "down: /etc/service/tor: 5s, normally up"
+var svStatus = regexp.MustCompile(`^(\w+): [^:]+: (?:\(pid (\d+)\) )?(\d+)s(.*)$`)
+
+func (b *runitBackend) Status(service string) (*ServiceStatus, error) {
+	out, err := b.run("sv", "status", b.path(service))
+	// The log service, if any, follows after "; "
+	first, _, _ := strings.Cut(strings.TrimSpace(out), ";")
+	m := svStatus.FindStringSubmatch(first)
+	if m == nil {
+		if err == nil {
+			err = errors.New("unrecognised sv status output")
+		}
+		return nil, combineErr(err, errorOutput(out))
+	}
+	st := &ServiceStatus{Manager: b.Name(), Service: service, Detail: m[1], Output: strings.TrimSpace(out)}
+	st.PID, _ = strconv.Atoi(m[2])
+	secs, _ := strconv.Atoi(m[3])
+	since := b.now().Add(-time.Duration(secs) * time.Second).UTC()
+	st.Since = &since
+	wantDown := strings.Contains(m[4], "want down")
+	switch m[1] {
+	case "run":
+		st.State = StateRunning
+		if wantDown {
+			st.State = StateStopping
+		}
+	case "down":
+		st.State = StateStopped
+		if strings.Contains(m[4], "want up") {
+			st.State = StateStarting
+		}
+	case "finish":
+		st.State = StateStopping
+	default:
+		st.State = StateUnknown
+	}
+	return st, nil
+}
+
+func (b *runitBackend) Run(service string, action Action) (*ServiceStatus, error) {
+	cmd := map[Action]string{Start: "start", Stop: "stop", Restart: "restart", Reload: "hup"}[action]
+	if cmd == "" {
+		return nil, errors.New("unsupported action")
+	}
+	return runThenStatus(b, service, func() (string, error) {
+		return b.run("sv", cmd, b.path(service))
+	})
+}
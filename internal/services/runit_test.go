@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunitStatus(t *testing.T) {
+	tests := []statusCase{
+		{name: "running with log service", reply: stubReply{out: "run: /etc/service/tor: (pid 1234) 456s; run: log: (pid 1200) 460s\n"},
+			state: StateRunning, detail: "run", pid: 1234, since: 456 * time.Second},
+		{name: "down", reply: stubReply{out: "down: /etc/service/tor: 5s, normally up\n", err: exitErr(t, 3)},
+			state: StateStopped, detail: "down", since: 5 * time.Second},
+		{name: "down but wanted up", reply: stubReply{out: "down: /etc/service/tor: 1s, normally up, want up\n"},
+			state: StateStarting, detail: "down", since: time.Second},
+		{name: "running but wanted down", reply: stubReply{out: "run: /etc/service/tor: (pid 99) 12s, want down\n"},
+			state: StateStopping, detail: "run", pid: 99, since: 12 * time.Second},
+		{name: "finishing", reply: stubReply{out: "finish: /etc/service/tor: (pid 77) 2s\n"},
+			state: StateStopping, detail: "finish", pid: 77, since: 2 * time.Second},
+		{name: "no supervisor", reply: stubReply{out: "warning: /etc/service/tor: unable to open supervise/ok: file does not exist\n", err: exitErr(t, 1)}, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &stubRunner{replies: map[string]stubReply{"sv status /etc/service/tor": tc.reply}}
+			b := &runitBackend{run: stub.run, dir: "/etc/service", now: func() time.Time { return testNow }}
+			st, err := b.Status("tor")
+			checkStatus(t, tc, st, err)
+		})
+	}
+}
+
+func TestRunitRun(t *testing.T) {
+	replies := map[string]stubReply{
+		"sv status /var/service/tor":  {out: "run: /var/service/tor: (pid 1) 1s\n"},
+		"sv start /var/service/tor":   {out: "ok: run: /var/service/tor: (pid 1) 0s\n"},
+		"sv stop /var/service/tor":    {out: "ok: down: /var/service/tor: 0s\n"},
+		"sv restart /var/service/tor": {out: "ok: run: /var/service/tor: (pid 2) 0s\n"},
+		"sv hup /var/service/tor":     {},
+	}
+	newBackend := func(s *stubRunner) ServiceManager {
+		return &runitBackend{run: s.run, dir: "/var/service", now: func() time.Time { return testNow }}
+	}
+	checkActions(t, newBackend, "tor", replies, []actionCase{
+		{action: Start, argv: []string{"sv start /var/service/tor", "sv status /var/service/tor"}},
+		{action: Stop, argv: []string{"sv stop /var/service/tor", "sv status /var/service/tor"}},
+		{action: Restart, argv: []string{"sv restart /var/service/tor", "sv status /var/service/tor"}},
+		{action: Reload, argv: []string{"sv hup /var/service/tor", "sv status /var/service/tor"}},
+		{action: Status, err: true},
+	})
+
+	// an absolute service path is used as is
+	stub := &stubRunner{replies: map[string]stubReply{"sv status /srv/sv/tor": {out: "run: /srv/sv/tor: (pid 5) 3s\n"}}}
+	if _, err := newBackend(stub).Status("/srv/sv/tor"); err != nil {
+		t.Error(err)
+	}
+}
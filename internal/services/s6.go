@@ -0,0 +1,99 @@
+// File: internal/services/s6.go
+// Purpose: s6 backend using s6-svc and s6-svstat
+
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s6ScanDir is the scan directory s6-svscan watches (s6-overlay uses /run/service)
+func s6ScanDir() string {
+	if dir := os.Getenv("S6_SCANDIR"); dir != "" {
+		return dir
+	}
+	return "/run/service"
+}
+
+type s6Backend struct {
+	run commandRunner
+	dir string
+	now func() time.Time
+}
+
+func newS6Backend(run commandRunner) *s6Backend {
+	return &s6Backend{run: run, dir: s6ScanDir(), now: time.Now}
+}
+
+func (b *s6Backend) Name() string { return "s6" }
+
+func (b *s6Backend) path(service string) string {
+	if filepath.IsAbs(service) {
+		return service
+	}
+	return filepath.Join(b.dir, service)
+}
+
+// svstat matches "up (pid 123) 45 seconds",
+// "down (exitcode 1) 3 seconds, normally up, want up, ready 3 seconds" and,
+// for a service that never ran, "down 3 seconds"
+var svstat = regexp.MustCompile(`^(up|down)(?: \((?:pid (\d+)|exitcode (-?\d+)|signal (\w+))\))? (\d+) seconds(.*)$`)
+
+func (b *s6Backend) Status(service string) (*ServiceStatus, error) {
+	out, err := b.run("s6-svstat", b.path(service))
+	m := svstat.FindStringSubmatch(strings.TrimSpace(out))
+	if m == nil {
+		if err == nil {
+			err = errors.New("unrecognised s6-svstat output")
+		}
+		return nil, combineErr(err, errorOutput(out))
+	}
+	st := &ServiceStatus{Manager: b.Name(), Service: service, Detail: m[1], Output: strings.TrimSpace(out)}
+	st.PID, _ = strconv.Atoi(m[2])
+	if code, err := strconv.Atoi(m[3]); err == nil {
+		st.LastExit = intPtr(code)
+	}
+	if m[4] != "" {
+		st.Detail += " (signal " + m[4] + ")"
+	}
+	secs, _ := strconv.Atoi(m[5])
+	since := b.now().Add(-time.Duration(secs) * time.Second).UTC()
+	st.Since = &since
+	switch {
+	case m[1] == "up" && strings.Contains(m[6], "want down"):
+		st.State = StateStopping
+	case m[1] == "up":
+		st.State = StateRunning
+	case strings.Contains(m[6], "want up"):
+		// Supervised but not staying up: s6 keeps relaunching it
+		st.State = StateStarting
+		if (st.LastExit != nil && *st.LastExit != 0) || m[4] != "" {
+			st.State = StateFailed
+		}
+	default:
+		st.State = StateStopped
+	}
+	return st, nil
+}
+
+// Run sends the s6-svc command and waits up to 10s for the state change
+func (b *s6Backend) Run(service string, action Action) (*ServiceStatus, error) {
+	flags := map[Action][]string{
+		Start:   {"-wu", "-T", "10000", "-u"},
+		Stop:    {"-wd", "-T", "10000", "-d"},
+		Restart: {"-wr", "-T", "10000", "-r"},
+		Reload:  {"-h"},
+	}[action]
+	if flags == nil {
+		return nil, errors.New("unsupported action")
+	}
+	return runThenStatus(b, service, func() (string, error) {
+		return b.run("s6-svc", append(flags, b.path(service))...)
+	})
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestS6Status(t *testing.T) {
+	tests := []statusCase{
+		{name: "up", reply: stubReply{out: "up (pid 321) 45 seconds, normally up, ready 45 seconds\n"},
+			state: StateRunning, detail: "up", pid: 321, since: 45 * time.Second},
+		{name: "up but wanted down", reply: stubReply{out: "up (pid 321) 2 seconds, want down\n"},
+			state: StateStopping, detail: "up", pid: 321, since: 2 * time.Second},
+		{name: "down cleanly", reply: stubReply{out: "down (exitcode 0) 30 seconds, normally up, ready 30 seconds\n"},
+			state: StateStopped, detail: "down", since: 30 * time.Second, exit: intPtr(0)},
+		{name: "restarting after a clean exit", reply: stubReply{out: "down (exitcode 0) 1 seconds, normally up, want up\n"},
+			state: StateStarting, detail: "down", since: time.Second, exit: intPtr(0)},
+		{name: "crash looping", reply: stubReply{out: "down (exitcode 1) 3 seconds, normally up, want up, ready 3 seconds\n"},
+			state: StateFailed, detail: "down", since: 3 * time.Second, exit: intPtr(1)},
+		{name: "killed by a signal", reply: stubReply{out: "down (signal SIGSEGV) 4 seconds, normally up, want up\n"},
+			state: StateFailed, detail: "down (signal SIGSEGV)", since: 4 * time.Second},
+		{name: "never started", reply: stubReply{out: "down 10 seconds, normally down\n"},
+			state: StateStopped, detail: "down", since: 10 * time.Second},
+		{name: "not supervised", reply: stubReply{out: "s6-svstat: fatal: unable to read status for /run/service/tor: No such file or directory\n", err: exitErr(t, 111)}, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &stubRunner{replies: map[string]stubReply{"s6-svstat /run/service/tor": tc.reply}}
+			b := &s6Backend{run: stub.run, dir: "/run/service", now: func() time.Time { return testNow }}
+			st, err := b.Status("tor")
+			checkStatus(t, tc, st, err)
+		})
+	}
+}
+
+func TestS6Run(t *testing.T) {
+	replies := map[string]stubReply{
+		"s6-svstat /run/service/tor":              {out: "up (pid 1) 1 seconds\n"},
+		"s6-svc -wu -T 10000 -u /run/service/tor": {},
+		"s6-svc -wd -T 10000 -d /run/service/tor": {},
+		"s6-svc -wr -T 10000 -r /run/service/tor": {out: "s6-svc: fatal: timed out\n", err: exitErr(t, 99)},
+		"s6-svc -h /run/service/tor":              {},
+	}
+	newBackend := func(s *stubRunner) ServiceManager {
+		return &s6Backend{run: s.run, dir: "/run/service", now: func() time.Time { return testNow }}
+	}
+	checkActions(t, newBackend, "tor", replies, []actionCase{
+		{action: Start, argv: []string{"s6-svc -wu -T 10000 -u /run/service/tor", "s6-svstat /run/service/tor"}},
+		{action: Stop, argv: []string{"s6-svc -wd -T 10000 -d /run/service/tor", "s6-svstat /run/service/tor"}},
+		{action: Restart, argv: []string{"s6-svc -wr -T 10000 -r /run/service/tor", "s6-svstat /run/service/tor"}, err: true},
+		{action: Reload, argv: []string{"s6-svc -h /run/service/tor", "s6-svstat /run/service/tor"}},
+		{action: Status, err: true},
+	})
+}
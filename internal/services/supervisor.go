@@ -0,0 +1,308 @@
+// File: internal/services/supervisor.go
+// Purpose: Run tor as a child process when there is no init system (containers)
+
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"tor-admin/internal/instance"
+	"tor-admin/internal/torlog"
+)
+
+// StopTimeout is how long Stop waits after SIGTERM before killing tor
+var StopTimeout = 30 * time.Second
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// Supervisor launches one tor instance, restarts it with backoff when it dies
+// and feeds its stdout/stderr into the log buffer
+type Supervisor struct {
+	binary string
+	inst   *instance.Instance
+	logs   *torlog.Buffer
+
+	mu       sync.Mutex
+	want     bool          // tor should be running
+	loopDone chan struct{} // non-nil while the supervise loop runs
+	wake     chan struct{} // cuts a restart backoff short
+	cmd      *exec.Cmd
+	started  time.Time
+	exited   time.Time
+	waiting  bool // between a crash and the next launch
+	restarts int
+	lastExit *int
+	reason   string // why tor last stopped or failed to start
+}
+
+// NewSupervisor prepares to run binary with inst's torrc; logs may be nil
+func NewSupervisor(binary string, inst *instance.Instance, logs *torlog.Buffer) *Supervisor {
+	return &Supervisor{binary: binary, inst: inst, logs: logs, wake: make(chan struct{}, 1)}
+}
+
+func (s *Supervisor) Name() string { return "builtin" }
+
+// Start launches tor unless it is already supervised
+func (s *Supervisor) Start() error {
+	if _, err := exec.LookPath(s.binary); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.want = true
+	if s.loopDone != nil {
+		// Already supervised; skip any pending backoff
+		s.poke()
+		return nil
+	}
+	select {
+	case <-s.wake:
+	default:
+	}
+	s.loopDone = make(chan struct{})
+	go s.loop(s.loopDone)
+	return nil
+}
+
+// Stop sends SIGTERM, kills tor after StopTimeout and waits for supervision to end
+func (s *Supervisor) Stop() error {
+	s.mu.Lock()
+	s.want = false
+	cmd, done := s.cmd, s.loopDone
+	s.poke()
+	s.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	if cmd != nil {
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(StopTimeout):
+	}
+	if cmd != nil {
+		_ = cmd.Process.Kill()
+	}
+	<-done
+	return nil
+}
+
+// Restart stops tor and starts it again
+func (s *Supervisor) Restart() error {
+	if err := s.Stop(); err != nil {
+		return err
+	}
+	return s.Start()
+}
+
+// Reload forwards SIGHUP so tor rereads its torrc
+func (s *Supervisor) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd == nil {
+		return errors.New("tor is not running")
+	}
+	return s.cmd.Process.Signal(syscall.SIGHUP)
+}
+
+func (s *Supervisor) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Supervisor) note(level, format string, args ...any) {
+	if s.logs != nil {
+		s.logs.Add(s.inst.ID, level, "supervisor", fmt.Sprintf(format, args...))
+	}
+}
+
+func (s *Supervisor) loop(done chan struct{}) {
+	defer func() {
+		s.mu.Lock()
+		s.loopDone = nil
+		s.mu.Unlock()
+		close(done)
+	}()
+	backoff := minBackoff
+	for {
+		s.mu.Lock()
+		if !s.want {
+			s.mu.Unlock()
+			return
+		}
+		ran := s.runOnce() // unlocks while tor runs
+		if !s.want {
+			s.mu.Unlock()
+			return
+		}
+		if ran > maxBackoff {
+			backoff = minBackoff
+		}
+		s.restarts++
+		s.waiting = true
+		reason := s.reason
+		s.mu.Unlock()
+
+		s.note("WARN", "tor stopped (%s); restarting in %s", reason, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-s.wake:
+		}
+		s.mu.Lock()
+		s.waiting = false
+		s.mu.Unlock()
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// runOnce is called with mu held and returns with it held, releasing it
+// while tor runs
+func (s *Supervisor) runOnce() time.Duration {
+	cmd := exec.Command(s.binary, "-f", s.inst.Torrc, "--RunAsDaemon", "0")
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		var stderr io.ReadCloser
+		if stderr, err = cmd.StderrPipe(); err == nil {
+			if err = cmd.Start(); err == nil {
+				return s.wait(cmd, stdout, stderr)
+			}
+		}
+	}
+	s.reason = err.Error()
+	s.exited = time.Now().UTC()
+	return 0
+}
+
+func (s *Supervisor) wait(cmd *exec.Cmd, stdout, stderr io.Reader) time.Duration {
+	s.cmd = cmd
+	s.started = time.Now().UTC()
+	s.reason = ""
+	s.mu.Unlock()
+	s.note("NOTICE", "started %s (pid %d)", s.binary, cmd.Process.Pid)
+
+	// Wait closes the pipes, so drain them first
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go s.capture(&wg, stdout, "stdout", "NOTICE")
+	go s.capture(&wg, stderr, "stderr", "WARN")
+	wg.Wait()
+	_ = cmd.Wait()
+
+	s.mu.Lock()
+	s.cmd = nil
+	s.exited = time.Now().UTC()
+	s.lastExit = intPtr(cmd.ProcessState.ExitCode()) // -1 when killed by a signal
+	s.reason = cmd.ProcessState.String()
+	return s.exited.Sub(s.started)
+}
+
+// torLine matches tor's own log prefix, e.g. "Oct 19 10:00:00.000 [notice] "
+var torLine = regexp.MustCompile(`^\w{3} [ \d]\d \d\d:\d\d:\d\d\.\d{3} \[(\w+)\] (.*)$`)
+
+func (s *Supervisor) capture(wg *sync.WaitGroup, r io.Reader, source, level string) {
+	defer wg.Done()
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if s.logs == nil || strings.TrimSpace(line) == "" {
+			continue
+		}
+		lvl, msg := level, line
+		if m := torLine.FindStringSubmatch(line); m != nil {
+			lvl, msg = m[1], m[2]
+		}
+		s.logs.Add(s.inst.ID, lvl, source, msg)
+	}
+	// Keep draining after an over-long line so tor never blocks on a full pipe
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// Owns reports whether service is the unit of the instance tor-admin runs;
+// ServiceAction sends every other unit to the host's init system
+func (s *Supervisor) Owns(service string) bool {
+	return service == s.inst.Unit
+}
+
+// supervises rejects units of other instances, which tor-admin does not run
+func (s *Supervisor) supervises(service string) error {
+	if !s.Owns(service) {
+		return fmt.Errorf("%s is not supervised by tor-admin (only %s)", service, s.inst.Unit)
+	}
+	return nil
+}
+
+// Status reports the supervised process, which must be the instance's unit
+func (s *Supervisor) Status(service string) (*ServiceStatus, error) {
+	if err := s.supervises(service); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := &ServiceStatus{Manager: s.Name(), Service: service, Restarts: s.restarts, LastExit: s.lastExit}
+	switch {
+	case s.cmd != nil && s.want:
+		st.State, st.Detail = StateRunning, "running"
+	case s.cmd != nil:
+		st.State, st.Detail = StateStopping, "stopping"
+	case s.waiting:
+		st.State, st.Detail = StateFailed, "restarting after backoff"
+	case s.want:
+		st.State, st.Detail = StateStarting, "starting"
+	default:
+		st.State, st.Detail = StateStopped, "stopped"
+	}
+	if s.cmd != nil {
+		st.PID = s.cmd.Process.Pid
+		since := s.started
+		st.Since = &since
+	} else if !s.exited.IsZero() {
+		since := s.exited
+		st.Since = &since
+		st.Detail += " (" + s.reason + ")"
+	}
+	return st, nil
+}
+
+func (s *Supervisor) Run(service string, action Action) (*ServiceStatus, error) {
+	if err := s.supervises(service); err != nil {
+		return nil, err
+	}
+	var err error
+	switch action {
+	case Start:
+		err = s.Start()
+	case Stop:
+		err = s.Stop()
+	case Restart:
+		err = s.Restart()
+	case Reload:
+		err = s.Reload()
+	default:
+		return nil, errors.New("unsupported action")
+	}
+	st, _ := s.Status(service)
+	// Launching is asynchronous; give tor a moment so the status has a pid
+	for deadline := time.Now().Add(2 * time.Second); err == nil && st.State == StateStarting && time.Now().Before(deadline); {
+		time.Sleep(50 * time.Millisecond)
+		st, _ = s.Status(service)
+	}
+	return st, err
+}
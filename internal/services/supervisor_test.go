@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+
+	"tor-admin/internal/instance"
+	"tor-admin/internal/torlog"
+)
+
+func TestSupervisorOnlyManagesItsInstance(t *testing.T) {
+	logs := torlog.NewBuffer(10)
+	sup := NewSupervisor("tor", &instance.Instance{ID: "relay", Torrc: "/nonexistent/torrc", Unit: "tor@relay"}, logs)
+
+	st, err := sup.Status("tor@relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.State != StateStopped || st.Manager != "builtin" {
+		t.Errorf("status = %+v", st)
+	}
+	for _, unit := range []string{"tor", "tor@bridge"} {
+		if _, err := sup.Status(unit); err == nil {
+			t.Errorf("Status(%q) accepted another instance's unit", unit)
+		}
+		if _, err := sup.Run(unit, Restart); err == nil {
+			t.Errorf("Run(%q) accepted another instance's unit", unit)
+		}
+	}
+
+	sup.note("NOTICE", "hello")
+	entries := logs.Recent(torlog.Filter{Instance: "relay"})
+	if len(entries) != 1 || entries[0].Source != "supervisor" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+// recordingManager stands in for the host's init system
+type recordingManager struct{ services []string }
+
+func (m *recordingManager) Name() string { return "recording" }
+
+func (m *recordingManager) Status(service string) (*ServiceStatus, error) {
+	m.services = append(m.services, service)
+	return &ServiceStatus{Manager: m.Name(), Service: service, State: StateRunning}, nil
+}
+
+func (m *recordingManager) Run(service string, action Action) (*ServiceStatus, error) {
+	return m.Status(service)
+}
+
+func TestServiceActionFallsBackForUnsupervisedUnits(t *testing.T) {
+	host := &recordingManager{}
+	managers["recording"] = func() ServiceManager { return host }
+	t.Cleanup(func() { delete(managers, "recording") })
+	t.Setenv("SERVICE_MANAGER", "recording")
+
+	sup := NewSupervisor("tor", &instance.Instance{ID: "default", Torrc: "/nonexistent/torrc", Unit: "tor"}, nil)
+	Use(sup)
+	t.Cleanup(func() { Use(nil) })
+
+	st, err := ServiceAction("tor", Status)
+	if err != nil || st.Manager != "builtin" {
+		t.Errorf("supervised unit: %+v, %v", st, err)
+	}
+	for _, action := range []Action{Status, Restart} {
+		st, err := ServiceAction("tor@relay", action)
+		if err != nil || st.Manager != "recording" {
+			t.Errorf("%s of another instance: %+v, %v", action, st, err)
+		}
+	}
+	if len(host.services) != 2 {
+		t.Errorf("init system saw %v", host.services)
+	}
+}
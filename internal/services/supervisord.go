@@ -0,0 +1,74 @@
+// File: internal/services/supervisord.go
+// Purpose: supervisord backend using supervisorctl
+
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type supervisordBackend struct {
+	run commandRunner
+	now func() time.Time
+}
+
+func (b *supervisordBackend) Name() string { return "supervisord" }
+
+// supervisorUptime matches "pid 123, uptime 1 day, 2:03:04"
+var supervisorUptime = regexp.MustCompile(`pid (\d+), uptime (?:(\d+) days?, )?(\d+):(\d\d):(\d\d)`)
+
+// Status parses "tor   RUNNING   pid 123, uptime 0:01:02"; supervisorctl
+// exits non-zero when the process is not running, so the text decides
+func (b *supervisordBackend) Status(service string) (*ServiceStatus, error) {
+	out, err := b.run("supervisorctl", "status", service)
+	fields := strings.Fields(out)
+	if len(fields) < 2 || fields[0] != service {
+		if err == nil {
+			err = errors.New("unrecognised supervisorctl output")
+		}
+		return nil, combineErr(err, errorOutput(out))
+	}
+	st := &ServiceStatus{Manager: b.Name(), Service: service, Detail: fields[1], Output: strings.TrimSpace(out)}
+	switch fields[1] {
+	case "RUNNING":
+		st.State = StateRunning
+	case "STOPPED", "EXITED":
+		st.State = StateStopped
+	case "FATAL", "BACKOFF":
+		st.State = StateFailed
+	case "STARTING":
+		st.State = StateStarting
+	case "STOPPING":
+		st.State = StateStopping
+	default:
+		st.State = StateUnknown
+	}
+	if m := supervisorUptime.FindStringSubmatch(out); m != nil {
+		st.PID, _ = strconv.Atoi(m[1])
+		days, _ := strconv.Atoi(m[2])
+		h, _ := strconv.Atoi(m[3])
+		mins, _ := strconv.Atoi(m[4])
+		sec, _ := strconv.Atoi(m[5])
+		up := time.Duration(days)*24*time.Hour + time.Duration(h)*time.Hour + time.Duration(mins)*time.Minute + time.Duration(sec)*time.Second
+		since := b.now().Add(-up).UTC()
+		st.Since = &since
+	}
+	return st, nil
+}
+
+func (b *supervisordBackend) Run(service string, action Action) (*ServiceStatus, error) {
+	return runThenStatus(b, service, func() (string, error) {
+		switch action {
+		case Start, Stop, Restart:
+			return b.run("supervisorctl", string(action), service)
+		case Reload:
+			return b.run("supervisorctl", "signal", "HUP", service)
+		default:
+			return "", errors.New("unsupported action")
+		}
+	})
+}
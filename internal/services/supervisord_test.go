@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSupervisordStatus(t *testing.T) {
+	tests := []statusCase{
+		{name: "running", reply: stubReply{out: "tor                              RUNNING   pid 2345, uptime 0:01:02\n"},
+			state: StateRunning, detail: "RUNNING", pid: 2345, since: 62 * time.Second},
+		{name: "running for days", reply: stubReply{out: "tor                              RUNNING   pid 2345, uptime 3 days, 4:05:06\n"},
+			state: StateRunning, detail: "RUNNING", pid: 2345, since: 3*24*time.Hour + 4*time.Hour + 5*time.Minute + 6*time.Second},
+		{name: "running for one day", reply: stubReply{out: "tor RUNNING pid 7, uptime 1 day, 0:00:01\n"},
+			state: StateRunning, detail: "RUNNING", pid: 7, since: 24*time.Hour + time.Second},
+		{name: "stopped", reply: stubReply{out: "tor                              STOPPED   Oct 19 10:00 AM\n", err: exitErr(t, 3)},
+			state: StateStopped, detail: "STOPPED"},
+		{name: "exited", reply: stubReply{out: "tor                              EXITED    Oct 19 10:00 AM\n", err: exitErr(t, 3)},
+			state: StateStopped, detail: "EXITED"},
+		{name: "fatal", reply: stubReply{out: "tor                              FATAL     Exited too quickly (process log may have details)\n", err: exitErr(t, 3)},
+			state: StateFailed, detail: "FATAL"},
+		{name: "backoff", reply: stubReply{out: "tor                              BACKOFF   Exited too quickly (process log may have details)\n", err: exitErr(t, 3)},
+			state: StateFailed, detail: "BACKOFF"},
+		{name: "starting", reply: stubReply{out: "tor                              STARTING  \n", err: exitErr(t, 3)},
+			state: StateStarting, detail: "STARTING"},
+		{name: "stopping", reply: stubReply{out: "tor                              STOPPING  \n", err: exitErr(t, 3)},
+			state: StateStopping, detail: "STOPPING"},
+		{name: "no such process", reply: stubReply{out: "tor: ERROR (no such process)\n", err: exitErr(t, 4)}, err: true},
+		{name: "supervisord down", reply: stubReply{out: "unix:///var/run/supervisor.sock no such file\n", err: exitErr(t, 4)}, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &stubRunner{replies: map[string]stubReply{"supervisorctl status tor": tc.reply}}
+			st, err := (&supervisordBackend{run: stub.run, now: func() time.Time { return testNow }}).Status("tor")
+			checkStatus(t, tc, st, err)
+		})
+	}
+}
+
+func TestSupervisordRun(t *testing.T) {
+	replies := map[string]stubReply{
+		"supervisorctl status tor":     {out: "tor RUNNING pid 1, uptime 0:00:01\n"},
+		"supervisorctl start tor":      {out: "tor: started\n"},
+		"supervisorctl stop tor":       {out: "tor: stopped\n"},
+		"supervisorctl restart tor":    {out: "tor: stopped\ntor: started\n"},
+		"supervisorctl signal HUP tor": {out: "tor: signalled\n"},
+	}
+	newBackend := func(s *stubRunner) ServiceManager {
+		return &supervisordBackend{run: s.run, now: func() time.Time { return testNow }}
+	}
+	checkActions(t, newBackend, "tor", replies, []actionCase{
+		{action: Start, argv: []string{"supervisorctl start tor", "supervisorctl status tor"}},
+		{action: Stop, argv: []string{"supervisorctl stop tor", "supervisorctl status tor"}},
+		{action: Restart, argv: []string{"supervisorctl restart tor", "supervisorctl status tor"}},
+		{action: Reload, argv: []string{"supervisorctl signal HUP tor", "supervisorctl status tor"}},
+		{action: Status, argv: []string{"supervisorctl status tor"}, err: true},
+	})
+}
@@ -0,0 +1,325 @@
+// File: internal/services/systemd.go
+// Purpose: Control systemd units over D-Bus (falling back to systemctl) with structured state and job tracking
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	systemdDest    = "org.freedesktop.systemd1"
+	systemdPath    = dbus.ObjectPath("/org/freedesktop/systemd1")
+	systemdManager = "org.freedesktop.systemd1.Manager"
+)
+
+// JobTimeout bounds how long an action waits for its systemd job to finish
+var JobTimeout = 90 * time.Second
+
+// UnitStatus is a systemd unit's state as reported over D-Bus
+type UnitStatus struct {
+	Unit           string     `json:"unit"`
+	LoadState      string     `json:"load_state"`
+	ActiveState    string     `json:"active_state"` // active, inactive, failed, activating, deactivating, reloading
+	SubState       string     `json:"sub_state"`
+	Result         string     `json:"result,omitempty"` // "success", "exit-code", "signal", ...
+	MainPID        uint32     `json:"main_pid"`
+	NRestarts      uint32     `json:"n_restarts"`
+	ExecMainStatus int32      `json:"exec_main_status"`
+	ActiveSince    *time.Time `json:"active_since,omitempty"`
+	InactiveSince  *time.Time `json:"inactive_since,omitempty"`
+	StateChanged   *time.Time `json:"state_changed,omitempty"`
+}
+
+// JobResult is the outcome of a start/stop/restart/reload job
+type JobResult struct {
+	Job    string `json:"job"`
+	Result string `json:"result"` // done, canceled, timeout, failed, dependency, skipped
+}
+
+// Systemd talks to the systemd manager on a D-Bus connection
+type Systemd struct {
+	conn *dbus.Conn
+}
+
+// NewSystemd connects to the system bus
+func NewSystemd() (*Systemd, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	return &Systemd{conn: conn}, nil
+}
+
+// NewSystemdConn uses an existing connection, e.g. a private bus
+func NewSystemdConn(conn *dbus.Conn) *Systemd {
+	return &Systemd{conn: conn}
+}
+
+// Close closes the bus connection
+func (s *Systemd) Close() error {
+	return s.conn.Close()
+}
+
+var unitSuffixes = []string{".service", ".socket", ".target", ".timer", ".path", ".mount", ".scope", ".slice"}
+
+// unitName adds ".service" when name has no unit type suffix
+func unitName(name string) string {
+	for _, suffix := range unitSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return name
+		}
+	}
+	return name + ".service"
+}
+
+// Status loads the unit and reads its state properties
+func (s *Systemd) Status(name string) (*UnitStatus, error) {
+	unit := unitName(name)
+	var path dbus.ObjectPath
+	if err := s.conn.Object(systemdDest, systemdPath).Call(systemdManager+".LoadUnit", 0, unit).Store(&path); err != nil {
+		return nil, err
+	}
+	obj := s.conn.Object(systemdDest, path)
+	var unitProps, serviceProps map[string]dbus.Variant
+	if err := obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, "org.freedesktop.systemd1.Unit").Store(&unitProps); err != nil {
+		return nil, err
+	}
+	// Non-service units have no Service interface; that is not an error
+	_ = obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, "org.freedesktop.systemd1.Service").Store(&serviceProps)
+
+	st := &UnitStatus{
+		Unit:          unit,
+		LoadState:     propString(unitProps, "LoadState"),
+		ActiveState:   propString(unitProps, "ActiveState"),
+		SubState:      propString(unitProps, "SubState"),
+		ActiveSince:   propTime(unitProps, "ActiveEnterTimestamp"),
+		InactiveSince: propTime(unitProps, "InactiveEnterTimestamp"),
+		StateChanged:  propTime(unitProps, "StateChangeTimestamp"),
+		Result:        propString(serviceProps, "Result"),
+	}
+	if v, ok := serviceProps["MainPID"].Value().(uint32); ok {
+		st.MainPID = v
+	}
+	if v, ok := serviceProps["NRestarts"].Value().(uint32); ok {
+		st.NRestarts = v
+	}
+	if v, ok := serviceProps["ExecMainStatus"].Value().(int32); ok {
+		st.ExecMainStatus = v
+	}
+	return st, nil
+}
+
+func propString(props map[string]dbus.Variant, key string) string {
+	v, _ := props[key].Value().(string)
+	return v
+}
+
+// propTime converts systemd's microseconds-since-epoch timestamps
+func propTime(props map[string]dbus.Variant, key string) *time.Time {
+	v, ok := props[key].Value().(uint64)
+	if !ok || v == 0 {
+		return nil
+	}
+	t := time.UnixMicro(int64(v)).UTC()
+	return &t
+}
+
+// Run queues a start, stop, restart or reload job and waits for systemd to
+// report its result
+func (s *Systemd) Run(ctx context.Context, name string, action Action) (*JobResult, error) {
+	methods := map[Action]string{Start: "StartUnit", Stop: "StopUnit", Restart: "RestartUnit", Reload: "ReloadUnit"}
+	method, ok := methods[action]
+	if !ok {
+		return nil, errors.New("unsupported action")
+	}
+	unit := unitName(name)
+
+	// Subscribe before queuing so a fast job cannot finish unseen
+	match := []dbus.MatchOption{dbus.WithMatchInterface(systemdManager), dbus.WithMatchMember("JobRemoved")}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return nil, err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+	signals := make(chan *dbus.Signal, 16)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	var job dbus.ObjectPath
+	if err := s.conn.Object(systemdDest, systemdPath).CallWithContext(ctx, systemdManager+"."+method, 0, unit, "replace").Store(&job); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, JobTimeout)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return &JobResult{Job: string(job), Result: "timeout"}, fmt.Errorf("%s %s: job did not finish in %s", action, unit, JobTimeout)
+		case sig := <-signals:
+			// JobRemoved(u id, o job, s unit, s result)
+			if len(sig.Body) < 4 {
+				continue
+			}
+			if p, _ := sig.Body[1].(dbus.ObjectPath); p != job {
+				continue
+			}
+			result, _ := sig.Body[3].(string)
+			res := &JobResult{Job: string(job), Result: result}
+			if result != "done" {
+				return res, fmt.Errorf("%s %s: job %s", action, unit, result)
+			}
+			return res, nil
+		}
+	}
+}
+
+// UnitState returns structured systemd state for a service via D-Bus
+func UnitState(serviceName string) (*UnitStatus, error) {
+	sd, err := NewSystemd()
+	if err != nil {
+		return nil, err
+	}
+	defer sd.Close()
+	return sd.Status(serviceName)
+}
+
+// systemdBackend drives systemd over D-Bus and falls back to systemctl when
+// the bus is unreachable
+type systemdBackend struct{}
+
+func (b *systemdBackend) Name() string { return "systemd" }
+
+func (b *systemdBackend) Status(service string) (*ServiceStatus, error) {
+	sd, err := NewSystemd()
+	if err != nil {
+		return (&systemctlBackend{run: execRunner}).Status(service)
+	}
+	defer sd.Close()
+	st, err := sd.Status(service)
+	if err != nil {
+		return nil, err
+	}
+	return unitServiceStatus(service, st), nil
+}
+
+func (b *systemdBackend) Run(service string, action Action) (*ServiceStatus, error) {
+	sd, err := NewSystemd()
+	if err != nil {
+		return (&systemctlBackend{run: execRunner}).Run(service, action)
+	}
+	defer sd.Close()
+	job, err := sd.Run(context.Background(), service, action)
+	unit, stErr := sd.Status(service)
+	if stErr != nil {
+		return nil, combineErr(err, stErr)
+	}
+	st := unitServiceStatus(service, unit)
+	if job != nil {
+		st.Output = fmt.Sprintf("%s job %s", action, job.Result)
+	}
+	return st, err
+}
+
+// unitState maps systemd's ActiveState onto the common states
+func unitState(active string) State {
+	switch active {
+	case "active", "reloading":
+		return StateRunning
+	case "inactive":
+		return StateStopped
+	case "failed":
+		return StateFailed
+	case "activating":
+		return StateStarting
+	case "deactivating":
+		return StateStopping
+	}
+	return StateUnknown
+}
+
+func unitServiceStatus(service string, u *UnitStatus) *ServiceStatus {
+	st := &ServiceStatus{
+		Manager:  "systemd",
+		Service:  service,
+		State:    unitState(u.ActiveState),
+		Detail:   u.ActiveState + " (" + u.SubState + ")",
+		PID:      int(u.MainPID),
+		Restarts: int(u.NRestarts),
+		Since:    u.StateChanged,
+	}
+	// A reload changes the state timestamp but not how long tor has been up
+	if st.State == StateRunning && u.ActiveSince != nil {
+		st.Since = u.ActiveSince
+	}
+	// The main process has exited at least once when the unit went inactive
+	if u.InactiveSince != nil {
+		st.LastExit = intPtr(int(u.ExecMainStatus))
+	}
+	return st
+}
+
+// systemctlBackend shells out to systemctl
+type systemctlBackend struct {
+	run commandRunner
+}
+
+func (b *systemctlBackend) Name() string { return "systemctl" }
+
+func (b *systemctlBackend) Status(service string) (*ServiceStatus, error) {
+	out, err := b.run("systemctl", "show", unitName(service),
+		"--property=ActiveState,SubState,MainPID,NRestarts,ExecMainStatus,ActiveEnterTimestamp,StateChangeTimestamp,InactiveEnterTimestamp")
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show: %w: %s", err, strings.TrimSpace(out))
+	}
+	props := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[k] = v
+		}
+	}
+	u := &UnitStatus{
+		Unit:          unitName(service),
+		ActiveState:   props["ActiveState"],
+		SubState:      props["SubState"],
+		ActiveSince:   showTime(props["ActiveEnterTimestamp"]),
+		StateChanged:  showTime(props["StateChangeTimestamp"]),
+		InactiveSince: showTime(props["InactiveEnterTimestamp"]),
+	}
+	if n, err := strconv.ParseUint(props["MainPID"], 10, 32); err == nil {
+		u.MainPID = uint32(n)
+	}
+	if n, err := strconv.ParseUint(props["NRestarts"], 10, 32); err == nil {
+		u.NRestarts = uint32(n)
+	}
+	if n, err := strconv.ParseInt(props["ExecMainStatus"], 10, 32); err == nil {
+		u.ExecMainStatus = int32(n)
+	}
+	st := unitServiceStatus(service, u)
+	st.Manager = b.Name()
+	return st, nil
+}
+
+// showTime parses systemctl show timestamps like "Mon 2026-10-19 10:00:00 UTC".
+// systemctl prints the local zone's abbreviation, which only resolves to the
+// right offset when parsed in the local location.
+func showTime(v string) *time.Time {
+	t, err := time.ParseInLocation("Mon 2006-01-02 15:04:05 MST", v, time.Local)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func (b *systemctlBackend) Run(service string, action Action) (*ServiceStatus, error) {
+	return runThenStatus(b, service, func() (string, error) {
+		return b.run("systemctl", string(action), unitName(service))
+	})
+}
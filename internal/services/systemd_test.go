@@ -0,0 +1,328 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// privateBus starts a throwaway dbus-daemon and returns its address
+func privateBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "bus.conf")
+	err = os.WriteFile(conf, []byte(`<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=`+filepath.Join(dir, "bus")+`</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(daemon, "--config-file="+conf, "--nofork", "--print-address")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	addr, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("dbus-daemon: %v", err)
+	}
+	return strings.TrimSpace(addr)
+}
+
+// fakeSystemd answers the Manager and Properties calls Systemd makes
+type fakeSystemd struct {
+	conn   *dbus.Conn
+	result string // JobRemoved result; empty never finishes the job
+
+	mu    sync.Mutex
+	calls []string
+	jobs  uint32
+}
+
+func (f *fakeSystemd) LoadUnit(name string) (dbus.ObjectPath, *dbus.Error) {
+	if name == "missing.service" {
+		return "", dbus.NewError("org.freedesktop.systemd1.NoSuchUnit", []interface{}{"Unit missing.service not found."})
+	}
+	return unitPath(name), nil
+}
+
+func (f *fakeSystemd) StartUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return f.queue("StartUnit", name, mode)
+}
+
+func (f *fakeSystemd) StopUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return f.queue("StopUnit", name, mode)
+}
+
+func (f *fakeSystemd) RestartUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return f.queue("RestartUnit", name, mode)
+}
+
+func (f *fakeSystemd) ReloadUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return f.queue("ReloadUnit", name, mode)
+}
+
+// queue records the call and, like systemd, announces the job's end with
+// JobRemoved, preceded by an unrelated job finishing
+func (f *fakeSystemd) queue(method, name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, method+" "+name+" "+mode)
+	f.jobs += 2
+	id := f.jobs
+	f.mu.Unlock()
+	job := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/systemd1/job/%d", id))
+	if f.result != "" {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			other := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/systemd1/job/%d", id-1))
+			f.conn.Emit(systemdPath, systemdManager+".JobRemoved", id-1, other, "other.service", "failed")
+			f.conn.Emit(systemdPath, systemdManager+".JobRemoved", id, job, name, f.result)
+		}()
+	}
+	return job, nil
+}
+
+// unitPath escapes a unit name the way systemd does for its object paths
+func unitPath(name string) dbus.ObjectPath {
+	var b strings.Builder
+	for _, c := range []byte(name) {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return dbus.ObjectPath("/org/freedesktop/systemd1/unit/" + b.String())
+}
+
+// unitProps serves GetAll for one unit object
+type unitProps map[string]map[string]dbus.Variant
+
+func (p unitProps) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	props, ok := p[iface]
+	if !ok {
+		return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []interface{}{iface})
+	}
+	return props, nil
+}
+
+var (
+	activeEnter = time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	reloaded    = time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)
+	stopped     = time.Date(2026, 10, 17, 22, 15, 0, 0, time.UTC)
+)
+
+// startFakeSystemd claims org.freedesktop.systemd1 on a private bus and
+// returns a Systemd client connected to it
+func startFakeSystemd(t *testing.T, result string) (*Systemd, *fakeSystemd) {
+	t.Helper()
+	addr := privateBus(t)
+	server, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	fake := &fakeSystemd{conn: server, result: result}
+	if err := server.Export(fake, systemdPath, systemdManager); err != nil {
+		t.Fatal(err)
+	}
+	usec := func(t time.Time) dbus.Variant { return dbus.MakeVariant(uint64(t.UnixMicro())) }
+	if err := server.Export(unitProps{
+		"org.freedesktop.systemd1.Unit": {
+			"LoadState":              dbus.MakeVariant("loaded"),
+			"ActiveState":            dbus.MakeVariant("active"),
+			"SubState":               dbus.MakeVariant("running"),
+			"ActiveEnterTimestamp":   usec(activeEnter),
+			"InactiveEnterTimestamp": usec(stopped),
+			"StateChangeTimestamp":   usec(reloaded),
+		},
+		"org.freedesktop.systemd1.Service": {
+			"Result":         dbus.MakeVariant("success"),
+			"MainPID":        dbus.MakeVariant(uint32(4242)),
+			"NRestarts":      dbus.MakeVariant(uint32(3)),
+			"ExecMainStatus": dbus.MakeVariant(int32(1)),
+		},
+	}, unitPath("tor.service"), "org.freedesktop.DBus.Properties"); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Export(unitProps{
+		"org.freedesktop.systemd1.Unit": {
+			"LoadState":   dbus.MakeVariant("loaded"),
+			"ActiveState": dbus.MakeVariant("active"),
+			"SubState":    dbus.MakeVariant("waiting"),
+		},
+	}, unitPath("tor-rotate.timer"), "org.freedesktop.DBus.Properties"); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := server.RequestName(systemdDest, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("RequestName = %v, %v", reply, err)
+	}
+
+	client, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd := NewSystemdConn(client)
+	t.Cleanup(func() { sd.Close() })
+	return sd, fake
+}
+
+func TestSystemdStatus(t *testing.T) {
+	sd, _ := startFakeSystemd(t, "done")
+
+	st, err := sd.Status("tor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Unit != "tor.service" || st.LoadState != "loaded" || st.ActiveState != "active" || st.SubState != "running" {
+		t.Errorf("unit state = %+v", st)
+	}
+	if st.MainPID != 4242 || st.NRestarts != 3 || st.ExecMainStatus != 1 || st.Result != "success" {
+		t.Errorf("service properties = %+v", st)
+	}
+	if st.ActiveSince == nil || !st.ActiveSince.Equal(activeEnter) || st.StateChanged == nil || !st.StateChanged.Equal(reloaded) {
+		t.Errorf("timestamps = %v %v", st.ActiveSince, st.StateChanged)
+	}
+
+	timer, err := sd.Status("tor-rotate.timer")
+	if err != nil {
+		t.Fatalf("unit without a Service interface: %v", err)
+	}
+	if timer.Unit != "tor-rotate.timer" || timer.SubState != "waiting" || timer.MainPID != 0 {
+		t.Errorf("timer = %+v", timer)
+	}
+
+	if _, err := sd.Status("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing unit error = %v", err)
+	}
+}
+
+func TestSystemdRun(t *testing.T) {
+	sd, fake := startFakeSystemd(t, "done")
+
+	for _, action := range []Action{Start, Stop, Restart, Reload} {
+		res, err := sd.Run(context.Background(), "tor", action)
+		if err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+		if res.Result != "done" || !strings.HasPrefix(res.Job, "/org/freedesktop/systemd1/job/") {
+			t.Errorf("%s job = %+v", action, res)
+		}
+	}
+	want := []string{"StartUnit tor.service replace", "StopUnit tor.service replace", "RestartUnit tor.service replace", "ReloadUnit tor.service replace"}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if strings.Join(fake.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q", fake.calls)
+	}
+	if _, err := sd.Run(context.Background(), "tor", Status); err == nil {
+		t.Error("status is not a job and must be refused")
+	}
+}
+
+func TestSystemdRunJobFailed(t *testing.T) {
+	sd, _ := startFakeSystemd(t, "dependency")
+	res, err := sd.Run(context.Background(), "tor", Start)
+	if err == nil || !strings.Contains(err.Error(), "job dependency") {
+		t.Errorf("err = %v", err)
+	}
+	if res == nil || res.Result != "dependency" {
+		t.Errorf("job = %+v", res)
+	}
+}
+
+func TestSystemdRunJobTimeout(t *testing.T) {
+	defer func(d time.Duration) { JobTimeout = d }(JobTimeout)
+	JobTimeout = 100 * time.Millisecond
+
+	sd, _ := startFakeSystemd(t, "")
+	res, err := sd.Run(context.Background(), "tor", Restart)
+	if err == nil || res == nil || res.Result != "timeout" {
+		t.Errorf("Run = %+v, %v; want a timeout", res, err)
+	}
+}
+
+func TestUnitServiceStatus(t *testing.T) {
+	sd, _ := startFakeSystemd(t, "done")
+	u, err := sd.Status("tor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := unitServiceStatus("tor", u)
+	if st.State != StateRunning || st.PID != 4242 || st.Restarts != 3 || st.Detail != "active (running)" {
+		t.Errorf("status = %+v", st)
+	}
+	// uptime counts from activation, not from the last reload
+	if st.Since == nil || !st.Since.Equal(activeEnter) {
+		t.Errorf("Since = %v, want %v", st.Since, activeEnter)
+	}
+	if st.LastExit == nil || *st.LastExit != 1 {
+		t.Errorf("LastExit = %v", st.LastExit)
+	}
+
+	u.ActiveState, u.SubState = "failed", "failed"
+	st = unitServiceStatus("tor", u)
+	if st.State != StateFailed || st.Since == nil || !st.Since.Equal(reloaded) {
+		t.Errorf("failed unit = %+v", st)
+	}
+}
+
+func TestSystemctlStatus(t *testing.T) {
+	var argv []string
+	b := &systemctlBackend{run: func(name string, args ...string) (string, error) {
+		argv = append([]string{name}, args...)
+		return `ActiveState=active
+SubState=running
+MainPID=812
+NRestarts=2
+ExecMainStatus=0
+ActiveEnterTimestamp=Sun 2026-10-18 09:00:00 UTC
+StateChangeTimestamp=Mon 2026-10-19 08:30:00 UTC
+InactiveEnterTimestamp=
+`, nil
+	}}
+	st, err := b.Status("tor@relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "systemctl show tor@relay.service --property=ActiveState,SubState,MainPID,NRestarts,ExecMainStatus,ActiveEnterTimestamp,StateChangeTimestamp,InactiveEnterTimestamp"
+	if strings.Join(argv, " ") != want {
+		t.Errorf("argv = %q", argv)
+	}
+	if st.Manager != "systemctl" || st.State != StateRunning || st.PID != 812 || st.Restarts != 2 || st.LastExit != nil {
+		t.Errorf("status = %+v", st)
+	}
+	if st.Since == nil || !st.Since.Equal(activeEnter) {
+		t.Errorf("Since = %v, want %v", st.Since, activeEnter)
+	}
+}
@@ -0,0 +1,58 @@
+// File: internal/services/sysv.go
+// Purpose: SysV init script backend (/etc/init.d or the service wrapper)
+
+package services
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+type sysvBackend struct {
+	run commandRunner
+	dir string // init script directory, normally /etc/init.d
+}
+
+func (b *sysvBackend) Name() string { return "sysv" }
+
+// command runs the init script directly, or through service(8) when the
+// script is not in the init directory
+func (b *sysvBackend) command(service string, action Action) (string, error) {
+	script := filepath.Join(b.dir, service)
+	if !exists(script) && inPath("service") {
+		return b.run("service", service, string(action))
+	}
+	return b.run(script, string(action))
+}
+
+// Status follows the LSB exit codes of "status": 0 running, 1 and 2 dead
+// with a stale pid or lock file, 3 not running, 4 unknown
+func (b *sysvBackend) Status(service string) (*ServiceStatus, error) {
+	out, err := b.command(service, Status)
+	st := &ServiceStatus{Manager: b.Name(), Service: service, Output: strings.TrimSpace(out)}
+	code := 0
+	if err != nil {
+		var ok bool
+		if code, ok = exitCode(err); !ok {
+			return nil, err
+		}
+	}
+	switch code {
+	case 0:
+		st.State, st.Detail = StateRunning, "running"
+		st.PID = pidFromFiles(service)
+	case 1, 2:
+		st.State, st.Detail = StateFailed, "dead"
+	case 3:
+		st.State, st.Detail = StateStopped, "not running"
+	default:
+		st.State, st.Detail = StateUnknown, "unknown"
+	}
+	return st, nil
+}
+
+func (b *sysvBackend) Run(service string, action Action) (*ServiceStatus, error) {
+	return runThenStatus(b, service, func() (string, error) {
+		return b.command(service, action)
+	})
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSysvStatus(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "tor-admin-test")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []statusCase{
+		{name: "running", reply: stubReply{out: "tor is running.\n"}, state: StateRunning, detail: "running"},
+		{name: "dead with pid file", reply: stubReply{out: "tor is not running but pid file exists\n", err: exitErr(t, 1)}, state: StateFailed, detail: "dead"},
+		{name: "dead with lock file", reply: stubReply{err: exitErr(t, 2)}, state: StateFailed, detail: "dead"},
+		{name: "not running", reply: stubReply{out: "tor is not running.\n", err: exitErr(t, 3)}, state: StateStopped, detail: "not running"},
+		{name: "unknown", reply: stubReply{err: exitErr(t, 4)}, state: StateUnknown, detail: "unknown"},
+		{name: "script did not run", reply: stubReply{err: os.ErrPermission}, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &stubRunner{replies: map[string]stubReply{script + " status": tc.reply}}
+			st, err := (&sysvBackend{run: stub.run, dir: dir}).Status("tor-admin-test")
+			checkStatus(t, tc, st, err)
+		})
+	}
+}
+
+func TestSysvRun(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "tor")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	replies := map[string]stubReply{
+		script + " status":  {out: "tor is running.\n"},
+		script + " start":   {out: "Starting tor daemon...done.\n"},
+		script + " stop":    {out: "Stopping tor daemon...done.\n"},
+		script + " restart": {},
+		script + " reload":  {out: "Reloading tor daemon configuration...failed.\n", err: exitErr(t, 1)},
+	}
+	newBackend := func(s *stubRunner) ServiceManager { return &sysvBackend{run: s.run, dir: dir} }
+	checkActions(t, newBackend, "tor", replies, []actionCase{
+		{action: Start, argv: []string{script + " start", script + " status"}},
+		{action: Stop, argv: []string{script + " stop", script + " status"}},
+		{action: Restart, argv: []string{script + " restart", script + " status"}},
+		{action: Reload, argv: []string{script + " reload", script + " status"}, err: true},
+	})
+
+	// without a script in the init directory, service(8) is used when present
+	if !inPath("service") {
+		return
+	}
+	stub := &stubRunner{replies: map[string]stubReply{"service tor status": {out: "tor is running.\n"}}}
+	if _, err := (&sysvBackend{run: stub.run, dir: t.TempDir()}).Status("tor"); err != nil {
+		t.Errorf("service(8) fallback: %v (ran %q)", err, stub.calls)
+	}
+}
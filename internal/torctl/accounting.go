@@ -0,0 +1,116 @@
+// File: internal/torctl/accounting.go
+// Purpose: Read live accounting counters and hibernation state via GETINFO accounting/*
+
+package torctl
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"tor-admin/internal/bandwidth"
+)
+
+// AccountingStatus is a snapshot of tor's accounting period
+type AccountingStatus struct {
+	Enabled       bool       `json:"enabled"`
+	Message       string     `json:"message,omitempty"`
+	Hibernating   string     `json:"hibernating,omitempty"` // "awake", "soft" or "hard"
+	Rule          string     `json:"rule,omitempty"`
+	QuotaBytes    int64      `json:"quota_bytes,omitempty"`
+	ReadBytes     int64      `json:"read_bytes"`
+	WrittenBytes  int64      `json:"written_bytes"`
+	ReadLeft      int64      `json:"read_left"`
+	WriteLeft     int64      `json:"write_left"`
+	UsedBytes     int64      `json:"used_bytes"`
+	UsedPercent   float64    `json:"used_percent"`
+	IntervalStart time.Time  `json:"interval_start"`
+	IntervalEnd   time.Time  `json:"interval_end"`
+	IntervalWake  time.Time  `json:"interval_wake"`
+	ExhaustedAt   *time.Time `json:"projected_exhaustion,omitempty"`
+}
+
+// Accounting reads the accounting/* GETINFO keys and the matching
+// AccountingMax/AccountingRule settings. When accounting is off only
+// Enabled and Message are set.
+func (c *Conn) Accounting(now time.Time) (*AccountingStatus, error) {
+	info, err := c.GetInfo("accounting/enabled")
+	if err != nil {
+		return nil, err
+	}
+	if info["accounting/enabled"] != "1" {
+		return &AccountingStatus{Message: "accounting is disabled (AccountingMax is 0)"}, nil
+	}
+	info, err = c.GetInfo(
+		"accounting/hibernating", "accounting/bytes", "accounting/bytes-left",
+		"accounting/interval-start", "accounting/interval-end", "accounting/interval-wake",
+	)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := c.GetConf("AccountingMax", "AccountingRule")
+	if err != nil {
+		return nil, err
+	}
+
+	st := &AccountingStatus{Enabled: true, Hibernating: info["accounting/hibernating"], Rule: "max"}
+	st.ReadBytes, st.WrittenBytes = parsePair(info["accounting/bytes"])
+	st.ReadLeft, st.WriteLeft = parsePair(info["accounting/bytes-left"])
+	st.IntervalStart = parseISOTime(info["accounting/interval-start"])
+	st.IntervalEnd = parseISOTime(info["accounting/interval-end"])
+	st.IntervalWake = parseISOTime(info["accounting/interval-wake"])
+	if v := conf["AccountingRule"]; len(v) > 0 && v[0] != "" {
+		st.Rule = strings.ToLower(v[0])
+	}
+	if v := conf["AccountingMax"]; len(v) > 0 {
+		if q, err := bandwidth.ParseBytes(v[0]); err == nil {
+			st.QuotaBytes = q.Bytes
+		}
+	}
+
+	switch st.Rule {
+	case "sum":
+		st.UsedBytes = st.ReadBytes + st.WrittenBytes
+	case "in":
+		st.UsedBytes = st.ReadBytes
+	case "out":
+		st.UsedBytes = st.WrittenBytes
+	default:
+		st.UsedBytes = max(st.ReadBytes, st.WrittenBytes)
+	}
+	if st.QuotaBytes > 0 {
+		st.UsedPercent = float64(st.UsedBytes) / float64(st.QuotaBytes) * 100
+	}
+
+	// Project exhaustion linearly from the average rate so far this period
+	if elapsed := now.Sub(st.IntervalStart); st.UsedBytes > 0 && elapsed > 0 && st.QuotaBytes > st.UsedBytes {
+		perSec := float64(st.UsedBytes) / elapsed.Seconds()
+		at := now.Add(time.Duration(float64(st.QuotaBytes-st.UsedBytes)/perSec) * time.Second)
+		if at.Before(st.IntervalEnd) {
+			st.ExhaustedAt = &at
+		}
+	} else if st.QuotaBytes > 0 && st.UsedBytes >= st.QuotaBytes {
+		st.ExhaustedAt = &now
+	}
+	return st, nil
+}
+
+// parsePair parses tor's "<read> <written>" byte counters
+func parsePair(s string) (int64, int64) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, 0
+	}
+	a, _ := strconv.ParseInt(fields[0], 10, 64)
+	b, _ := strconv.ParseInt(fields[1], 10, 64)
+	return a, b
+}
+
+// parseISOTime parses tor's "YYYY-MM-DD HH:MM:SS" timestamps, which are UTC
+func parseISOTime(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", strings.Trim(s, `"`))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
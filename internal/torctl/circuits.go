@@ -0,0 +1,158 @@
+// File: internal/torctl/circuits.go
+// Purpose: Parse circuit/stream status lines and close circuits or streams
+
+package torctl
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Relay is one hop of a circuit path
+type Relay struct {
+	Fingerprint string `json:"fingerprint"`
+	Nickname    string `json:"nickname,omitempty"`
+}
+
+// Circuit is one entry from circuit-status or a CIRC event
+type Circuit struct {
+	ID         string    `json:"id"`
+	Status     string    `json:"status"`
+	Path       []Relay   `json:"path"`
+	Purpose    string    `json:"purpose,omitempty"`
+	BuildFlags []string  `json:"build_flags,omitempty"`
+	HSState    string    `json:"hs_state,omitempty"`
+	RendQuery  string    `json:"rend_query,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Created    time.Time `json:"created"`
+	AgeSeconds float64   `json:"age_seconds"`
+	Streams    []Stream  `json:"streams"`
+}
+
+// Stream is one entry from stream-status or a STREAM event
+type Stream struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	CircuitID string `json:"circuit_id"`
+	Target    string `json:"target"`
+	Purpose   string `json:"purpose,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ParseCircuit parses "<ID> <STATUS> [<PATH>] [KEY=VALUE ...]"
+func ParseCircuit(line string) Circuit {
+	fields := strings.Fields(line)
+	var c Circuit
+	if len(fields) < 2 {
+		return c
+	}
+	c.ID, c.Status = fields[0], fields[1]
+	rest := fields[2:]
+	if len(rest) > 0 && (strings.HasPrefix(rest[0], "$") || !strings.Contains(rest[0], "=")) {
+		for _, hop := range strings.Split(rest[0], ",") {
+			c.Path = append(c.Path, parseRelay(hop))
+		}
+		rest = rest[1:]
+	}
+	kv := ParseKeyValues(strings.Join(rest, " "))
+	if f := kv["BUILD_FLAGS"]; f != "" {
+		c.BuildFlags = strings.Split(f, ",")
+	}
+	c.Purpose = kv["PURPOSE"]
+	c.HSState = kv["HS_STATE"]
+	c.RendQuery = kv["REND_QUERY"]
+	c.Reason = kv["REASON"]
+	if t, err := time.Parse("2006-01-02T15:04:05.999999", kv["TIME_CREATED"]); err == nil {
+		c.Created = t
+	}
+	return c
+}
+
+// parseRelay splits "$FINGERPRINT~nickname", "$FINGERPRINT=nickname" or "nickname"
+func parseRelay(hop string) Relay {
+	if !strings.HasPrefix(hop, "$") {
+		return Relay{Nickname: hop}
+	}
+	hop = hop[1:]
+	if i := strings.IndexAny(hop, "~="); i >= 0 {
+		return Relay{Fingerprint: hop[:i], Nickname: hop[i+1:]}
+	}
+	return Relay{Fingerprint: hop}
+}
+
+// ParseStream parses "<ID> <STATUS> <CIRCID> <TARGET> [KEY=VALUE ...]"
+func ParseStream(line string) Stream {
+	fields := strings.Fields(line)
+	var s Stream
+	if len(fields) < 4 {
+		return s
+	}
+	s.ID, s.Status, s.CircuitID, s.Target = fields[0], fields[1], fields[2], fields[3]
+	kv := ParseKeyValues(strings.Join(fields[4:], " "))
+	s.Purpose = kv["PURPOSE"]
+	s.Reason = kv["REASON"]
+	return s
+}
+
+// Circuits returns live circuits with their attached streams
+func (c *Conn) Circuits(now time.Time) ([]Circuit, error) {
+	info, err := c.GetInfo("circuit-status", "stream-status")
+	if err != nil {
+		return nil, err
+	}
+	circuits := []Circuit{}
+	byID := map[string]int{}
+	for _, line := range strings.Split(info["circuit-status"], "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		circ := ParseCircuit(line)
+		circ.Streams = []Stream{}
+		if !circ.Created.IsZero() {
+			circ.AgeSeconds = now.Sub(circ.Created).Seconds()
+		}
+		byID[circ.ID] = len(circuits)
+		circuits = append(circuits, circ)
+	}
+	for _, line := range strings.Split(info["stream-status"], "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		s := ParseStream(line)
+		if i, ok := byID[s.CircuitID]; ok {
+			circuits[i].Streams = append(circuits[i].Streams, s)
+		}
+	}
+	return circuits, nil
+}
+
+// CloseCircuit tears down a circuit with CLOSECIRCUIT
+func (c *Conn) CloseCircuit(id string) error {
+	if !isNumericID(id) {
+		return errors.New("invalid circuit id")
+	}
+	_, err := c.Request("CLOSECIRCUIT " + id)
+	return err
+}
+
+// CloseStream closes a stream with CLOSESTREAM (reason 6 = DONE)
+func (c *Conn) CloseStream(id string) error {
+	if !isNumericID(id) {
+		return errors.New("invalid stream id")
+	}
+	_, err := c.Request("CLOSESTREAM " + id + " 6")
+	return err
+}
+
+func isNumericID(id string) bool {
+	if id == "" || len(id) > 16 {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
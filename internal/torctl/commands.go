@@ -0,0 +1,157 @@
+// File: internal/torctl/commands.go
+// Purpose: Typed wrappers around common control-port commands (GETINFO, GETCONF, SETEVENTS)
+
+package torctl
+
+import (
+	"sort"
+	"strings"
+)
+
+// GetInfo runs GETINFO for the given keys and returns their values
+func (c *Conn) GetInfo(keys ...string) (map[string]string, error) {
+	reply, err := c.Request("GETINFO " + strings.Join(keys, " "))
+	if err != nil {
+		return nil, err
+	}
+	return replyValues(reply), nil
+}
+
+// GetConf runs GETCONF and returns every value per key (options such as
+// HiddenServicePort can repeat). Unset options map to an empty slice.
+func (c *Conn) GetConf(keys ...string) (map[string][]string, error) {
+	reply, err := c.Request("GETCONF " + strings.Join(keys, " "))
+	if err != nil {
+		return nil, err
+	}
+	out := map[string][]string{}
+	for _, l := range reply.Lines {
+		key, val, ok := strings.Cut(l.Text, "=")
+		if !ok {
+			if l.Text != "OK" {
+				out[l.Text] = nil
+			}
+			continue
+		}
+		if strings.HasPrefix(val, `"`) {
+			val, _ = unquote(val)
+		}
+		out[key] = append(out[key], val)
+	}
+	return out, nil
+}
+
+// GetConfLenient is GetConf that skips options this tor does not recognize
+// instead of failing the whole request
+func (c *Conn) GetConfLenient(keys ...string) (map[string][]string, error) {
+	out, err := c.GetConf(keys...)
+	if _, ok := err.(*ReplyError); !ok {
+		return out, err
+	}
+	out = map[string][]string{}
+	for _, k := range keys {
+		one, err := c.GetConf(k)
+		if _, ok := err.(*ReplyError); ok {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for key, v := range one {
+			out[key] = v
+		}
+	}
+	return out, nil
+}
+
+// SetConf changes options on the running tor without touching torrc
+func (c *Conn) SetConf(values map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([]string, 0, len(keys))
+	for _, k := range keys {
+		args = append(args, k+"="+quote(values[k]))
+	}
+	_, err := c.Request("SETCONF " + strings.Join(args, " "))
+	return err
+}
+
+// ResetConf returns options on the running tor to their defaults
+func (c *Conn) ResetConf(keys ...string) error {
+	_, err := c.Request("RESETCONF " + strings.Join(keys, " "))
+	return err
+}
+
+// SaveConf asks tor to write its running configuration over its torrc
+func (c *Conn) SaveConf() error {
+	_, err := c.Request("SAVECONF")
+	return err
+}
+
+// SetEvents subscribes handler to the given event types. Each call adds to
+// the subscription; pass a nil handler to drop a type. Handlers run on the
+// connection's reader goroutine and must not issue commands on the same Conn.
+func (c *Conn) SetEvents(handler func(Event), types ...string) error {
+	c.hmu.Lock()
+	for _, t := range types {
+		if handler == nil {
+			delete(c.handlers, t)
+		} else {
+			c.handlers[t] = handler
+		}
+	}
+	all := make([]string, 0, len(c.handlers))
+	for t := range c.handlers {
+		all = append(all, t)
+	}
+	c.hmu.Unlock()
+	sort.Strings(all)
+	_, err := c.Request(strings.TrimSpace("SETEVENTS " + strings.Join(all, " ")))
+	return err
+}
+
+func (c *Conn) dispatch(reply *Reply) {
+	first := reply.Lines[0]
+	typ, text, _ := strings.Cut(first.Text, " ")
+	ev := Event{Type: typ, Text: text}
+	if first.Data != "" {
+		ev.Lines = append(ev.Lines, strings.Split(first.Data, "\n")...)
+	}
+	for _, l := range reply.Lines[1:] {
+		if l.Text != "OK" {
+			ev.Lines = append(ev.Lines, l.Text)
+		}
+		if l.Data != "" {
+			ev.Lines = append(ev.Lines, strings.Split(l.Data, "\n")...)
+		}
+	}
+	c.hmu.Lock()
+	handler := c.handlers[typ]
+	c.hmu.Unlock()
+	if handler != nil {
+		handler(ev)
+	}
+}
+
+// replyValues collects "key=value" and "key=\n<data>" lines into a map
+func replyValues(reply *Reply) map[string]string {
+	out := map[string]string{}
+	for _, l := range reply.Lines {
+		key, val, ok := strings.Cut(l.Text, "=")
+		if !ok {
+			continue
+		}
+		if l.Data != "" {
+			val = l.Data
+		} else if strings.HasPrefix(val, `"`) {
+			// a list of quoted strings (net/listeners/*) is left for the caller
+			if s, n := unquote(val); n == len(val) {
+				val = s
+			}
+		}
+		out[key] = val
+	}
+	return out
+}
@@ -0,0 +1,352 @@
+// File: internal/torctl/conn.go
+// Purpose: Minimal Tor control-port client (dial, authenticate, commands, async events)
+
+package torctl
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplyLine is one line of a control-port reply; Data holds the body of "+" lines
+type ReplyLine struct {
+	Status int
+	Text   string
+	Data   string
+}
+
+// Reply is a complete (possibly multi-line) control-port reply
+type Reply struct {
+	Status int
+	Lines  []ReplyLine
+}
+
+// ReplyError is returned when tor answers a command with a 4xx/5xx status
+type ReplyError struct {
+	Status int
+	Text   string
+}
+
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("tor: %d %s", e.Status, e.Text)
+}
+
+// Event is an asynchronous 650 notification
+type Event struct {
+	Type  string   // e.g. "CIRC", "STREAM", "NOTICE"
+	Text  string   // first line without the event type
+	Lines []string // continuation lines and data bodies, if any
+}
+
+// Conn is a single authenticated control-port connection. Commands are
+// serialized; events are delivered on a separate goroutine.
+type Conn struct {
+	addr    string // as dialed, keys per-tor state such as the NEWNYM rate limit
+	conn    net.Conn
+	mu      sync.Mutex // serializes commands
+	replies chan *Reply
+	readErr chan error
+	done    chan struct{} // closed when the connection drops
+
+	hmu      sync.Mutex
+	handlers map[string]func(Event)
+}
+
+// Dial connects to a control port ("127.0.0.1:9051") or socket ("unix:/run/tor/control")
+func Dial(addr string) (*Conn, error) {
+	dialed := addr
+	network := "tcp"
+	if strings.HasPrefix(addr, "unix:") {
+		network, addr = "unix", strings.TrimPrefix(addr, "unix:")
+	} else if strings.HasPrefix(addr, "/") {
+		network = "unix"
+	}
+	nc, err := net.DialTimeout(network, addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{
+		addr:     dialed,
+		conn:     nc,
+		replies:  make(chan *Reply, 1),
+		readErr:  make(chan error, 1),
+		done:     make(chan struct{}),
+		handlers: map[string]func(Event){},
+	}
+	go c.readLoop(bufio.NewReader(nc))
+	return c, nil
+}
+
+// DialEnv dials and authenticates using TOR_CONTROL_ADDR and TOR_CONTROL_PASSWORD
+func DialEnv() (*Conn, error) {
+	addr := os.Getenv("TOR_CONTROL_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:9051"
+	}
+	return DialAuth(addr, os.Getenv("TOR_CONTROL_PASSWORD"))
+}
+
+// DialAuth dials addr and authenticates with the best method tor offers
+func DialAuth(addr, password string) (*Conn, error) {
+	c, err := Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Authenticate(password); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Done is closed once the connection has been lost or closed
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the underlying connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Authenticate performs PROTOCOLINFO and then NULL, SAFECOOKIE, COOKIE or
+// HASHEDPASSWORD authentication, preferring cookies when a password is not given
+func (c *Conn) Authenticate(password string) error {
+	reply, err := c.Request("PROTOCOLINFO 1")
+	if err != nil {
+		return err
+	}
+	var methods []string
+	var cookieFile string
+	for _, l := range reply.Lines {
+		if !strings.HasPrefix(l.Text, "AUTH ") {
+			continue
+		}
+		kv := ParseKeyValues(strings.TrimPrefix(l.Text, "AUTH "))
+		methods = strings.Split(kv["METHODS"], ",")
+		cookieFile = kv["COOKIEFILE"]
+	}
+	has := func(m string) bool {
+		for _, x := range methods {
+			if x == m {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("NULL"):
+		_, err = c.Request("AUTHENTICATE")
+	case password != "" && has("HASHEDPASSWORD"):
+		_, err = c.Request("AUTHENTICATE " + quote(password))
+	case has("SAFECOOKIE") && cookieFile != "":
+		err = c.authSafeCookie(cookieFile)
+	case has("COOKIE") && cookieFile != "":
+		var cookie []byte
+		if cookie, err = os.ReadFile(cookieFile); err == nil {
+			_, err = c.Request("AUTHENTICATE " + hex.EncodeToString(cookie))
+		}
+	default:
+		err = errors.New("tor: no usable control-port authentication method")
+	}
+	return err
+}
+
+func (c *Conn) authSafeCookie(cookieFile string) error {
+	cookie, err := os.ReadFile(cookieFile)
+	if err != nil {
+		return err
+	}
+	clientNonce := make([]byte, 32)
+	if _, err := rand.Read(clientNonce); err != nil {
+		return err
+	}
+	reply, err := c.Request("AUTHCHALLENGE SAFECOOKIE " + hex.EncodeToString(clientNonce))
+	if err != nil {
+		return err
+	}
+	kv := ParseKeyValues(strings.TrimPrefix(reply.Lines[0].Text, "AUTHCHALLENGE "))
+	serverHash, err1 := hex.DecodeString(kv["SERVERHASH"])
+	serverNonce, err2 := hex.DecodeString(kv["SERVERNONCE"])
+	if err1 != nil || err2 != nil {
+		return errors.New("tor: malformed AUTHCHALLENGE reply")
+	}
+	msg := append(append(append([]byte{}, cookie...), clientNonce...), serverNonce...)
+	mac := func(key string) []byte {
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write(msg)
+		return h.Sum(nil)
+	}
+	if !hmac.Equal(serverHash, mac("Tor safe cookie authentication server-to-controller hash")) {
+		return errors.New("tor: safe cookie server hash mismatch")
+	}
+	_, err = c.Request("AUTHENTICATE " + hex.EncodeToString(mac("Tor safe cookie authentication controller-to-server hash")))
+	return err
+}
+
+// RequestTimeout bounds how long one command may take to send and answer
+var RequestTimeout = 30 * time.Second
+
+// ErrTimeout is returned when tor does not answer within RequestTimeout
+var ErrTimeout = errors.New("tor: control port request timed out")
+
+// Request sends one command and waits for its reply. 4xx/5xx replies are
+// returned as *ReplyError. On timeout the connection is closed, since a late
+// reply would otherwise be taken as the answer to the next command.
+func (c *Conn) Request(cmd string) (*Reply, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(RequestTimeout))
+	if _, err := io.WriteString(c.conn, cmd+"\r\n"); err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			c.conn.Close()
+			return nil, ErrTimeout
+		}
+		return nil, err
+	}
+	timer := time.NewTimer(RequestTimeout)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		c.conn.Close()
+		return nil, ErrTimeout
+	case reply := <-c.replies:
+		if reply.Status >= 400 {
+			return reply, &ReplyError{Status: reply.Status, Text: reply.Lines[len(reply.Lines)-1].Text}
+		}
+		return reply, nil
+	case err := <-c.readErr:
+		c.readErr <- err // keep it for later callers
+		return nil, err
+	}
+}
+
+// readLoop splits the stream into command replies and 650 events
+func (c *Conn) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			c.readErr <- err
+			return
+		}
+		if reply.Status == 650 {
+			c.dispatch(reply)
+			continue
+		}
+		c.replies <- reply
+	}
+}
+
+func readReply(r *bufio.Reader) (*Reply, error) {
+	reply := &Reply{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) < 4 {
+			return nil, fmt.Errorf("tor: short reply line %q", line)
+		}
+		status, err := strconv.Atoi(line[:3])
+		if err != nil {
+			return nil, fmt.Errorf("tor: bad status in %q", line)
+		}
+		rl := ReplyLine{Status: status, Text: line[4:]}
+		if line[3] == '+' {
+			var data []string
+			for {
+				d, err := r.ReadString('\n')
+				if err != nil {
+					return nil, err
+				}
+				d = strings.TrimRight(d, "\r\n")
+				if d == "." {
+					break
+				}
+				data = append(data, strings.TrimPrefix(d, "."))
+			}
+			rl.Data = strings.Join(data, "\n")
+		}
+		reply.Status = status
+		reply.Lines = append(reply.Lines, rl)
+		if line[3] == ' ' {
+			return reply, nil
+		}
+	}
+}
+
+// ParseKeyValues splits `KEY=VALUE KEY="quoted value"` into a map, skipping bare words
+func ParseKeyValues(s string) map[string]string {
+	out := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		sp := strings.IndexByte(s, ' ')
+		if eq < 0 || (sp >= 0 && sp < eq) {
+			// bare word (e.g. a positional argument); skip it
+			if sp < 0 {
+				break
+			}
+			s = s[sp+1:]
+			continue
+		}
+		key, rest := s[:eq], s[eq+1:]
+		if strings.HasPrefix(rest, `"`) {
+			val, n := unquote(rest)
+			out[key] = val
+			s = rest[n:]
+			continue
+		}
+		if sp = strings.IndexByte(rest, ' '); sp < 0 {
+			out[key], s = rest, ""
+		} else {
+			out[key], s = rest[:sp], rest[sp+1:]
+		}
+	}
+	return out
+}
+
+// unquote decodes a leading control-port quoted string and reports how many bytes it used
+func unquote(s string) (string, int) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(s[i])
+				}
+			}
+		case '"':
+			return b.String(), i + 1
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), len(s)
+}
+
+// quote encodes s as a control-port quoted string
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,72 @@
+package torctl
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeTor accepts one control connection and answers each command with reply;
+// an empty reply never answers
+func fakeTor(t *testing.T, reply string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		for {
+			if _, err := r.ReadString('\n'); err != nil {
+				return
+			}
+			if reply != "" {
+				c.Write([]byte(reply))
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRequest(t *testing.T) {
+	c, err := Dial(fakeTor(t, "250-version=0.4.8.12\r\n250 OK\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	info, err := c.GetInfo("version")
+	if err != nil || info["version"] != "0.4.8.12" {
+		t.Fatalf("GetInfo = %v, %v", info, err)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	defer func(d time.Duration) { RequestTimeout = d }(RequestTimeout)
+	RequestTimeout = 100 * time.Millisecond
+
+	c, err := Dial(fakeTor(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	start := time.Now()
+	if _, err := c.Request("GETINFO version"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Request error = %v, want ErrTimeout", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("Request took %v", waited)
+	}
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Error("connection left open after a timeout")
+	}
+}
@@ -0,0 +1,90 @@
+// File: internal/torctl/health.go
+// Purpose: Build a daemon health model from bootstrap, version, listener and process GETINFO keys
+
+package torctl
+
+import (
+	"strconv"
+	"strings"
+)
+
+var listenerKinds = []string{"or", "dir", "socks", "trans", "natd", "dns", "control", "extor", "httptunnel"}
+
+// Bootstrap is the parsed status/bootstrap-phase event
+type Bootstrap struct {
+	Progress int    `json:"progress"`
+	Tag      string `json:"tag"`
+	Summary  string `json:"summary"`
+	Warning  string `json:"warning,omitempty"`
+}
+
+// Health summarizes whether tor is bootstrapped and usable
+type Health struct {
+	Healthy            bool                `json:"healthy"`
+	Problems           []string            `json:"problems"`
+	Version            string              `json:"version"`
+	VersionStatus      string              `json:"version_status"`
+	PID                int                 `json:"pid"`
+	Bootstrap          Bootstrap           `json:"bootstrap"`
+	CircuitEstablished bool                `json:"circuit_established"`
+	EnoughDirInfo      bool                `json:"enough_dir_info"`
+	Listeners          map[string][]string `json:"listeners"`
+}
+
+// Health queries tor and evaluates it; tor is healthy once fully
+// bootstrapped with a circuit and enough directory info
+func (c *Conn) Health() (*Health, error) {
+	info, err := c.GetInfo("status/bootstrap-phase", "status/circuit-established",
+		"status/enough-dir-info", "version", "status/version/current", "process/pid")
+	if err != nil {
+		return nil, err
+	}
+	h := &Health{
+		Problems:           []string{},
+		Version:            info["version"],
+		VersionStatus:      info["status/version/current"],
+		CircuitEstablished: info["status/circuit-established"] == "1",
+		EnoughDirInfo:      info["status/enough-dir-info"] == "1",
+		Listeners:          map[string][]string{},
+	}
+	h.PID, _ = strconv.Atoi(info["process/pid"])
+
+	kv := ParseKeyValues(info["status/bootstrap-phase"])
+	h.Bootstrap.Progress, _ = strconv.Atoi(kv["PROGRESS"])
+	h.Bootstrap.Tag = kv["TAG"]
+	h.Bootstrap.Summary = kv["SUMMARY"]
+	h.Bootstrap.Warning = kv["WARNING"]
+
+	// Each listener key is asked separately: tor errors on kinds it does not know
+	for _, kind := range listenerKinds {
+		li, err := c.GetInfo("net/listeners/" + kind)
+		if err != nil {
+			continue
+		}
+		for _, addr := range strings.Fields(li["net/listeners/"+kind]) {
+			if strings.HasPrefix(addr, `"`) {
+				addr, _ = unquote(addr)
+			}
+			h.Listeners[kind] = append(h.Listeners[kind], addr)
+		}
+	}
+
+	if h.Bootstrap.Progress < 100 {
+		h.Problems = append(h.Problems, "bootstrapping: "+strconv.Itoa(h.Bootstrap.Progress)+"% "+h.Bootstrap.Summary)
+	}
+	if h.Bootstrap.Warning != "" {
+		h.Problems = append(h.Problems, "bootstrap warning: "+h.Bootstrap.Warning)
+	}
+	if !h.CircuitEstablished {
+		h.Problems = append(h.Problems, "no circuit established")
+	}
+	if !h.EnoughDirInfo {
+		h.Problems = append(h.Problems, "not enough directory info")
+	}
+	switch h.VersionStatus {
+	case "obsolete", "unrecommended":
+		h.Problems = append(h.Problems, "tor version is "+h.VersionStatus)
+	}
+	h.Healthy = h.Bootstrap.Progress >= 100 && h.CircuitEstablished && h.EnoughDirInfo
+	return h, nil
+}
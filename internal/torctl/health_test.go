@@ -0,0 +1,115 @@
+package torctl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"tor-admin/internal/torctl/torctltest"
+)
+
+// getInfo answers GETINFO from info and, like tor, fails the whole
+// command on an unknown key
+func getInfo(info map[string]string) torctltest.Handler {
+	return func(args string) string {
+		var b strings.Builder
+		for _, key := range strings.Fields(args) {
+			v, ok := info[key]
+			if !ok {
+				return "552 Unrecognized key \"" + key + "\"\r\n"
+			}
+			b.WriteString("250-" + key + "=" + v + "\r\n")
+		}
+		return b.String() + "250 OK\r\n"
+	}
+}
+
+func TestHealth(t *testing.T) {
+	base := map[string]string{
+		"status/bootstrap-phase":     `NOTICE BOOTSTRAP PROGRESS=100 TAG=done SUMMARY="Done"`,
+		"status/circuit-established": "1",
+		"status/enough-dir-info":     "1",
+		"version":                    "0.4.8.12",
+		"status/version/current":     "recommended",
+		"process/pid":                "4242",
+		"net/listeners/socks":        `"127.0.0.1:9050" "[::1]:9050"`,
+		"net/listeners/control":      `"127.0.0.1:9051"`,
+	}
+	with := func(changes map[string]string) map[string]string {
+		m := map[string]string{}
+		for k, v := range base {
+			m[k] = v
+		}
+		for k, v := range changes {
+			m[k] = v
+		}
+		return m
+	}
+	tests := []struct {
+		name      string
+		info      map[string]string
+		healthy   bool
+		bootstrap Bootstrap
+		problems  []string
+	}{
+		{
+			name:      "bootstrapped",
+			info:      base,
+			healthy:   true,
+			bootstrap: Bootstrap{Progress: 100, Tag: "done", Summary: "Done"},
+			problems:  []string{},
+		},
+		{
+			name: "still bootstrapping",
+			info: with(map[string]string{
+				"status/bootstrap-phase":     `NOTICE BOOTSTRAP PROGRESS=45 TAG=requesting_descriptors SUMMARY="Asking for relay descriptors"`,
+				"status/circuit-established": "0",
+			}),
+			bootstrap: Bootstrap{Progress: 45, Tag: "requesting_descriptors", Summary: "Asking for relay descriptors"},
+			problems:  []string{"bootstrapping: 45% Asking for relay descriptors", "no circuit established"},
+		},
+		{
+			name: "bootstrap warning",
+			info: with(map[string]string{
+				"status/bootstrap-phase": `WARN BOOTSTRAP PROGRESS=5 TAG=conn SUMMARY="Connecting to a relay" WARNING="Connection refused" REASON=CONNECTREFUSED COUNT=3 RECOMMENDATION=warn`,
+				"status/enough-dir-info": "0",
+			}),
+			bootstrap: Bootstrap{Progress: 5, Tag: "conn", Summary: "Connecting to a relay", Warning: "Connection refused"},
+			problems: []string{"bootstrapping: 5% Connecting to a relay", "bootstrap warning: Connection refused",
+				"not enough directory info"},
+		},
+		{
+			name:      "obsolete version is a problem but still healthy",
+			info:      with(map[string]string{"status/version/current": "obsolete"}),
+			healthy:   true,
+			bootstrap: Bootstrap{Progress: 100, Tag: "done", Summary: "Done"},
+			problems:  []string{"tor version is obsolete"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := torctltest.New(t)
+			srv.Handle("GETINFO", getInfo(tt.info))
+			c, err := DialAuth(srv.Addr, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			h, err := c.Health()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Healthy != tt.healthy || h.Bootstrap != tt.bootstrap || !reflect.DeepEqual(h.Problems, tt.problems) {
+				t.Errorf("Health = healthy %v, bootstrap %+v, problems %q; want %v, %+v, %q",
+					h.Healthy, h.Bootstrap, h.Problems, tt.healthy, tt.bootstrap, tt.problems)
+			}
+			if h.PID != 4242 || h.Version != "0.4.8.12" {
+				t.Errorf("PID/version = %d/%q", h.PID, h.Version)
+			}
+			want := map[string][]string{"socks": {"127.0.0.1:9050", "[::1]:9050"}, "control": {"127.0.0.1:9051"}}
+			if !reflect.DeepEqual(h.Listeners, want) {
+				t.Errorf("Listeners = %v, want %v", h.Listeners, want)
+			}
+		})
+	}
+}
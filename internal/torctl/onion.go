@@ -0,0 +1,159 @@
+// File: internal/torctl/onion.go
+// Purpose: ADD_ONION / DEL_ONION and onion listing for ephemeral v3 services
+
+package torctl
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// AddOnionRequest describes an ephemeral v3 onion service
+type AddOnionRequest struct {
+	PrivateKey string   // "ED25519-V3:<base64>" to reuse a key; empty generates a new one
+	Ports      []string // "virtport[,target]" e.g. "80,127.0.0.1:8080"
+	Flags      []string // Detach, DiscardPK, MaxStreamsCloseCircuit, ...
+	MaxStreams int
+	ClientAuth []string // x25519 public keys (base32) allowed to connect
+}
+
+// AddOnionResult is tor's answer to ADD_ONION
+type AddOnionResult struct {
+	ServiceID  string
+	PrivateKey string // empty when DiscardPK was set or a key was supplied
+}
+
+// AddOnion creates an ephemeral onion service. Unless Detach is set it lives
+// only as long as this control connection.
+func (c *Conn) AddOnion(req AddOnionRequest) (*AddOnionResult, error) {
+	if len(req.Ports) == 0 {
+		return nil, errors.New("at least one port mapping is required")
+	}
+	key := "NEW:ED25519-V3"
+	if req.PrivateKey != "" {
+		if !strings.HasPrefix(req.PrivateKey, "ED25519-V3:") || strings.ContainsAny(req.PrivateKey, " \r\n") {
+			return nil, errors.New("private key must be ED25519-V3:<base64>")
+		}
+		key = req.PrivateKey
+	}
+	args := []string{"ADD_ONION", key}
+	flags := append([]string(nil), req.Flags...)
+	if len(req.ClientAuth) > 0 {
+		flags = append(flags, "V3Auth")
+	}
+	if len(flags) > 0 {
+		args = append(args, "Flags="+strings.Join(flags, ","))
+	}
+	if req.MaxStreams > 0 {
+		args = append(args, "MaxStreams="+strconv.Itoa(req.MaxStreams))
+	}
+	for _, p := range req.Ports {
+		if strings.ContainsAny(p, " \r\n") {
+			return nil, errors.New("invalid port mapping")
+		}
+		args = append(args, "Port="+p)
+	}
+	for _, k := range req.ClientAuth {
+		if strings.ContainsAny(k, " \r\n") {
+			return nil, errors.New("invalid client auth key")
+		}
+		args = append(args, "ClientAuthV3="+k)
+	}
+	reply, err := c.Request(strings.Join(args, " "))
+	if err != nil {
+		return nil, err
+	}
+	vals := replyValues(reply)
+	return &AddOnionResult{ServiceID: vals["ServiceID"], PrivateKey: vals["PrivateKey"]}, nil
+}
+
+// DelOnion removes an ephemeral onion service by its service ID
+func (c *Conn) DelOnion(serviceID string) error {
+	if strings.ContainsAny(serviceID, " \r\n") || serviceID == "" {
+		return errors.New("invalid service id")
+	}
+	_, err := c.Request("DEL_ONION " + strings.TrimSuffix(serviceID, ".onion"))
+	return err
+}
+
+// Onions returns service IDs owned by this connection and detached ones
+func (c *Conn) Onions() ([]string, []string, error) {
+	info, err := c.GetInfo("onions/current", "onions/detached")
+	if err != nil {
+		// tor answers 551 when a list is empty on some versions
+		if _, ok := err.(*ReplyError); !ok {
+			return nil, nil, err
+		}
+		info = map[string]string{}
+		for _, k := range []string{"onions/current", "onions/detached"} {
+			if one, err := c.GetInfo(k); err == nil {
+				info[k] = one[k]
+			}
+		}
+	}
+	return strings.Fields(info["onions/current"]), strings.Fields(info["onions/detached"]), nil
+}
+
+// ClientAuth is one credential tor holds for connecting to a restricted onion service
+type ClientAuth struct {
+	Address    string   `json:"address"`
+	KeyType    string   `json:"key_type"`
+	ClientName string   `json:"client_name,omitempty"`
+	Flags      []string `json:"flags,omitempty"`
+}
+
+// OnionClientAuthAdd loads an x25519 credential (base64 private key) into tor.
+// With permanent set tor also writes it to ClientOnionAuthDir.
+func (c *Conn) OnionClientAuthAdd(address, privateKeyBase64, name string, permanent bool) error {
+	for _, v := range []string{address, privateKeyBase64, name} {
+		if strings.ContainsAny(v, " \r\n\"") {
+			return errors.New("invalid client auth argument")
+		}
+	}
+	cmd := "ONION_CLIENT_AUTH_ADD " + strings.TrimSuffix(address, ".onion") + " x25519:" + privateKeyBase64
+	if name != "" {
+		cmd += " ClientName=" + name
+	}
+	if permanent {
+		cmd += " Flags=Permanent"
+	}
+	_, err := c.Request(cmd)
+	return err
+}
+
+// OnionClientAuthView lists credentials loaded in tor; private keys are dropped
+func (c *Conn) OnionClientAuthView() ([]ClientAuth, error) {
+	reply, err := c.Request("ONION_CLIENT_AUTH_VIEW")
+	if err != nil {
+		return nil, err
+	}
+	out := []ClientAuth{}
+	for _, l := range reply.Lines {
+		if !strings.HasPrefix(l.Text, "CLIENT ") {
+			continue
+		}
+		fields := strings.Fields(l.Text)
+		if len(fields) < 3 {
+			continue
+		}
+		a := ClientAuth{Address: fields[1]}
+		a.KeyType, _, _ = strings.Cut(fields[2], ":")
+		kv := ParseKeyValues(strings.Join(fields[3:], " "))
+		a.ClientName = kv["ClientName"]
+		if f := kv["Flags"]; f != "" {
+			a.Flags = strings.Split(f, ",")
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// OnionClientAuthRemove drops tor's credential for an onion address
+func (c *Conn) OnionClientAuthRemove(address string) error {
+	if strings.ContainsAny(address, " \r\n") || address == "" {
+		return errors.New("invalid onion address")
+	}
+	_, err := c.Request("ONION_CLIENT_AUTH_REMOVE " + strings.TrimSuffix(address, ".onion"))
+	return err
+}
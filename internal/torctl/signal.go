@@ -0,0 +1,88 @@
+// File: internal/torctl/signal.go
+// Purpose: Send SIGNAL commands, with tor's NEWNYM rate limit tracked locally
+
+package torctl
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signals tor accepts via the control port that tor-admin exposes
+var Signals = []string{"NEWNYM", "RELOAD", "DUMP", "DEBUG", "CLEARDNSCACHE", "HEARTBEAT", "SHUTDOWN", "HALT"}
+
+// NewnymInterval is how often tor honours NEWNYM; extra requests are delayed
+const NewnymInterval = 10 * time.Second
+
+// Signal times per control address, since each tor rate-limits on its own
+var (
+	newnymMu   sync.Mutex
+	lastNewnym = map[string]time.Time{}
+	lastReload = map[string]time.Time{}
+)
+
+// LastReload returns when tor-admin last sent RELOAD to the tor at control
+// address addr (zero if never)
+func LastReload(addr string) time.Time {
+	newnymMu.Lock()
+	defer newnymMu.Unlock()
+	return lastReload[addr]
+}
+
+// SignalResult describes what happened to a signal request
+type SignalResult struct {
+	Signal string `json:"signal"`
+	// DelayedSeconds is set when tor will postpone a NEWNYM because the
+	// previous one was too recent (best effort: only tor-admin's own
+	// requests are tracked)
+	DelayedSeconds int `json:"delayed_seconds,omitempty"`
+}
+
+// NormalizeSignal upper-cases sig, maps HUP to RELOAD and rejects unknown signals
+func NormalizeSignal(sig string) (string, error) {
+	sig = strings.ToUpper(strings.TrimSpace(sig))
+	switch sig {
+	case "HUP":
+		sig = "RELOAD"
+	case "INT":
+		sig = "SHUTDOWN"
+	case "TERM":
+		sig = "HALT"
+	}
+	for _, s := range Signals {
+		if s == sig {
+			return sig, nil
+		}
+	}
+	return "", errors.New("unsupported signal")
+}
+
+// Signal sends SIGNAL <sig>
+func (c *Conn) Signal(sig string) (*SignalResult, error) {
+	sig, err := NormalizeSignal(sig)
+	if err != nil {
+		return nil, err
+	}
+	res := &SignalResult{Signal: sig}
+	if sig == "NEWNYM" {
+		newnymMu.Lock()
+		if wait := NewnymInterval - time.Since(lastNewnym[c.addr]); wait > 0 {
+			res.DelayedSeconds = int(wait.Round(time.Second) / time.Second)
+		}
+		newnymMu.Unlock()
+	}
+	if _, err := c.Request("SIGNAL " + sig); err != nil {
+		return nil, err
+	}
+	newnymMu.Lock()
+	switch sig {
+	case "NEWNYM":
+		lastNewnym[c.addr] = time.Now().Add(time.Duration(res.DelayedSeconds) * time.Second)
+	case "RELOAD":
+		lastReload[c.addr] = time.Now()
+	}
+	newnymMu.Unlock()
+	return res, nil
+}
@@ -0,0 +1,101 @@
+package torctl
+
+import (
+	"testing"
+
+	"tor-admin/internal/torctl/torctltest"
+)
+
+func TestNormalizeSignal(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "newnym", want: "NEWNYM"},
+		{in: " Reload ", want: "RELOAD"},
+		{in: "HUP", want: "RELOAD"},
+		{in: "int", want: "SHUTDOWN"},
+		{in: "TERM", want: "HALT"},
+		{in: "CLEARDNSCACHE", want: "CLEARDNSCACHE"},
+		{in: "USR1", wantErr: true},
+		{in: "KILL", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeSignal(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeSignal(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSignalNewnymDelay(t *testing.T) {
+	a, b := torctltest.New(t), torctltest.New(t)
+	signal := func(addr, sig string) *SignalResult {
+		t.Helper()
+		c, err := DialAuth(addr, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		res, err := c.Signal(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := signal(a.Addr, "newnym"); res.Signal != "NEWNYM" || res.DelayedSeconds != 0 {
+		t.Errorf("first NEWNYM = %+v, want no delay", res)
+	}
+	if res := signal(a.Addr, "NEWNYM"); res.DelayedSeconds < 9 || res.DelayedSeconds > 10 {
+		t.Errorf("second NEWNYM delayed %ds, want about %v", res.DelayedSeconds, NewnymInterval)
+	}
+	// the next one queues behind the delayed one
+	if res := signal(a.Addr, "NEWNYM"); res.DelayedSeconds < 19 || res.DelayedSeconds > 20 {
+		t.Errorf("third NEWNYM delayed %ds, want about %v", res.DelayedSeconds, 2*NewnymInterval)
+	}
+	// another tor has its own rate limit
+	if res := signal(b.Addr, "NEWNYM"); res.DelayedSeconds != 0 {
+		t.Errorf("NEWNYM to a second tor delayed %ds, want 0", res.DelayedSeconds)
+	}
+	for _, srv := range []*torctltest.Server{a, b} {
+		for _, cmd := range srv.Commands() {
+			if cmd != "SIGNAL NEWNYM" {
+				t.Errorf("%s got %q, want SIGNAL NEWNYM", srv.Addr, cmd)
+			}
+		}
+	}
+
+	if !LastReload(b.Addr).IsZero() {
+		t.Fatal("LastReload set before any RELOAD")
+	}
+	signal(b.Addr, "HUP")
+	if LastReload(b.Addr).IsZero() || !LastReload(a.Addr).IsZero() {
+		t.Errorf("LastReload a=%v b=%v, want only b set", LastReload(a.Addr), LastReload(b.Addr))
+	}
+}
+
+func TestSignalRejected(t *testing.T) {
+	srv := torctltest.New(t)
+	srv.Reply("SIGNAL", "552 Unrecognized signal code \"NEWNYM\"\r\n")
+	c, err := DialAuth(srv.Addr, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Signal("NEWNYM"); err == nil {
+		t.Fatal("tor's 552 was not returned")
+	}
+	if _, err := c.Signal("USR2"); err == nil {
+		t.Fatal("unknown signal was sent")
+	}
+	if got := srv.Commands(); len(got) != 1 {
+		t.Errorf("commands = %q, want only the NEWNYM", got)
+	}
+	// a refused NEWNYM does not count towards the rate limit
+	srv.Reply("SIGNAL", "250 OK\r\n")
+	if res, err := c.Signal("NEWNYM"); err != nil || res.DelayedSeconds != 0 {
+		t.Errorf("NEWNYM after a refused one = %+v, %v; want no delay", res, err)
+	}
+}
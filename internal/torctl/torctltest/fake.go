@@ -0,0 +1,126 @@
+// File: internal/torctl/torctltest/fake.go
+// Purpose: Scriptable fake tor control port for tests of packages that talk to tor
+
+package torctltest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Handler answers one command; args is the text after the command verb.
+// The reply must be complete control-port lines ending in "\r\n".
+type Handler func(args string) string
+
+// Server accepts any number of control connections, authenticates them with
+// the NULL method and answers commands with the registered handlers; other
+// commands get "250 OK"
+type Server struct {
+	Addr string
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	commands []string
+}
+
+// New starts a server that stops when the test ends
+func New(t testing.TB) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Addr: ln.Addr().String(), handlers: map[string]Handler{}}
+	var wg sync.WaitGroup
+	conns := map[net.Conn]struct{}{}
+	var connMu sync.Mutex
+	t.Cleanup(func() {
+		ln.Close()
+		connMu.Lock()
+		for c := range conns {
+			c.Close()
+		}
+		connMu.Unlock()
+		wg.Wait()
+	})
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			connMu.Lock()
+			conns[c] = struct{}{}
+			connMu.Unlock()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.serve(c)
+			}()
+		}
+	}()
+	return s
+}
+
+// Handle sets the handler for a command verb such as "GETINFO"
+func (s *Server) Handle(verb string, h Handler) {
+	s.mu.Lock()
+	s.handlers[strings.ToUpper(verb)] = h
+	s.mu.Unlock()
+}
+
+// Reply answers every verb command with the fixed reply
+func (s *Server) Reply(verb, reply string) {
+	s.Handle(verb, func(string) string { return reply })
+}
+
+// Commands returns the commands received after authentication, in order
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Reset forgets the commands received so far
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.commands = nil
+	s.mu.Unlock()
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		verb, args, _ := strings.Cut(cmd, " ")
+		verb = strings.ToUpper(verb)
+
+		var reply string
+		switch verb {
+		case "PROTOCOLINFO":
+			reply = "250-PROTOCOLINFO 1\r\n250-AUTH METHODS=NULL\r\n250-VERSION Tor=\"0.4.8.12\"\r\n250 OK\r\n"
+		case "AUTHENTICATE":
+			reply = "250 OK\r\n"
+		default:
+			s.mu.Lock()
+			s.commands = append(s.commands, cmd)
+			h := s.handlers[verb]
+			s.mu.Unlock()
+			reply = "250 OK\r\n"
+			if h != nil {
+				reply = h(args)
+			}
+		}
+		if _, err := c.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
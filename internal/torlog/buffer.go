@@ -0,0 +1,119 @@
+// File: internal/torlog/buffer.go
+// Purpose: Bounded ring buffer of tor log lines with live subscribers
+
+package torlog
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Levels in increasing severity, as named by tor's log events
+var Levels = []string{"DEBUG", "INFO", "NOTICE", "WARN", "ERR"}
+
+// LevelIndex returns a level's severity rank, or -1 if unknown
+func LevelIndex(level string) int {
+	level = strings.ToUpper(level)
+	for i, l := range Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// Entry is one log line
+type Entry struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Instance string    `json:"instance"` // id of the tor instance that logged the line
+	Level    string    `json:"level"`
+	Source   string    `json:"source"` // "control" for log events, "stdout"/"stderr"/"supervisor" for a supervised tor
+	Message  string    `json:"message"`
+}
+
+// Filter selects entries of Instance (any when empty) at or above MinLevel
+// containing Query (case-insensitive)
+type Filter struct {
+	Instance string
+	MinLevel string
+	Query    string
+}
+
+// Match reports whether e passes the filter
+func (f Filter) Match(e Entry) bool {
+	if f.Instance != "" && e.Instance != f.Instance {
+		return false
+	}
+	if f.MinLevel != "" && LevelIndex(e.Level) < LevelIndex(f.MinLevel) {
+		return false
+	}
+	return f.Query == "" || strings.Contains(strings.ToLower(e.Message), strings.ToLower(f.Query))
+}
+
+// Buffer keeps the most recent entries so late joiners see history
+type Buffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	seq     uint64
+	subs    map[chan Entry]struct{}
+}
+
+// NewBuffer creates a buffer holding up to size entries
+func NewBuffer(size int) *Buffer {
+	return &Buffer{entries: make([]Entry, size), subs: map[chan Entry]struct{}{}}
+}
+
+// Add records a line from an instance and fans it out to subscribers; slow
+// subscribers miss lines
+func (b *Buffer) Add(instance, level, source, msg string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	e := Entry{Seq: b.seq, Time: time.Now().UTC(), Instance: instance, Level: strings.ToUpper(level), Source: source, Message: msg}
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Recent returns buffered entries matching f, oldest first
+func (b *Buffer) Recent(f Filter) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := []Entry{}
+	start, n := 0, b.next
+	if b.full {
+		start, n = b.next, len(b.entries)
+	}
+	for i := 0; i < n; i++ {
+		e := b.entries[(start+i)%len(b.entries)]
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Subscribe returns a channel of new entries and a function to stop receiving
+func (b *Buffer) Subscribe() (<-chan Entry, func()) {
+	ch := make(chan Entry, 256)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
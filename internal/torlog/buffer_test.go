@@ -0,0 +1,43 @@
+package torlog
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	e := Entry{Instance: "relay", Level: "WARN", Message: "Clock skew detected"}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"same instance", Filter{Instance: "relay"}, true},
+		{"other instance", Filter{Instance: "default"}, false},
+		{"level below", Filter{MinLevel: "notice"}, true},
+		{"level above", Filter{MinLevel: "ERR"}, false},
+		{"query", Filter{Instance: "relay", Query: "clock SKEW"}, true},
+		{"query miss", Filter{Query: "bootstrap"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(e); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBufferKeepsInstance(t *testing.T) {
+	b := NewBuffer(2)
+	b.Add("default", "notice", "control", "one")
+	b.Add("relay", "warn", "control", "two")
+	b.Add("relay", "err", "stderr", "three")
+
+	all := b.Recent(Filter{})
+	if len(all) != 2 || all[0].Message != "two" || all[1].Message != "three" {
+		t.Fatalf("ring = %+v", all)
+	}
+	if all[1].Instance != "relay" || all[1].Level != "ERR" {
+		t.Errorf("entry = %+v", all[1])
+	}
+	if got := b.Recent(Filter{Instance: "default"}); len(got) != 0 {
+		t.Errorf("evicted default entry still returned: %+v", got)
+	}
+}
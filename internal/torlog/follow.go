@@ -0,0 +1,79 @@
+// File: internal/torlog/follow.go
+// Purpose: Follow tor's log events over the control port, scrubbing addresses under SafeLogging
+
+package torlog
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"tor-admin/internal/config"
+	"tor-admin/internal/instance"
+	"tor-admin/internal/torctl"
+)
+
+// safeLogging reports whether the torrc leaves SafeLogging on (tor's default)
+func safeLogging(torrcPath string) bool {
+	tc, err := config.LoadTorrc(torrcPath)
+	if err != nil {
+		return true
+	}
+	v, ok := tc.Get("SafeLogging")
+	return !ok || strings.TrimSpace(v) != "0"
+}
+
+// Follow subscribes to inst's log events at minLevel and above, feeding b
+// until ctx ends. Dropped control connections are retried with backoff.
+func Follow(ctx context.Context, b *Buffer, minLevel string, inst *instance.Instance) {
+	idx := LevelIndex(minLevel)
+	if idx < 0 {
+		idx = LevelIndex("NOTICE")
+	}
+	levels := Levels[idx:]
+	backoff := time.Second
+	for {
+		if err := followOnce(ctx, b, inst, levels, safeLogging(inst.Torrc)); err != nil {
+			log.Printf("tor log follower %s: %v", inst.ID, err)
+		} else {
+			backoff = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func followOnce(ctx context.Context, b *Buffer, inst *instance.Instance, levels []string, scrub bool) error {
+	ctl, err := inst.Dial()
+	if err != nil {
+		return err
+	}
+	defer ctl.Close()
+	err = ctl.SetEvents(func(ev torctl.Event) {
+		msgs := ev.Lines
+		if ev.Text != "" {
+			msgs = append([]string{ev.Text}, msgs...)
+		}
+		for _, msg := range msgs {
+			if scrub {
+				msg = Scrub(msg)
+			}
+			b.Add(inst.ID, ev.Type, "control", msg)
+		}
+	}, levels...)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+	case <-ctl.Done():
+	}
+	return nil
+}
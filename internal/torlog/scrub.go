@@ -0,0 +1,67 @@
+// File: internal/torlog/scrub.go
+// Purpose: Scrub IP addresses from log lines the way tor does with SafeLogging
+
+package torlog
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// token is a run of characters an address (with port) can be made of;
+	// anything else, such as spaces, commas or '/', ends a candidate
+	token = regexp.MustCompile(`[\w:.\[\]]+`)
+
+	ipv4Port   = regexp.MustCompile(`^(\d{1,3}(?:\.\d{1,3}){3})(:\d{1,5})?$`)
+	ipv6Braced = regexp.MustCompile(`^\[([0-9A-Fa-f:.]+)\](:\d{1,5})?$`)
+	ipv6Bare   = regexp.MustCompile(`^[0-9A-Fa-f:.]+$`)
+)
+
+// Scrub replaces IPv4/IPv6 addresses (and a following port) the way tor does
+// with SafeLogging, leaving version numbers and "::" in ordinary text alone
+func Scrub(msg string) string {
+	return token.ReplaceAllStringFunc(msg, func(t string) string {
+		if isAddress(t) {
+			return "[scrubbed]"
+		}
+		// Sentence punctuation after an address is not part of it
+		if body := strings.TrimRight(t, ".:"); body != t && isAddress(body) {
+			return "[scrubbed]" + t[len(body):]
+		}
+		return t
+	})
+}
+
+func isAddress(t string) bool {
+	if m := ipv4Port.FindStringSubmatch(t); m != nil {
+		return isIPv4(m[1])
+	}
+	if m := ipv6Braced.FindStringSubmatch(t); m != nil {
+		return isIPv6(m[1])
+	}
+	return ipv6Bare.MatchString(t) && isIPv6(t)
+}
+
+// isIPv4 wants four 0-255 octets without leading zeros. 0.x.y.z never names
+// a host and is how tor's own versions look ("Tor 0.4.8.12").
+func isIPv4(s string) bool {
+	for i, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n > 255 || (len(part) > 1 && part[0] == '0') || (i == 0 && n == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIPv6 wants a parseable IPv6 address with at least one hex digit, so a
+// lone "::" is not one
+func isIPv6(s string) bool {
+	if !strings.Contains(s, ":") || !strings.ContainsAny(s, "0123456789abcdefABCDEF") {
+		return false
+	}
+	return net.ParseIP(s) != nil
+}
@@ -0,0 +1,35 @@
+package torlog
+
+import "testing"
+
+func TestScrub(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Tor 0.4.8.12 running on Linux", "Tor 0.4.8.12 running on Linux"},
+		{"Tor 0.4.9.0-alpha-dev (git-1a2b3c4d)", "Tor 0.4.9.0-alpha-dev (git-1a2b3c4d)"},
+		{"OpenSSL 3.0.13 and Zlib 1.3.1", "OpenSSL 3.0.13 and Zlib 1.3.1"},
+		{"libevent 2.1.12-stable", "libevent 2.1.12-stable"},
+		{"caught std::string exception", "caught std::string exception"},
+		{"separator :: in text", "separator :: in text"},
+		{"Bootstrapped 100% (done): Done at 10:00:00.000", "Bootstrapped 100% (done): Done at 10:00:00.000"},
+		{"dotted run 1.2.3.4.5 is no address", "dotted run 1.2.3.4.5 is no address"},
+		{"octet 256.1.1.1 is out of range", "octet 256.1.1.1 is out of range"},
+		{"Opening OR listener on 0.0.0.0:9001", "Opening OR listener on 0.0.0.0:9001"},
+		{"connection to 203.0.113.7 failed", "connection to [scrubbed] failed"},
+		{"connection to 203.0.113.7:9001 failed", "connection to [scrubbed] failed"},
+		{"our address is 198.51.100.2.", "our address is [scrubbed]."},
+		{"peers 192.0.2.1,192.0.2.2", "peers [scrubbed],[scrubbed]"},
+		{"from [2001:db8::1]:443 refused", "from [scrubbed] refused"},
+		{"from [2001:db8::1] refused", "from [scrubbed] refused"},
+		{"address 2001:db8:0:1:2:3:4:5 reachable", "address [scrubbed] reachable"},
+		{"loopback ::1 only", "loopback [scrubbed] only"},
+		{"prefix 2001:db8:: announced", "prefix [scrubbed] announced"},
+		{"mapped ::ffff:192.0.2.9 seen", "mapped [scrubbed] seen"},
+	}
+	for _, tt := range tests {
+		if got := Scrub(tt.in); got != tt.want {
+			t.Errorf("Scrub(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
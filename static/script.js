@@ -9,9 +9,50 @@ document.addEventListener('DOMContentLoaded', () => {
     hookConfigSave();
   }
 
+  if (document.getElementById('instance-select')) {
+    loadInstances();
+  }
+
+  if (document.getElementById('drift-panel')) {
+    loadDrift();
+  }
+
   if (document.getElementById('amount')) {
     loadHiddenServices();
   }
+
+  if (document.getElementById('health-panel')) {
+    loadHealth();
+  }
+
+  if (document.getElementById('acct-panel')) {
+    loadAccounting();
+  }
+
+  if (document.getElementById('sched-heatmap')) {
+    loadSchedule();
+  }
+
+  if (document.getElementById('mp-panels')) {
+    loadMetricsPort();
+  }
+
+  if (document.getElementById('log-view')) {
+    initLogViewer();
+  }
+
+  if (document.getElementById('circuit-table')) {
+    loadCircuits();
+    watchCircuits();
+  }
+
+  if (document.getElementById('onions-page')) {
+    loadOnionServices();
+    loadEphemeral();
+    loadCredentials();
+    loadVanity();
+    loadProxySites();
+  }
 });
 
 // ========================
@@ -38,20 +79,16 @@ function renderConfigForm() {
 
           const labelText = document.createElement('span');
           labelText.className = 'label-text font-medium';
-          labelText.textContent = `${opt.Name} (${opt.Type})`;
+          labelText.textContent = `${opt.name} (${opt.type})`;
 
           const resetBtn = document.createElement('button');
           resetBtn.type = 'button';
           resetBtn.className = 'btn btn-xs btn-outline';
           resetBtn.textContent = 'Reset';
-          resetBtn.onclick = () => {
-            const field = document.getElementById(`opt-${opt.Name}`);
-            field.value = opt.Default;
-            if (field.type === 'checkbox') field.checked = opt.Default === '1';
-          };
+          resetBtn.onclick = () => setFieldDefault(document.getElementById(`opt-${opt.name}`), opt);
 
           label.appendChild(labelText);
-          if (opt.Resettable) label.appendChild(resetBtn);
+          if (opt.resettable) label.appendChild(resetBtn);
 
           const input = createInputField(opt);
           wrapper.appendChild(label);
@@ -65,25 +102,40 @@ function renderConfigForm() {
 }
 
 function createInputField(opt) {
+  if (opt.input_type === 'select') {
+    const select = document.createElement('select');
+    select.id = `opt-${opt.name}`;
+    select.name = opt.name;
+    select.className = 'select select-bordered w-full';
+    (opt.choices || []).forEach((choice) => {
+      const option = document.createElement('option');
+      option.value = choice;
+      option.textContent = choice;
+      select.appendChild(option);
+    });
+    setFieldDefault(select, opt);
+    return select;
+  }
+
   const input = document.createElement('input');
-  input.id = `opt-${opt.Name}`;
-  input.name = opt.Name;
-  input.placeholder = opt.Placeholder || '';
+  input.id = `opt-${opt.name}`;
+  input.name = opt.name;
+  input.placeholder = opt.placeholder || '';
   input.className = 'input input-bordered w-full';
 
-  switch (opt.InputType) {
+  switch (opt.input_type) {
     case 'number':
       input.type = 'number';
-      input.value = opt.Default;
+      input.value = opt.default;
       break;
     case 'checkbox':
       input.type = 'checkbox';
       input.className = 'toggle';
-      input.checked = opt.Default === '1';
+      input.checked = opt.default === '1';
       break;
     default:
       input.type = 'text';
-      input.value = opt.Default;
+      input.value = opt.default;
   }
 
   return input;
@@ -94,17 +146,24 @@ function resetAllToDefaults() {
     Object.values(configData)
       .flat()
       .forEach((opt) => {
-        const field = document.getElementById(`opt-${opt.Name}`);
-        if (field) {
-          if (field.type === 'checkbox') {
-            field.checked = opt.Default === '1';
-          } else {
-            field.value = opt.Default;
-          }
-        }
+        const field = document.getElementById(`opt-${opt.name}`);
+        if (field) setFieldDefault(field, opt);
       });
 }
 
+// setFieldDefault puts an option's default into its input, toggle or select;
+// a select whose choices lack the default falls back to the first choice
+function setFieldDefault(field, opt) {
+  if (field.tagName === 'SELECT') {
+    field.value = opt.default;
+    if (field.selectedIndex < 0) field.selectedIndex = 0;
+  } else if (field.type === 'checkbox') {
+    field.checked = opt.default === '1';
+  } else {
+    field.value = opt.default;
+  }
+}
+
 function hookConfigSave() {
   document.getElementById('config-form').addEventListener('submit', (e) => {
     e.preventDefault();
@@ -119,13 +178,13 @@ function hookConfigSave() {
       Object.values(configData)
         .flat()
         .forEach((opt) => {
-          if (opt.InputType === 'checkbox') {
-            const el = document.getElementById(`opt-${opt.Name}`);
-            body[opt.Name] = el.checked ? '1' : '0';
+          if (opt.input_type === 'checkbox') {
+            const el = document.getElementById(`opt-${opt.name}`);
+            body[opt.name] = el.checked ? '1' : '0';
           }
         });
 
-    fetch('/api/torrc', {
+    fetch(withInstance('/api/torrc'), {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify(body),
@@ -136,11 +195,53 @@ function hookConfigSave() {
   });
 }
 
+function loadDrift() {
+  const out = document.getElementById('drift-result');
+  fetch(withInstance('/api/drift'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      if (data.in_sync) {
+        out.innerText = `✅ ${data.torrc} matches the running configuration.`;
+        return;
+      }
+      const label = {
+        unsaved: 'unsaved (changed live)',
+        pending_reload: 'pending reload (torrc edited)',
+        differs: 'differs (both changed, or unknown)',
+      };
+      out.innerHTML =
+        '<table class="table table-xs"><tr><th>Option</th><th>State</th><th>torrc</th><th>Running</th></tr>' +
+        data.drifts
+          .map((d) => `<tr><td>${d.key}</td><td>${label[d.kind]}</td><td>${d.disk.join('<br>')}</td><td>${d.live.join('<br>')}</td></tr>`)
+          .join('') +
+        '</table>';
+    })
+    .catch((err) => {
+      out.innerText = err;
+    });
+}
+
+function resolveDrift(action) {
+  const msg =
+    action === 'saveconf'
+      ? 'Overwrite torrc with the running configuration? tor rewrites the file and drops comments.'
+      : 'Reload tor so it matches torrc?';
+  if (!confirm(msg)) return;
+  fetch(withInstance('/api/drift/resolve'), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ action }),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then(() => setTimeout(loadDrift, 1000))
+    .catch((err) => alert(`Failed: ${err}`));
+}
+
 // ========================
 // BANDWIDTH + INDEX FEATURES
 // ========================
 function calculateBandwidth() {
-  const amount = parseInt(document.getElementById('amount').value || '0');
+  const amount = parseFloat(document.getElementById('amount').value || '0');
   const unit = document.getElementById('unit').value;
   const interval = document.getElementById('interval').value;
 
@@ -149,31 +250,20 @@ function calculateBandwidth() {
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify({ amount, unit, interval }),
   })
-    .then((res) => res.json())
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
     .then((data) => {
-      document.getElementById('bw-result').innerText = `≈ ${data.human} (${data.bps} bytes/sec)`;
-      renderGraph(amount, unit, interval);
+      document.getElementById('bw-result').innerText = `≈ ${data.human} (${data.bps} bytes/sec) → BandwidthRate ${data.rate}`;
+      renderGraph(data.bps);
+    })
+    .catch((err) => {
+      document.getElementById('bw-result').innerText = err;
     });
 }
 
-function renderGraph(amount, unit, interval) {
+function renderGraph(bps) {
   const ctx = document.getElementById('bwChart')?.getContext('2d');
   if (!ctx) return;
 
-  const seconds = {
-    daily: 86400,
-    weekly: 604800,
-    monthly: 2592000,
-  }[interval];
-
-  const multiplier = {
-    KB: 1024,
-    MB: 1024 * 1024,
-    GB: 1024 * 1024 * 1024,
-    TB: 1024 * 1024 * 1024 * 1024,
-  }[unit];
-
-  const bps = (amount * multiplier) / seconds;
   const data = Array.from({ length: 24 }, () => Math.round(bps * 3600 * (Math.random() * 0.2 + 0.9)));
 
   if (window.bwChart) window.bwChart.destroy();
@@ -195,8 +285,907 @@ function renderGraph(amount, unit, interval) {
   });
 }
 
+function loadHealth() {
+  fetch(withInstance('/api/health'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      const summary = document.getElementById('health-summary');
+      const bar = document.getElementById('health-bootstrap');
+      if (data.reachable === false) {
+        bar.value = 0;
+        summary.innerText = `❌ tor unreachable: ${data.problems.join('; ')}`;
+        return;
+      }
+      bar.value = data.bootstrap.progress;
+      const listeners = Object.entries(data.listeners)
+        .map(([kind, addrs]) => `${kind}: ${addrs.join(', ')}`)
+        .join(' · ');
+      const lines = [
+        `${data.healthy ? '✅ Healthy' : '⚠️ Unhealthy'} — bootstrap ${data.bootstrap.progress}% (${data.bootstrap.summary})`,
+        `Tor ${data.version} (${data.version_status}) · PID ${data.pid}`,
+        `Listeners: ${listeners || 'none'}`,
+      ];
+      if (data.problems.length) lines.push(`Problems: ${data.problems.join('; ')}`);
+      summary.innerText = lines.join('\n');
+    })
+    .catch((err) => {
+      document.getElementById('health-summary').innerText = err;
+    });
+}
+
+// ========================
+// Tor instances: the selection is remembered and sent as ?instance=
+function currentInstance() {
+  return localStorage.getItem('torInstance') || '';
+}
+
+function withInstance(url) {
+  const id = currentInstance();
+  if (!id) return url;
+  return url + (url.includes('?') ? '&' : '?') + 'instance=' + encodeURIComponent(id);
+}
+
+function loadInstances() {
+  const select = document.getElementById('instance-select');
+  fetch('/api/instances')
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      select.innerHTML = '';
+      data.instances.forEach((inst) => {
+        const opt = document.createElement('option');
+        opt.value = inst.id;
+        opt.textContent = `${inst.id} (${inst.unit})`;
+        opt.title = `${inst.torrc} · ${inst.control_addr}`;
+        select.appendChild(opt);
+      });
+      let current = currentInstance() || data.default;
+      if (!data.instances.some((inst) => inst.id === current)) {
+        localStorage.removeItem('torInstance');
+        current = data.default;
+      }
+      select.value = current;
+      select.classList.toggle('hidden', data.instances.length < 2);
+    });
+  select.addEventListener('change', () => {
+    localStorage.setItem('torInstance', select.value);
+    location.reload();
+  });
+}
+
+function loadAccounting() {
+  fetch(withInstance('/api/bandwidth/accounting'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      const summary = document.getElementById('acct-summary');
+      const bar = document.getElementById('acct-progress');
+      if (!data.enabled) {
+        bar.classList.add('hidden');
+        summary.innerText = data.message;
+        return;
+      }
+      bar.value = Math.min(100, data.used_percent);
+      const gb = (n) => (n / 1024 ** 3).toFixed(2) + ' GB';
+      const lines = [
+        `State: ${data.hibernating} · rule ${data.rule}`,
+        `Used ${gb(data.used_bytes)} of ${gb(data.quota_bytes)} (${data.used_percent.toFixed(1)}%)`,
+        `Period: ${new Date(data.interval_start).toLocaleString()} → ${new Date(data.interval_end).toLocaleString()}`,
+        data.projected_exhaustion
+          ? `Projected exhaustion: ${new Date(data.projected_exhaustion).toLocaleString()}`
+          : 'Quota should last the whole period',
+      ];
+      if (data.hibernating !== 'awake') lines.push(`Wakes at: ${new Date(data.interval_wake).toLocaleString()}`);
+      summary.innerText = lines.join('\n');
+    })
+    .catch((err) => {
+      document.getElementById('acct-summary').innerText = err;
+    });
+}
+
+function planAccounting() {
+  const val = (id) => document.getElementById(id).value.trim();
+  const out = document.getElementById('plan-result');
+  fetch('/api/bandwidth/plan', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({
+      quota: val('plan-quota'),
+      rule: val('plan-rule'),
+      start: val('plan-start'),
+      rate: val('plan-rate'),
+      expected: val('plan-expected'),
+    }),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      const f = data.forecast;
+      const lines = [
+        ...data.torrc,
+        '',
+        `Period: ${new Date(data.plan.interval_start).toLocaleString()} → ${new Date(data.plan.interval_end).toLocaleString()}`,
+        f.hibernates
+          ? `Hibernates at ${new Date(f.hibernate_at).toLocaleString()}`
+          : `Quota lasts the whole period (${(f.left_bytes / 1024 ** 3).toFixed(2)} GB to spare)`,
+      ];
+      out.innerText = lines.join('\n');
+    })
+    .catch((err) => {
+      out.innerText = `Plan failed: ${err}`;
+    });
+}
+
+function loadSchedule() {
+  fetch(withInstance('/api/bandwidth/schedule'))
+    .then((res) => res.json())
+    .then(renderSchedule);
+}
+
+function saveSchedule() {
+  let body;
+  try {
+    body = JSON.parse(document.getElementById('sched-json').value);
+  } catch (e) {
+    alert('Schedule is not valid JSON: ' + e.message);
+    return;
+  }
+  fetch(withInstance('/api/bandwidth/schedule'), {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      renderSchedule(data);
+      if (data.apply_error) alert('Saved, but could not apply: ' + data.apply_error);
+    })
+    .catch((err) => alert('Failed to save schedule: ' + err));
+}
+
+function deleteSchedule() {
+  if (!confirm('Delete the schedule and restore the torrc bandwidth values?')) return;
+  fetch(withInstance('/api/bandwidth/schedule'), { method: 'DELETE' })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      renderSchedule(data);
+      if (data.apply_error) alert('Could not restore torrc values: ' + data.apply_error);
+    })
+    .catch((err) => alert('Failed to delete schedule: ' + err));
+}
+
+function renderSchedule(data) {
+  document.getElementById('sched-json').value = JSON.stringify(data.schedule, null, 2);
+  document.getElementById('sched-active').innerText = data.schedule.enabled
+    ? `Active now: rate ${data.active_rate}, burst ${data.active_burst}`
+    : 'Schedule disabled';
+
+  const days = ['Sun', 'Mon', 'Tue', 'Wed', 'Thu', 'Fri', 'Sat'];
+  const peak = Math.max(1, ...data.heatmap.flat());
+  const table = document.getElementById('sched-heatmap');
+  table.innerHTML = '<tr><th></th>' + [...Array(24).keys()].map((h) => `<th>${h}</th>`).join('') + '</tr>';
+  data.heatmap.forEach((hours, d) => {
+    const row = document.createElement('tr');
+    row.innerHTML = `<th>${days[d]}</th>`;
+    hours.forEach((bps, h) => {
+      const cell = document.createElement('td');
+      cell.title = `${days[d]} ${h}:00 — ${(bps / 1024).toFixed(0)} KB/s`;
+      cell.style.background = `rgba(189, 147, 249, ${(bps / peak).toFixed(2)})`;
+      row.appendChild(cell);
+    });
+    table.appendChild(row);
+  });
+}
+
+function loadMetricsPort() {
+  const box = document.getElementById('mp-panels');
+  fetch(withInstance('/api/metricsport'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      if (!data.enabled) {
+        box.innerText = data.message;
+        return;
+      }
+      const p = data.panels;
+      const card = (title, rows) =>
+        `<div class="card bg-base-100 p-3"><h4 class="font-semibold mb-1">${title}</h4>` +
+        (rows.length ? rows.map(([k, v]) => `<div class="flex justify-between"><span>${k}</span><span>${v}</span></div>`).join('') : '<div>No data</div>') +
+        '</div>';
+      const onions = (p.onions || []).flatMap((o) => [
+        [`${o.onion.slice(0, 16)}… intros`, `${o.introductions} (${o.intro_rejected} rejected)`],
+        [`${o.onion.slice(0, 16)}… rendezvous`, `${o.rendezvous} (${o.rendezvous_errors} errors)`],
+        [`${o.onion.slice(0, 16)}… PoW effort`, o.pow_suggested_effort],
+      ]);
+      box.innerHTML =
+        card('Onion services', onions) +
+        card('Relay load', Object.entries(p.relay_load).sort()) +
+        card('DoS mitigation', Object.entries(p.dos).sort());
+    })
+    .catch((err) => {
+      box.innerText = err;
+    });
+}
+
+// ========================
+// CIRCUITS (circuits.html)
+// ========================
+let circuitRows = {};
+
+function loadCircuits() {
+  fetch(withInstance('/api/circuits'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      circuitRows = {};
+      data.circuits.forEach((c) => (circuitRows[c.id] = c));
+      renderCircuits();
+    })
+    .catch((err) => {
+      document.getElementById('circuit-live').innerText = err;
+    });
+}
+
+function watchCircuits() {
+  const status = document.getElementById('circuit-live');
+  const es = new EventSource(withInstance('/api/circuits/events'));
+  es.onopen = () => (status.innerText = 'Live');
+  es.onerror = () => (status.innerText = 'Event feed disconnected, retrying...');
+  es.onmessage = (msg) => {
+    const ev = JSON.parse(msg.data);
+    if (ev.type === 'circuit') {
+      const c = ev.circuit;
+      if (c.status === 'CLOSED' || c.status === 'FAILED') {
+        delete circuitRows[c.id];
+      } else {
+        c.streams = circuitRows[c.id]?.streams || [];
+        if (!c.created || c.created.startsWith('0001')) c.created = circuitRows[c.id]?.created || new Date().toISOString();
+        circuitRows[c.id] = c;
+      }
+    } else {
+      const s = ev.stream;
+      Object.values(circuitRows).forEach((c) => (c.streams = c.streams.filter((x) => x.id !== s.id)));
+      if (s.status !== 'CLOSED' && s.status !== 'FAILED' && circuitRows[s.circuit_id]) {
+        circuitRows[s.circuit_id].streams.push(s);
+      }
+    }
+    renderCircuits();
+  };
+}
+
+function renderCircuits() {
+  const table = document.getElementById('circuit-table');
+  const isAdmin = table.dataset.admin === 'true';
+  const body = table.querySelector('tbody');
+  body.innerHTML = '';
+  Object.values(circuitRows)
+    .sort((a, b) => Number(a.id) - Number(b.id))
+    .forEach((c) => {
+      const row = document.createElement('tr');
+      const path = (c.path || [])
+        .map((h) => `<span title="${h.fingerprint}">${h.nickname || h.fingerprint.slice(0, 8)}</span>`)
+        .join(' → ');
+      const age = c.created && !c.created.startsWith('0001') ? Math.round((Date.now() - Date.parse(c.created)) / 1000) + 's' : '';
+      const streams = c.streams
+        .map(
+          (s) =>
+            `<div>${s.id} ${s.status} ${s.target}` +
+            (isAdmin ? ` <button class="btn btn-xs btn-outline" onclick="closeTor('stream','${s.id}')">×</button>` : '') +
+            '</div>'
+        )
+        .join('');
+      row.innerHTML =
+        `<td>${c.id}</td><td>${c.status}</td><td>${c.purpose || ''}</td><td>${path}</td>` +
+        `<td>${(c.build_flags || []).join(', ')}</td><td>${age}</td><td>${streams}</td>` +
+        `<td>${isAdmin ? `<button class="btn btn-xs btn-error" onclick="closeTor('circuit','${c.id}')">Close</button>` : ''}</td>`;
+      body.appendChild(row);
+    });
+}
+
+function closeTor(type, id) {
+  fetch(withInstance('/api/circuits/close'), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ type, id }),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then(loadCircuits)
+    .catch((err) => alert(`Failed to close ${type}: ${err}`));
+}
+
+// ========================
+// ONION SERVICES (onions.html)
+// ========================
+function onionAdmin() {
+  return document.getElementById('onions-page').dataset.admin === 'true';
+}
+
+function loadOnionServices() {
+  fetch(withInstance('/api/hidden'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      const list = document.getElementById('hs-list');
+      list.innerHTML = '';
+      if (!data.services.length) list.innerHTML = '<p class="opacity-70">No HiddenServiceDir configured</p>';
+      renderOnionBalanceChoices(data.services);
+      data.services.forEach((svc) => {
+        const card = document.createElement('details');
+        card.className = 'collapse collapse-arrow bg-base-200';
+        card.innerHTML = `
+          <summary class="collapse-title">
+            <span class="font-mono">${svc.hostname || '(no hostname yet)'}</span>
+            <span class="text-sm opacity-70 ml-2">${svc.dir} · ${svc.ports.join(', ')} · ${svc.clients} client(s)</span>
+          </summary>
+          <div class="collapse-content">
+            <table class="table table-xs"><tbody class="hs-clients"></tbody></table>
+            <button class="btn btn-xs mt-2 hs-dos-toggle">DoS defenses</button>
+            <div class="hs-dos mt-2"></div>
+          </div>`;
+        card.dataset.dir = svc.dir;
+        if (onionAdmin()) {
+          const pick = document.createElement('input');
+          pick.type = 'checkbox';
+          pick.className = 'checkbox checkbox-xs mr-2 hs-pick';
+          pick.value = svc.dir;
+          pick.onclick = (e) => e.stopPropagation();
+          card.querySelector('summary').prepend(pick);
+        }
+        card.addEventListener('toggle', () => card.open && loadOnionClients(card));
+        card.querySelector('.hs-dos-toggle').onclick = () => loadOnionDoS(card);
+        if (onionAdmin()) {
+          const form = document.createElement('div');
+          form.className = 'flex gap-2 mt-2';
+          form.innerHTML = '<input class="input input-bordered input-xs" placeholder="client name" />';
+          const btn = document.createElement('button');
+          btn.className = 'btn btn-xs btn-primary';
+          btn.textContent = 'Add client';
+          btn.onclick = () => addOnionClient(card, form.querySelector('input').value.trim());
+          form.appendChild(btn);
+          card.querySelector('.collapse-content').appendChild(form);
+        }
+        list.appendChild(card);
+      });
+    })
+    .catch((err) => (document.getElementById('hs-list').innerText = err));
+}
+
+function loadOnionClients(card) {
+  fetch(withInstance('/api/onions/clients?' + new URLSearchParams({ dir: card.dataset.dir })))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      const tbody = card.querySelector('.hs-clients');
+      tbody.innerHTML = '';
+      if (!data.clients.length) tbody.innerHTML = '<tr><td class="opacity-70">No client authorization (public service)</td></tr>';
+      data.clients.forEach((c) => {
+        const tr = document.createElement('tr');
+        tr.innerHTML = `<td>${c.name}</td><td class="font-mono text-xs">${c.error || c.public_key}</td><td></td>`;
+        if (onionAdmin()) {
+          const btn = document.createElement('button');
+          btn.className = 'btn btn-xs btn-error';
+          btn.textContent = 'Revoke';
+          btn.onclick = () => onionClientAction('revoke', card, c.name);
+          tr.lastElementChild.appendChild(btn);
+        }
+        tbody.appendChild(tr);
+      });
+    })
+    .catch((err) => (card.querySelector('.hs-clients').innerHTML = `<tr><td>${err}</td></tr>`));
+}
+
+function addOnionClient(card, name) {
+  onionClientAction('add', card, name).then((data) => {
+    if (!data) return;
+    downloadText(data.client.filename, data.client.client_file);
+    alert(`Client ${data.client.name} added. Its private key was downloaded as ${data.client.filename} and is not kept by tor-admin.`);
+  });
+}
+
+function onionClientAction(action, card, name) {
+  if (action === 'revoke' && !confirm(`Revoke client ${name}?`)) return Promise.resolve();
+  return fetch(withInstance('/api/onions/clients/' + action), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ dir: card.dataset.dir, name }),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      if (!data.reload.ok) alert(`Saved, but tor was not reloaded: ${data.reload.error}`);
+      loadOnionClients(card);
+      return data;
+    })
+    .catch((err) => alert(`Failed to ${action} client: ${err}`));
+}
+
+function downloadText(filename, text) {
+  const a = document.createElement('a');
+  a.href = URL.createObjectURL(new Blob([text], { type: 'text/plain' }));
+  a.download = filename;
+  a.click();
+  URL.revokeObjectURL(a.href);
+}
+
+function loadEphemeral() {
+  fetch(withInstance('/api/onions/ephemeral'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      const tbody = document.querySelector('#eph-table tbody');
+      tbody.innerHTML = '';
+      const known = {};
+      data.known.forEach((s) => (known[s.service_id] = s));
+      const ids = [...new Set([...data.current, ...data.detached])];
+      if (!ids.length) {
+        tbody.innerHTML = '<tr><td colspan="5" class="opacity-70">No ephemeral services</td></tr>';
+      }
+      ids.forEach((id) => {
+        const s = known[id] || {};
+        const tr = document.createElement('tr');
+        const ports = (s.ports || []).map((p) => `${p.virtual_port} → ${p.target || p.virtual_port}`).join(', ');
+        const flags = data.detached.includes(id) ? 'Detach' : '';
+        tr.innerHTML = `<td class="font-mono">${id}.onion</td><td>${ports || '?'}</td><td>${flags}</td><td>${s.persist ? 'yes' : 'no'}</td><td></td>`;
+        if (onionAdmin()) {
+          const btn = document.createElement('button');
+          btn.className = 'btn btn-xs btn-error';
+          btn.textContent = 'Remove';
+          btn.onclick = () => removeEphemeral(id);
+          tr.lastElementChild.appendChild(btn);
+        }
+        tbody.appendChild(tr);
+      });
+    })
+    .catch((err) => {
+      document.querySelector('#eph-table tbody').innerHTML = `<tr><td colspan="5">${err}</td></tr>`;
+    });
+}
+
+function parsePortMappings(text) {
+  return text
+    .split(',')
+    .map((p) => p.trim())
+    .filter(Boolean)
+    .map((p) => {
+      const [port, target] = p.split(/\s+/);
+      return { virtual_port: Number(port), target: target || '' };
+    });
+}
+
+function createEphemeral() {
+  const body = {
+    ports: parsePortMappings(document.getElementById('eph-ports').value),
+    private_key: document.getElementById('eph-key').value.trim(),
+    max_streams: Number(document.getElementById('eph-max-streams').value) || 0,
+    client_auth: document
+      .getElementById('eph-client-auth')
+      .value.split(',')
+      .map((k) => k.trim())
+      .filter(Boolean),
+    detach: document.getElementById('eph-detach').checked,
+    discard_pk: document.getElementById('eph-discard').checked,
+    persist: document.getElementById('eph-persist').checked,
+  };
+  const out = document.getElementById('eph-result');
+  fetch(withInstance('/api/onions/ephemeral/create'), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((svc) => {
+      out.innerText = `Created ${svc.service_id}.onion` + (svc.private_key ? `\nPrivate key (shown once): ${svc.private_key}` : '');
+      loadEphemeral();
+    })
+    .catch((err) => (out.innerText = `Failed: ${err}`));
+}
+
+function removeEphemeral(id) {
+  if (!confirm(`Remove ${id}.onion?`)) return;
+  fetch(withInstance('/api/onions/ephemeral/remove'), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ service_id: id }),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then(loadEphemeral)
+    .catch((err) => alert(`Failed to remove: ${err}`));
+}
+
+function loadCredentials() {
+  const tbody = document.querySelector('#cred-table tbody');
+  fetch(withInstance('/api/onions/credentials'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      document.getElementById('cred-dir').innerText = data.dir + (data.live_error ? ` (tor: ${data.live_error})` : '');
+      const live = new Set((data.live || []).map((c) => c.address + '.onion'));
+      tbody.innerHTML = '';
+      if (!data.files.length) tbody.innerHTML = '<tr><td colspan="5" class="opacity-70">No credentials</td></tr>';
+      data.files.forEach((c) => {
+        const tr = document.createElement('tr');
+        tr.innerHTML = c.error
+          ? `<td>${c.name}</td><td colspan="3" class="text-error">${c.error}</td><td></td>`
+          : `<td>${c.name}</td><td class="font-mono text-xs">${c.address}</td><td class="font-mono text-xs">${c.public_key}</td><td>${live.has(c.address) ? 'yes' : 'no'}</td><td></td>`;
+        if (onionAdmin()) {
+          const btn = document.createElement('button');
+          btn.className = 'btn btn-xs btn-error';
+          btn.textContent = 'Delete';
+          btn.onclick = () => deleteCredential(c.name, live.has(c.address));
+          tr.lastElementChild.appendChild(btn);
+        }
+        tbody.appendChild(tr);
+      });
+    })
+    .catch((err) => (tbody.innerHTML = `<tr><td colspan="5">${err}</td></tr>`));
+}
+
+function importCredential() {
+  const file = document.getElementById('cred-file').files[0];
+  if (!file) return alert('Choose an .auth_private file');
+  const name = document.getElementById('cred-name').value.trim() || file.name.replace(/\.auth_private$/, '');
+  file
+    .text()
+    .then((content) =>
+      fetch(withInstance('/api/onions/credentials/import'), {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name, content, load: document.getElementById('cred-load').checked }),
+      })
+    )
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      if (data.load_error) alert(`Imported, but not loaded into tor: ${data.load_error}`);
+      loadCredentials();
+    })
+    .catch((err) => alert(`Import failed: ${err}`));
+}
+
+function deleteCredential(name, loaded) {
+  if (!confirm(`Delete credential ${name}?`)) return;
+  fetch(withInstance('/api/onions/credentials/delete'), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ name, unload: loaded }),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then(loadCredentials)
+    .catch((err) => alert(`Delete failed: ${err}`));
+}
+
+function formatDuration(seconds) {
+  if (seconds < 60) return `${Math.round(seconds)}s`;
+  if (seconds < 3600) return `${Math.round(seconds / 60)}m`;
+  if (seconds < 86400) return `${(seconds / 3600).toFixed(1)}h`;
+  if (seconds < 86400 * 365) return `${(seconds / 86400).toFixed(1)}d`;
+  return `${(seconds / (86400 * 365)).toFixed(1)}y`;
+}
+
+function loadVanity() {
+  fetch(withInstance('/api/onions/vanity'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      const box = document.getElementById('vanity-jobs');
+      box.innerHTML = '';
+      data.jobs.forEach((job) => {
+        const card = document.createElement('div');
+        card.className = 'card bg-base-200 p-3 text-sm';
+        const pct = (job.probability * 100).toFixed(1);
+        let detail = `${job.attempts.toLocaleString()} keys · ${Math.round(job.keys_per_sec).toLocaleString()} keys/s on ${job.workers} cores`;
+        if (job.state === 'running') detail += ` · expected ${formatDuration(job.eta_seconds || 0)}`;
+        card.innerHTML = `
+          <div class="flex justify-between"><span><b>${job.prefix}</b> — ${job.state}</span><span class="actions flex gap-1"></span></div>
+          <progress class="progress w-full my-1" value="${job.state === 'running' ? pct : 100}" max="100"></progress>
+          <div class="opacity-70">${detail}</div>
+          ${job.address ? `<div class="font-mono mt-1">${job.address}${job.dir ? ' → ' + job.dir : ''}</div>` : ''}`;
+        if (onionAdmin()) {
+          const actions = card.querySelector('.actions');
+          if (job.state === 'found') {
+            const imp = document.createElement('button');
+            imp.className = 'btn btn-xs btn-primary';
+            imp.textContent = 'Import as service';
+            imp.onclick = () => importVanity(job.id);
+            actions.appendChild(imp);
+          }
+          const cancel = document.createElement('button');
+          cancel.className = 'btn btn-xs';
+          cancel.textContent = job.state === 'running' ? 'Cancel' : 'Dismiss';
+          cancel.onclick = () => vanityAction('cancel', { id: job.id });
+          actions.appendChild(cancel);
+        }
+        box.appendChild(card);
+      });
+      clearTimeout(window.vanityTimer);
+      if (data.jobs.some((j) => j.state === 'running')) window.vanityTimer = setTimeout(loadVanity, 2000);
+    });
+}
+
+function vanityAction(action, body) {
+  return fetch(withInstance('/api/onions/vanity/' + action), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      loadVanity();
+      return data;
+    })
+    .catch((err) => alert(`Vanity ${action} failed: ${err}`));
+}
+
+function startVanity() {
+  vanityAction('start', { prefix: document.getElementById('vanity-prefix').value.trim() });
+}
+
+function importVanity(id) {
+  const dir = prompt('HiddenServiceDir for the new service', '/var/lib/tor/');
+  if (!dir) return;
+  const ports = prompt('Port mappings, comma separated', '80 127.0.0.1:8080');
+  if (!ports) return;
+  vanityAction('import', { id, dir, ports: ports.split(',').map((p) => p.trim()) }).then((data) => {
+    if (!data) return;
+    if (!data.reload.ok) alert(`Imported, but tor was not reloaded: ${data.reload.error}`);
+    loadOnionServices();
+  });
+}
+
+function loadOnionDoS(card) {
+  const box = card.querySelector('.hs-dos');
+  fetch(withInstance('/api/onions/dos?' + new URLSearchParams({ dir: card.dataset.dir })))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      box.innerHTML = '';
+      data.options.forEach((opt) => {
+        const row = document.createElement('div');
+        row.className = 'form-control mb-2';
+        const value = data.values[opt.name] ?? '';
+        const input =
+          opt.type === 'bool'
+            ? `<select class="select select-bordered select-xs w-40" data-name="${opt.name}">
+                 <option value="">default (${opt.default})</option>
+                 <option value="1" ${value === '1' ? 'selected' : ''}>1 (on)</option>
+                 <option value="0" ${value === '0' ? 'selected' : ''}>0 (off)</option>
+               </select>`
+            : `<input type="number" min="${opt.min}" max="${opt.max}" class="input input-bordered input-xs w-40"
+                 data-name="${opt.name}" value="${value}" placeholder="default ${opt.default}" />`;
+        row.innerHTML = `<label class="label justify-start gap-2"><span class="label-text font-mono">${opt.name}</span>${input}</label>
+          <span class="text-xs opacity-70">${opt.description}</span>`;
+        box.appendChild(row);
+      });
+      const stats = document.createElement('div');
+      stats.className = 'text-sm mt-2';
+      if (data.metrics) {
+        const p = data.metrics.panel;
+        const reasons = Object.entries(data.metrics.rejected_by_reason)
+          .map(([k, v]) => `${k || 'unknown'}: ${v}`)
+          .join(', ');
+        stats.innerText =
+          `Introductions ${p.introductions} · rejected ${p.intro_rejected}${reasons ? ` (${reasons})` : ''} · ` +
+          `rendezvous ${p.rendezvous} (errors ${p.rendezvous_errors}) · suggested PoW effort ${p.pow_suggested_effort}`;
+      } else {
+        stats.innerText = `Effectiveness unavailable: ${data.metrics_error || 'service has no hostname yet'}`;
+      }
+      box.appendChild(stats);
+      if (onionAdmin()) {
+        const btn = document.createElement('button');
+        btn.className = 'btn btn-xs btn-primary mt-2';
+        btn.textContent = 'Save DoS settings';
+        btn.onclick = () => saveOnionDoS(card);
+        box.appendChild(btn);
+      }
+    })
+    .catch((err) => (box.innerText = err));
+}
+
+function saveOnionDoS(card) {
+  const values = {};
+  card.querySelectorAll('.hs-dos [data-name]').forEach((el) => (values[el.dataset.name] = el.value));
+  fetch(withInstance('/api/onions/dos/save'), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ dir: card.dataset.dir, values }),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      alert(data.reload.ok ? 'Saved and tor reloaded' : `Saved, but tor was not reloaded: ${data.reload.error}`);
+      loadOnionDoS(card);
+    })
+    .catch((err) => alert(`Save failed: ${err}`));
+}
+
+function renderOnionBalanceChoices(services) {
+  const front = document.getElementById('ob-frontend');
+  const backs = document.getElementById('ob-backends');
+  if (!front) return;
+  front.querySelectorAll('option:not([value=""])').forEach((o) => o.remove());
+  backs.innerHTML = '';
+  services
+    .filter((svc) => svc.hostname)
+    .forEach((svc) => {
+      const opt = document.createElement('option');
+      opt.value = svc.dir;
+      opt.textContent = `${svc.hostname} (${svc.dir})`;
+      front.appendChild(opt);
+      const label = document.createElement('label');
+      label.className = 'label cursor-pointer justify-start gap-2';
+      label.innerHTML = `<input type="checkbox" class="checkbox checkbox-xs" value="${svc.dir}" />
+        <span class="font-mono">${svc.hostname}</span><span class="opacity-70">${svc.dir}</span>`;
+      backs.appendChild(label);
+    });
+}
+
+function setupOnionBalance() {
+  const backends = [...document.querySelectorAll('#ob-backends input:checked')].map((c) => c.value);
+  const out = document.getElementById('ob-result');
+  fetch(withInstance('/api/onions/onionbalance'), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ frontend_dir: document.getElementById('ob-frontend').value, backends }),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      out.innerText = `Frontend ${data.frontend}\n\n${data.config_yaml}` + (data.reload.ok ? '' : `\nTor not reloaded: ${data.reload.error}`);
+      downloadText('config.yaml', data.config_yaml);
+      const key = Uint8Array.from(atob(data.key.data), (c) => c.charCodeAt(0));
+      const a = document.createElement('a');
+      a.href = URL.createObjectURL(new Blob([key], { type: 'application/octet-stream' }));
+      a.download = data.key.filename;
+      a.click();
+      URL.revokeObjectURL(a.href);
+    })
+    .catch((err) => (out.innerText = `Failed: ${err}`));
+}
+
+function loadProxySites() {
+  const tbody = document.querySelector('#proxy-table tbody');
+  fetch(withInstance('/api/onions/proxy'))
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      tbody.innerHTML = '';
+      if (!data.sites.length) tbody.innerHTML = '<tr><td colspan="6" class="opacity-70">No proxy sites</td></tr>';
+      data.sites.forEach((site) => {
+        const tr = document.createElement('tr');
+        const state = site.running ? 'running' : `<span class="text-error">${site.error || 'stopped'}</span>`;
+        tr.innerHTML = `<td>${site.name}</td><td class="font-mono">${site.listen}</td><td class="font-mono">${site.upstream}</td>
+          <td class="font-mono text-xs">${site.onion || '(no hostname yet)'}</td><td>${state}</td><td class="flex gap-1"></td>`;
+        if (onionAdmin()) {
+          const edit = document.createElement('button');
+          edit.className = 'btn btn-xs';
+          edit.textContent = 'Edit';
+          edit.onclick = () => {
+            ['name', 'listen', 'upstream', 'dir'].forEach((k) => (document.getElementById('proxy-' + k).value = site[k]));
+            document.getElementById('proxy-scheme').value = site.onion_scheme || 'http';
+          };
+          const del = document.createElement('button');
+          del.className = 'btn btn-xs btn-error';
+          del.textContent = 'Delete';
+          del.onclick = () => proxyAction('delete', { name: site.name });
+          tr.lastElementChild.append(edit, del);
+        }
+        tbody.appendChild(tr);
+      });
+    })
+    .catch((err) => (tbody.innerHTML = `<tr><td colspan="6">${err}</td></tr>`));
+}
+
+function saveProxySite() {
+  const site = {};
+  ['name', 'listen', 'upstream', 'dir'].forEach((k) => (site[k] = document.getElementById('proxy-' + k).value.trim()));
+  site.onion_scheme = document.getElementById('proxy-scheme').value;
+  proxyAction('save', site);
+}
+
+function proxyAction(action, body) {
+  if (action === 'delete' && !confirm(`Delete proxy site ${body.name}?`)) return;
+  fetch(withInstance('/api/onions/proxy/' + action), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then(loadProxySites)
+    .catch((err) => {
+      alert(`Proxy ${action} failed: ${err}`);
+      loadProxySites();
+    });
+}
+
+function exportBackup() {
+  const dirs = [...document.querySelectorAll('.hs-pick:checked')].map((c) => c.value);
+  fetch(withInstance('/api/onions/backup/export'), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ dirs, passphrase: document.getElementById('backup-pass').value }),
+  })
+    .then((res) => (res.ok ? res.blob() : res.text().then((t) => Promise.reject(t))))
+    .then((blob) => {
+      const a = document.createElement('a');
+      a.href = URL.createObjectURL(blob);
+      a.download = `onion-backup-${new Date().toISOString().slice(0, 10)}.tahs`;
+      a.click();
+      URL.revokeObjectURL(a.href);
+    })
+    .catch((err) => alert(`Export failed: ${err}`));
+}
+
+function backupForm(action) {
+  const file = document.getElementById('restore-file').files[0];
+  const out = document.getElementById('restore-result');
+  if (!file) return alert('Choose an archive');
+  if (action === 'restore' && !confirm('Restore onion services from this archive?')) return;
+  const form = new FormData();
+  form.append('archive', file);
+  form.append('passphrase', document.getElementById('restore-pass').value);
+  form.append('overwrite', document.getElementById('restore-overwrite').checked);
+  form.append('any_dir', document.getElementById('restore-any-dir').checked);
+  fetch(withInstance('/api/onions/backup/' + action), { method: 'POST', body: form })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      if (action === 'verify') {
+        out.innerText =
+          `Archive OK, created ${new Date(data.created).toLocaleString()}\n` +
+          data.services.map((s) => `${s.hostname}  ${s.dir}  (${s.files.length} files)`).join('\n');
+      } else {
+        out.innerText = `Restored: ${data.restored.join(', ')}` + (data.reload.ok ? '' : `\nTor not reloaded: ${data.reload.error}`);
+        loadOnionServices();
+      }
+    })
+    .catch((err) => (out.innerText = `Failed: ${err}`));
+}
+
+// ========================
+// LOG VIEWER (logs.html)
+// ========================
+let logSource = null;
+let logPaused = false;
+let logPending = [];
+
+function logQuery() {
+  const level = document.getElementById('log-level').value;
+  const q = document.getElementById('log-search').value;
+  return new URLSearchParams({ level, q }).toString();
+}
+
+function initLogViewer() {
+  document.getElementById('log-level').onchange = reloadLogs;
+  document.getElementById('log-search').oninput = () => {
+    clearTimeout(window.logSearchTimer);
+    window.logSearchTimer = setTimeout(reloadLogs, 300);
+  };
+  reloadLogs();
+}
+
+function reloadLogs() {
+  const view = document.getElementById('log-view');
+  fetch(withInstance('/api/logs?' + logQuery()))
+    .then((res) => res.json())
+    .then((data) => {
+      view.innerHTML = '';
+      data.entries.forEach(appendLog);
+      if (logSource) logSource.close();
+      logSource = new EventSource(withInstance('/api/logs/stream?' + logQuery()));
+      logSource.onmessage = (msg) => {
+        const entry = JSON.parse(msg.data);
+        if (logPaused) logPending.push(entry);
+        else appendLog(entry);
+      };
+    });
+}
+
+function toggleLogPause() {
+  logPaused = !logPaused;
+  document.getElementById('log-pause').innerText = logPaused ? 'Resume' : 'Pause';
+  if (!logPaused) {
+    logPending.forEach(appendLog);
+    logPending = [];
+  }
+}
+
+function appendLog(e) {
+  const view = document.getElementById('log-view');
+  const colors = { ERR: 'text-error', WARN: 'text-warning', NOTICE: 'text-info', INFO: '', DEBUG: 'opacity-60' };
+  const line = document.createElement('div');
+  line.className = colors[e.level] || '';
+  line.textContent = `${new Date(e.time).toLocaleTimeString()} [${e.level.toLowerCase()}] ${e.message}`;
+  const atBottom = view.scrollTop + view.clientHeight >= view.scrollHeight - 4;
+  view.appendChild(line);
+  while (view.childElementCount > 2000) view.removeChild(view.firstChild);
+  if (atBottom) view.scrollTop = view.scrollHeight;
+}
+
 function loadHiddenServices() {
-  fetch('/api/hidden')
+  fetch(withInstance('/api/hidden'))
     .then((res) => res.json())
     .then((data) => {
       const list = document.getElementById('onion-list');
@@ -210,11 +1199,49 @@ function loadHiddenServices() {
     });
 }
 
+function torSignal(signal) {
+  if ((signal === 'SHUTDOWN' || signal === 'HALT') && !confirm(`Send ${signal} to tor?`)) return;
+  const out = document.getElementById('svc-output');
+  fetch(withInstance('/api/signal'), {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ signal }),
+  })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
+    .then((data) => {
+      let msg = `${data.result.signal}: sent`;
+      if (data.result.delayed_seconds) msg += ` (tor rate-limits NEWNYM; takes effect in ~${data.result.delayed_seconds}s)`;
+      out.innerText = msg;
+    })
+    .catch((err) => {
+      out.innerText = `${signal} failed: ${err}`;
+    });
+}
+
 function control(action) {
-  fetch('/api/status?action=' + action)
-    .then((res) => res.text())
+  if (action === 'stop' && !confirm('Stop tor?')) return;
+  const out = document.getElementById('svc-output');
+  out.innerText = `${action}...`;
+  fetch(withInstance('/api/service/' + action), { method: 'POST' })
+    .then((res) => (res.ok ? res.json() : res.text().then((t) => Promise.reject(t))))
     .then((data) => {
-      const out = document.getElementById('svc-output');
-      if (out) out.innerText = data;
+      const lines = [`${data.service} (${data.manager}): ${data.state}${data.detail ? ` · ${data.detail}` : ''}`];
+      if (data.pid) lines.push(`PID ${data.pid} · up ${formatUptime(data.uptime_seconds)}`);
+      if (data.restarts) lines.push(`Restarts: ${data.restarts}`);
+      if (data.last_exit !== null && data.last_exit !== undefined) lines.push(`Last exit: ${data.last_exit}`);
+      if (data.error) lines.push(`Error: ${data.error}`);
+      if (data.output) lines.push('', data.output);
+      out.innerText = lines.join('\n');
+    })
+    .catch((err) => {
+      out.innerText = `${action} failed: ${err}`;
     });
 }
+
+function formatUptime(secs) {
+  secs = Math.floor(secs);
+  const d = Math.floor(secs / 86400);
+  const h = Math.floor((secs % 86400) / 3600);
+  const m = Math.floor((secs % 3600) / 60);
+  return (d ? `${d}d ` : '') + `${h}h ${m}m ${secs % 60}s`;
+}
@@ -4,12 +4,26 @@
 package web
 
 import (
+	"encoding/json"
+	"fmt"
 	"html/template"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"tor-admin/internal/audit"
 	"tor-admin/internal/auth"
 	"tor-admin/internal/bandwidth"
+	"tor-admin/internal/config"
+	"tor-admin/internal/instance"
+	"tor-admin/internal/metrics"
+	"tor-admin/internal/scheduler"
+	"tor-admin/internal/services"
+	"tor-admin/internal/torctl"
+	"tor-admin/internal/torlog"
 )
 
 func IndexHandler(tfs templateFS) http.HandlerFunc {
@@ -22,18 +36,44 @@ func IndexHandler(tfs templateFS) http.HandlerFunc {
 	}
 }
 
-func LoginHandler(tfs templateFS, cfg *auth.UserConfig) http.HandlerFunc {
+func ConfigHandler(tfs templateFS) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl := template.Must(template.ParseFS(tfs, "config.html"))
+		_ = tmpl.Execute(w, nil)
+	}
+}
+
+func CircuitsHandler(tfs templateFS) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl := template.Must(template.ParseFS(tfs, "circuits.html"))
+		_ = tmpl.Execute(w, map[string]any{
+			"Username": auth.GetUser(r),
+			"IsAdmin":  auth.IsAdmin(r),
+		})
+	}
+}
+
+func LogsHandler(tfs templateFS) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl := template.Must(template.ParseFS(tfs, "logs.html"))
+		_ = tmpl.Execute(w, map[string]any{"Levels": torlog.Levels})
+	}
+}
+
+func LoginHandler(tfs templateFS) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			r.ParseForm()
 			user := r.FormValue("username")
 			pass := r.FormValue("password")
 
-			if user == cfg.Username && auth.CheckPassword(pass, cfg.PasswordHash) {
+			cfg, err := auth.LoadOrInitConfig()
+			if err == nil && cfg != nil && user == cfg.Username && auth.CheckPassword(pass, cfg.PasswordHash) {
 				_ = auth.SetSession(w, r, user)
 				http.Redirect(w, r, "/", http.StatusSeeOther)
 				return
 			}
+			metrics.LoginFailures.Inc()
 			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 			return
 		}
@@ -85,29 +125,711 @@ func LogoutHandler() http.HandlerFunc {
 
 // ==== API ====
 
-func StatusAPIHandler() http.HandlerFunc {
+// serviceMu serializes start/stop/restart/reload so two admins cannot race
+var serviceMu sync.Mutex
+
+// ServiceAPIHandler runs /api/service/{action} against the instance's unit and
+// answers with its structured status. Only status may be fetched with GET.
+func ServiceAPIHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`{"ok":true}`))
+		action := services.Action(strings.TrimPrefix(r.URL.Path, "/api/service/"))
+		switch action {
+		case services.Status:
+			if r.Method != http.MethodGet && r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+		case services.Start, services.Stop, services.Restart, services.Reload:
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+		default:
+			http.Error(w, "Unknown service action", http.StatusNotFound)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+
+		if action != services.Status {
+			serviceMu.Lock()
+			defer serviceMu.Unlock()
+		}
+		st, err := services.ServiceAction(inst.Unit, action)
+		if action != services.Status {
+			audit.Record(auth.GetUser(r), "service-"+string(action), inst.Unit, err)
+		}
+		if st == nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		resp := map[string]any{
+			"ok":             err == nil,
+			"instance":       inst.ID,
+			"action":         action,
+			"manager":        st.Manager,
+			"service":        st.Service,
+			"state":          st.State,
+			"detail":         st.Detail,
+			"pid":            st.PID,
+			"since":          st.Since,
+			"uptime_seconds": st.Uptime(time.Now()).Seconds(),
+			"restarts":       st.Restarts,
+			"last_exit":      st.LastExit,
+			"output":         st.Output,
+		}
+		if err != nil {
+			resp["error"] = err.Error()
+		}
+		writeJSON(w, resp)
 	}
 }
 
+type bandwidthRequest struct {
+	Amount   float64 `json:"amount"`
+	Unit     string  `json:"unit"`
+	Value    string  `json:"value"` // alternative to amount+unit, e.g. "500 GBytes"
+	Interval string  `json:"interval"`
+}
+
+// BandwidthAPIHandler converts a quota per interval into a torrc rate, answering
+// in the same bit/byte family the quota was written in
 func BandwidthAPIHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Example: convert 5 GB/month to bytes/sec
-		bps, _ := bandwidth.ToBytesPerSecond(5, bandwidth.GB, bandwidth.Monthly)
-		w.Write([]byte(`{"bps":` + bandwidth.PrettyPrintBytes(bps) + `}`))
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req bandwidthRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if req.Value == "" {
+			req.Value = strconv.FormatFloat(req.Amount, 'f', -1, 64) + " " + req.Unit
+		}
+		quota, err := bandwidth.ParseBytes(req.Value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		interval, err := bandwidth.ParseInterval(req.Interval)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		now := time.Now()
+		bps, err := bandwidth.BytesPerSecondAt(quota.Bytes, interval, now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		begin, end, _ := bandwidth.IntervalBounds(interval, now)
+		writeJSON(w, map[string]any{
+			"period_start": begin,
+			"period_end":   end,
+			"bps":          bps,
+			"human":        bandwidth.PrettyPrintBytes(bps),
+			"quota":        quota.String(),
+			"rate":         bandwidth.FormatRate(bps, quota.IsBits()),
+		})
+	}
+}
+
+// AccountingAPIHandler reports tor's live accounting counters, hibernation
+// state and projected quota exhaustion
+func AccountingAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		ctl, err := inst.Dial()
+		if err != nil {
+			http.Error(w, "Control port unavailable: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer ctl.Close()
+		st, err := ctl.Accounting(time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, st)
+	}
+}
+
+// AccountingPlanAPIHandler plans AccountingMax/Start/Rule plus relay rates for
+// a quota and forecasts hibernation: POST {"quota":"500 GBytes","rule":"sum",
+// "start":"month 1 00:00","expected":"2 MBytes"} or a 24-entry "profile"
+func AccountingPlanAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Quota    string   `json:"quota"`
+			Rule     string   `json:"rule"`
+			Start    string   `json:"start"`
+			Rate     string   `json:"rate"`     // optional override of the suggested relay rate
+			Expected string   `json:"expected"` // flat expected traffic per direction
+			Profile  []string `json:"profile"`  // or one expected rate per hour of day
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		quota, err := bandwidth.ParseBytes(req.Quota)
+		if err != nil {
+			http.Error(w, "quota: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Start == "" {
+			req.Start = "month 1 00:00"
+		}
+		start, err := bandwidth.ParseAccountingStart(req.Start)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rule, err := bandwidth.ParseAccountingRule(req.Rule)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		plan, err := bandwidth.PlanAccounting(quota.Bytes, rule, start, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Rate != "" {
+			rate, err := bandwidth.ParseRate(req.Rate)
+			if err == nil {
+				err = plan.WithRate(rate.Bytes)
+			}
+			if err != nil {
+				http.Error(w, "rate: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Without a traffic estimate assume the relay runs flat out
+		profile := bandwidth.FlatProfile(plan.RateBytes)
+		switch {
+		case len(req.Profile) > 0:
+			if len(req.Profile) != len(profile) {
+				http.Error(w, "profile must have 24 hourly rates", http.StatusBadRequest)
+				return
+			}
+			for i, v := range req.Profile {
+				q, err := bandwidth.ParseRate(v)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("profile hour %d: %v", i, err), http.StatusBadRequest)
+					return
+				}
+				profile[i] = q.Bytes
+			}
+		case req.Expected != "":
+			q, err := bandwidth.ParseRate(req.Expected)
+			if err != nil {
+				http.Error(w, "expected: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			profile = bandwidth.FlatProfile(q.Bytes)
+		}
+
+		writeJSON(w, map[string]any{
+			"plan":     plan,
+			"torrc":    plan.TorrcLines(),
+			"forecast": plan.Simulate(profile),
+		})
+	}
+}
+
+// BandwidthScheduleAPIHandler reads (GET), replaces (PUT/POST) or removes
+// (DELETE) an instance's weekly bandwidth schedule and applies it right away
+func BandwidthScheduleAPIHandler(scheds map[string]*scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		sched, ok := scheds[inst.ID]
+		if !ok {
+			http.Error(w, "No bandwidth schedule for instance "+inst.ID, http.StatusNotFound)
+			return
+		}
+		var applyErr error
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var next bandwidth.Schedule
+			if err := json.NewDecoder(r.Body).Decode(&next); err != nil {
+				http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			if err := next.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if applyErr = sched.Set(next); applyErr == nil {
+				applyErr = sched.Apply(time.Now())
+			}
+			audit.Record(auth.GetUser(r), "bandwidth-schedule", inst.ID, applyErr)
+		case http.MethodDelete:
+			applyErr = sched.Delete()
+			audit.Record(auth.GetUser(r), "bandwidth-schedule-delete", inst.ID, applyErr)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		current := sched.Get()
+		rate, burst := current.At(time.Now())
+		resp := map[string]any{
+			"instance":     inst.ID,
+			"schedule":     current,
+			"heatmap":      current.Heatmap(),
+			"active_rate":  rate,
+			"active_burst": burst,
+		}
+		if applyErr != nil {
+			resp["apply_error"] = applyErr.Error()
+		}
+		writeJSON(w, resp)
+	}
+}
+
+// MetricsPortAPIHandler scrapes tor's MetricsPort and returns the dashboard
+// panels built from it
+func MetricsPortAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		url := metrics.MetricsPortURL(inst.Torrc)
+		if url == "" {
+			writeJSON(w, map[string]any{"enabled": false, "message": "MetricsPort is not configured"})
+			return
+		}
+		families, err := metrics.Scrape(r.Context(), url)
+		if err != nil {
+			http.Error(w, "MetricsPort scrape failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, map[string]any{"enabled": true, "panels": metrics.BuildDashboard(families)})
+	}
+}
+
+// CircuitsAPIHandler lists live circuits with their attached streams
+func CircuitsAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		ctl, err := inst.Dial()
+		if err != nil {
+			http.Error(w, "Control port unavailable: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer ctl.Close()
+		circuits, err := ctl.Circuits(time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, map[string]any{"circuits": circuits})
+	}
+}
+
+// CircuitCloseAPIHandler closes a circuit or stream: POST {"type":"circuit"|"stream","id":"12"}
+func CircuitCloseAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		var req struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		ctl, err := inst.Dial()
+		if err != nil {
+			http.Error(w, "Control port unavailable: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer ctl.Close()
+		switch req.Type {
+		case "circuit":
+			err = ctl.CloseCircuit(req.ID)
+		case "stream":
+			err = ctl.CloseStream(req.ID)
+		default:
+			http.Error(w, "type must be circuit or stream", http.StatusBadRequest)
+			return
+		}
+		audit.Record(auth.GetUser(r), "close-"+req.Type, req.ID, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"closed": true})
+	}
+}
+
+// CircuitEventsHandler streams CIRC and STREAM events as server-sent events
+func CircuitEventsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		ctl, err := inst.Dial()
+		if err != nil {
+			http.Error(w, "Control port unavailable: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer ctl.Close()
+
+		events := make(chan any, 64)
+		err = ctl.SetEvents(func(ev torctl.Event) {
+			var msg any
+			if ev.Type == "CIRC" {
+				msg = map[string]any{"type": "circuit", "circuit": torctl.ParseCircuit(ev.Text)}
+			} else {
+				msg = map[string]any{"type": "stream", "stream": torctl.ParseStream(ev.Text)}
+			}
+			select {
+			case events <- msg:
+			default: // slow client; drop rather than stall tor's event reader
+			}
+		}, "CIRC", "STREAM")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		flusher.Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case msg := <-events:
+				data, _ := json.Marshal(msg)
+				fmt.Fprintf(w, "data: %s\n\n", data)
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// SignalAPIHandler sends a control-port signal: POST {"signal":"NEWNYM"}
+func SignalAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		var req struct {
+			Signal string `json:"signal"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		sig, err := torctl.NormalizeSignal(req.Signal)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctl, err := inst.Dial()
+		if err != nil {
+			audit.Record(auth.GetUser(r), "signal", inst.ID+": "+sig, err)
+			http.Error(w, "Control port unavailable: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer ctl.Close()
+		res, err := ctl.Signal(sig)
+		audit.Record(auth.GetUser(r), "signal", inst.ID+": "+sig, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, map[string]any{"ok": true, "result": res})
+	}
+}
+
+// HealthAPIHandler reports tor's bootstrap, version, listener and process state
+func HealthAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		h, err := torHealth(inst)
+		if err != nil {
+			writeJSON(w, map[string]any{"healthy": false, "reachable": false, "problems": []string{err.Error()}})
+			return
+		}
+		writeJSON(w, h)
+	}
+}
+
+// HealthzHandler is the unauthenticated liveness probe. With HEALTHZ_CHECK_TOR=true
+// it also requires tor to be reachable and bootstrapped, answering 503 otherwise.
+func HealthzHandler() http.HandlerFunc {
+	checkTor := os.Getenv("HEALTHZ_CHECK_TOR") == "true"
+	return func(w http.ResponseWriter, r *http.Request) {
+		if checkTor {
+			inst, _ := instance.Get(instance.DefaultID)
+			h, err := torHealth(inst)
+			if err != nil {
+				http.Error(w, "tor unreachable: "+err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			if !h.Healthy {
+				http.Error(w, "tor unhealthy: "+strings.Join(h.Problems, "; "), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}
+}
+
+func torHealth(inst *instance.Instance) (*torctl.Health, error) {
+	ctl, err := inst.Dial()
+	if err != nil {
+		return nil, err
+	}
+	defer ctl.Close()
+	return ctl.Health()
+}
+
+// logFilter selects the lines of the request's instance, answering 404 itself
+// for unknown ids
+func logFilter(w http.ResponseWriter, r *http.Request) (torlog.Filter, bool) {
+	inst, ok := requestInstance(w, r)
+	if !ok {
+		return torlog.Filter{}, false
+	}
+	return torlog.Filter{Instance: inst.ID, MinLevel: r.URL.Query().Get("level"), Query: r.URL.Query().Get("q")}, true
+}
+
+// LogsAPIHandler returns buffered tor log lines of ?instance=, filtered by ?level= and ?q=
+func LogsAPIHandler(logs *torlog.Buffer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, ok := logFilter(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, map[string]any{"entries": logs.Recent(filter)})
+	}
+}
+
+// LogStreamHandler streams new tor log lines of ?instance= as server-sent events
+func LogStreamHandler(logs *torlog.Buffer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		filter, ok := logFilter(w, r)
+		if !ok {
+			return
+		}
+		entries, cancel := logs.Subscribe()
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		flusher.Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case e := <-entries:
+				if !filter.Match(e) {
+					continue
+				}
+				data, _ := json.Marshal(e)
+				fmt.Fprintf(w, "data: %s\n\n", data)
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// loadedTorrcs remembers, per instance, the torrc as tor last loaded it so a
+// later edit on disk can be told apart from a live SETCONF
+var loadedTorrcs = struct {
+	sync.Mutex
+	m map[string]loadedSnapshot
+}{m: map[string]loadedSnapshot{}}
+
+type loadedSnapshot struct {
+	at time.Time
+	tc *config.TorConfig
+}
+
+// loadedTorrc returns the torrc tor loaded at loadedAt, or nil when unknown.
+// An unchanged file is what tor loaded, and is kept for later comparisons.
+func loadedTorrc(id string, tc *config.TorConfig, loadedAt time.Time, fileNewer bool) *config.TorConfig {
+	if loadedAt.IsZero() {
+		return nil
+	}
+	loadedTorrcs.Lock()
+	defer loadedTorrcs.Unlock()
+	if !fileNewer {
+		loadedTorrcs.m[id] = loadedSnapshot{at: loadedAt, tc: tc}
+		return tc
+	}
+	// loadedAt derived from uptime moves by a second between requests
+	if snap, ok := loadedTorrcs.m[id]; ok && snap.at.Sub(loadedAt).Abs() < 2*time.Second {
+		return snap.tc
+	}
+	return nil
+}
+
+// DriftAPIHandler compares the torrc on disk with tor's running configuration
+func DriftAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		path := inst.Torrc
+		tc, err := config.LoadTorrc(path)
+		if err != nil {
+			http.Error(w, "Failed to read torrc: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctl, err := inst.Dial()
+		if err != nil {
+			http.Error(w, "Control port unavailable: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer ctl.Close()
+
+		var names []string
+		for _, opt := range config.GetAllOptions() {
+			names = append(names, opt.Name)
+		}
+		live, err := ctl.GetConfLenient(names...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		// tor last read torrc when it started or when we last sent RELOAD
+		now := time.Now()
+		loadedAt := torctl.LastReload(inst.ControlAddr)
+		if info, err := ctl.GetInfo("uptime"); err == nil {
+			if secs, err := strconv.ParseInt(info["uptime"], 10, 64); err == nil {
+				if started := now.Add(-time.Duration(secs) * time.Second); started.After(loadedAt) {
+					loadedAt = started
+				}
+			}
+		}
+		fileNewer := false
+		if st, err := os.Stat(path); err == nil {
+			fileNewer = !loadedAt.IsZero() && st.ModTime().After(loadedAt)
+		}
+
+		drifts := config.CheckDrift(tc, loadedTorrc(inst.ID, tc, loadedAt, fileNewer), live)
+		writeJSON(w, map[string]any{
+			"in_sync":    len(drifts) == 0,
+			"drifts":     drifts,
+			"instance":   inst.ID,
+			"torrc":      path,
+			"loaded_at":  loadedAt,
+			"file_newer": fileNewer,
+		})
 	}
 }
 
-func HiddenServicesAPIHandler() http.HandlerFunc {
+// DriftResolveAPIHandler fixes drift: POST {"action":"saveconf"} writes the
+// running config to disk, {"action":"reload"} makes tor re-read the torrc
+func DriftResolveAPIHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`{"onions":["abc123.onion","xyz456.onion"]}`))
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		var req struct {
+			Action string `json:"action"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if req.Action != "saveconf" && req.Action != "reload" {
+			http.Error(w, "action must be saveconf or reload", http.StatusBadRequest)
+			return
+		}
+		ctl, err := inst.Dial()
+		if err != nil {
+			http.Error(w, "Control port unavailable: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer ctl.Close()
+		if req.Action == "saveconf" {
+			err = ctl.SaveConf()
+			metrics.ConfigSaves.Inc(metrics.Result(err))
+		} else {
+			_, err = ctl.Signal("RELOAD")
+		}
+		audit.Record(auth.GetUser(r), "drift-"+req.Action, inst.Torrc, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, map[string]any{"ok": true, "action": req.Action})
 	}
 }
 
 func TorrcUpdateAPIHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// For now, just pretend we updated it
-		w.Write([]byte(`{"saved":true}`))
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		// For now, just pretend we updated it; nothing is written, so no
+		// config save is counted
+		writeJSON(w, map[string]any{"saved": true, "instance": inst.ID, "torrc": inst.Torrc})
 	}
 }
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
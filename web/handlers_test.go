@@ -0,0 +1,256 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tor-admin/internal/audit"
+	"tor-admin/internal/services"
+	"tor-admin/internal/torctl/torctltest"
+)
+
+// fakeTor points the default instance at a fake control port and an empty torrc
+func fakeTor(t *testing.T) *torctltest.Server {
+	t.Helper()
+	srv := torctltest.New(t)
+	torrc := filepath.Join(t.TempDir(), "torrc")
+	if err := os.WriteFile(torrc, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOR_CONTROL_ADDR", srv.Addr)
+	t.Setenv("TOR_CONTROL_PASSWORD", "")
+	t.Setenv("TORRC_PATH", torrc)
+	return srv
+}
+
+// auditLog sends audit records to a temp file and returns a reader for them
+func auditLog(t *testing.T) func() []audit.Entry {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit.SetPath(path)
+	t.Cleanup(func() { audit.SetPath("") })
+	return func() []audit.Entry {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		var entries []audit.Entry
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if line == "" {
+				continue
+			}
+			var e audit.Entry
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, e)
+		}
+		return entries
+	}
+}
+
+func TestSignalAPIHandlerAudit(t *testing.T) {
+	srv := fakeTor(t)
+	entries := auditLog(t)
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		SignalAPIHandler()(rec, httptest.NewRequest(http.MethodPost, "/api/signal", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(`{"signal":"hup"}`); rec.Code != http.StatusOK {
+		t.Fatalf("HUP: %d %s", rec.Code, rec.Body)
+	}
+	srv.Reply("SIGNAL", "551 Internal error\r\n")
+	if rec := post(`{"signal":"NEWNYM"}`); rec.Code != http.StatusBadGateway {
+		t.Fatalf("refused NEWNYM: %d %s", rec.Code, rec.Body)
+	}
+	if rec := post(`{"signal":"USR1"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown signal: %d %s", rec.Code, rec.Body)
+	}
+
+	got := entries()
+	if len(got) != 2 {
+		t.Fatalf("audit entries = %+v, want 2 (unknown signals are not sent)", got)
+	}
+	if e := got[0]; e.Action != "signal" || e.Target != "default: RELOAD" || e.Result != "ok" || e.Error != "" {
+		t.Errorf("HUP entry = %+v", e)
+	}
+	if e := got[1]; e.Action != "signal" || e.Target != "default: NEWNYM" || e.Result != "error" || !strings.Contains(e.Error, "551") {
+		t.Errorf("NEWNYM entry = %+v", e)
+	}
+}
+
+func TestHealthzChecksTor(t *testing.T) {
+	srv := fakeTor(t)
+	info := map[string]string{
+		"status/bootstrap-phase":     `NOTICE BOOTSTRAP PROGRESS=100 TAG=done SUMMARY="Done"`,
+		"status/circuit-established": "1",
+		"status/enough-dir-info":     "1",
+		"version":                    "0.4.8.12",
+		"status/version/current":     "recommended",
+		"process/pid":                "1",
+	}
+	srv.Handle("GETINFO", func(args string) string {
+		var b strings.Builder
+		for _, key := range strings.Fields(args) {
+			v, ok := info[key]
+			if !ok {
+				return "552 Unrecognized key \"" + key + "\"\r\n"
+			}
+			b.WriteString("250-" + key + "=" + v + "\r\n")
+		}
+		return b.String() + "250 OK\r\n"
+	})
+	get := func(h http.HandlerFunc) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rec
+	}
+
+	t.Setenv("HEALTHZ_CHECK_TOR", "")
+	info["status/circuit-established"] = "0"
+	if rec := get(HealthzHandler()); rec.Code != http.StatusOK {
+		t.Errorf("without HEALTHZ_CHECK_TOR: %d, want 200", rec.Code)
+	}
+
+	t.Setenv("HEALTHZ_CHECK_TOR", "true")
+	h := HealthzHandler()
+	if rec := get(h); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "no circuit established") {
+		t.Errorf("no circuit: %d %q, want 503 naming the problem", rec.Code, rec.Body)
+	}
+	info["status/circuit-established"] = "1"
+	if rec := get(h); rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("healthy: %d %q, want 200 ok", rec.Code, rec.Body)
+	}
+	t.Setenv("TOR_CONTROL_ADDR", "127.0.0.1:1")
+	if rec := get(h); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "tor unreachable") {
+		t.Errorf("unreachable: %d %q, want 503", rec.Code, rec.Body)
+	}
+}
+
+// stubManager stands in for the init system and tracks how many actions overlap
+type stubManager struct {
+	mu      sync.Mutex
+	running int
+	overlap int
+	calls   []string
+}
+
+func (m *stubManager) Name() string { return "stub" }
+
+func (m *stubManager) Status(service string) (*services.ServiceStatus, error) {
+	since := time.Now().Add(-time.Minute)
+	return &services.ServiceStatus{Manager: "stub", Service: service, State: services.StateRunning, PID: 42, Since: &since}, nil
+}
+
+func (m *stubManager) Run(service string, action services.Action) (*services.ServiceStatus, error) {
+	m.mu.Lock()
+	m.running++
+	if m.running > m.overlap {
+		m.overlap = m.running
+	}
+	m.calls = append(m.calls, string(action))
+	m.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	m.mu.Lock()
+	m.running--
+	m.mu.Unlock()
+	if action == services.Stop {
+		return &services.ServiceStatus{Manager: "stub", Service: service, State: services.StateStopped, Output: "stopped"},
+			errors.New("stop refused")
+	}
+	return m.Status(service)
+}
+
+func TestServiceAPIHandler(t *testing.T) {
+	fakeTor(t)
+	t.Setenv("TOR_SERVICE_NAME", "tor@test")
+	stub := &stubManager{}
+	services.Use(stub)
+	t.Cleanup(func() { services.Use(nil) })
+	entries := auditLog(t)
+	h := ServiceAPIHandler()
+	do := func(method, action string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(method, "/api/service/"+action, nil))
+		return rec
+	}
+
+	for _, action := range []string{"start", "stop", "restart", "reload"} {
+		rec := do(http.MethodGet, action)
+		if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodPost {
+			t.Errorf("GET %s: %d Allow=%q, want 405 Allow=POST", action, rec.Code, rec.Header().Get("Allow"))
+		}
+	}
+	if rec := do(http.MethodPost, "enable"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown action: %d, want 404", rec.Code)
+	}
+	if len(stub.calls) != 0 {
+		t.Fatalf("rejected requests reached the manager: %v", stub.calls)
+	}
+
+	rec := do(http.MethodGet, "status")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: %d %s", rec.Code, rec.Body)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"ok": true, "instance": "default", "action": "status", "manager": "stub",
+		"service": "tor@test", "state": "running", "pid": float64(42)}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("status %s = %v, want %v", k, got[k], v)
+		}
+	}
+	if up, _ := got["uptime_seconds"].(float64); up < 59 {
+		t.Errorf("uptime_seconds = %v, want about 60", got["uptime_seconds"])
+	}
+	if _, ok := got["error"]; ok {
+		t.Errorf("status reported an error: %v", got["error"])
+	}
+
+	rec = do(http.MethodPost, "stop")
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || got["ok"] != false || got["error"] != "stop refused" || got["state"] != "stopped" {
+		t.Errorf("failed stop: %d %v", rec.Code, got)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := do(http.MethodPost, "restart"); rec.Code != http.StatusOK {
+				t.Errorf("restart: %d %s", rec.Code, rec.Body)
+			}
+		}()
+	}
+	wg.Wait()
+	if stub.overlap != 1 || len(stub.calls) != 3 {
+		t.Errorf("calls %v overlapped %d at once, want 3 calls one at a time", stub.calls, stub.overlap)
+	}
+
+	// status is not audited; every mutating action is
+	var actions []string
+	for _, e := range entries() {
+		actions = append(actions, e.Action+" "+e.Target+" "+e.Result)
+	}
+	wantActions := []string{"service-stop tor@test error", "service-restart tor@test ok", "service-restart tor@test ok"}
+	if strings.Join(actions, ",") != strings.Join(wantActions, ",") {
+		t.Errorf("audit = %q, want %q", actions, wantActions)
+	}
+}
@@ -0,0 +1,32 @@
+// File: web/instance.go
+// Purpose: Resolve the ?instance= a request targets and list known tor instances
+
+package web
+
+import (
+	"net/http"
+
+	"tor-admin/internal/instance"
+)
+
+// requestInstance resolves ?instance= (the default instance when absent),
+// answering 404 itself for unknown ids
+func requestInstance(w http.ResponseWriter, r *http.Request) (*instance.Instance, bool) {
+	inst, err := instance.Get(r.URL.Query().Get("instance"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return inst, true
+}
+
+// InstancesAPIHandler lists the tor instances on this host
+func InstancesAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list := []instance.Instance{}
+		for _, inst := range instance.List() {
+			list = append(list, inst.Public())
+		}
+		writeJSON(w, map[string]any{"instances": list, "default": instance.DefaultID})
+	}
+}
@@ -0,0 +1,791 @@
+// File: web/onion.go
+// Purpose: API handlers for onion service management
+
+package web
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tor-admin/internal/audit"
+	"tor-admin/internal/auth"
+	"tor-admin/internal/config"
+	"tor-admin/internal/instance"
+	"tor-admin/internal/metrics"
+	"tor-admin/internal/onion"
+	"tor-admin/internal/proxy"
+	"tor-admin/internal/torctl"
+)
+
+func OnionsHandler(tfs templateFS) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl := template.Must(template.ParseFS(tfs, "onions.html"))
+		_ = tmpl.Execute(w, map[string]any{
+			"Username": auth.GetUser(r),
+			"IsAdmin":  auth.IsAdmin(r),
+		})
+	}
+}
+
+// HiddenServicesAPIHandler lists the onion services configured in the torrc
+func HiddenServicesAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		services, err := onion.Services(inst.Torrc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		onions := []string{}
+		for _, s := range services {
+			if s.Hostname != "" {
+				onions = append(onions, s.Hostname)
+			}
+		}
+		writeJSON(w, map[string]any{"onions": onions, "services": services})
+	}
+}
+
+// ephemeralManager picks the ephemeral onion manager of the request's instance
+func ephemeralManager(w http.ResponseWriter, r *http.Request, onions map[string]*onion.Manager) (*onion.Manager, bool) {
+	inst, ok := requestInstance(w, r)
+	if !ok {
+		return nil, false
+	}
+	m, ok := onions[inst.ID]
+	if !ok {
+		http.Error(w, "No ephemeral onion manager for instance "+inst.ID, http.StatusNotFound)
+	}
+	return m, ok
+}
+
+// EphemeralOnionsAPIHandler lists ephemeral onion services known to tor
+func EphemeralOnionsAPIHandler(onions map[string]*onion.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m, ok := ephemeralManager(w, r, onions)
+		if !ok {
+			return
+		}
+		list, err := m.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, list)
+	}
+}
+
+// EphemeralOnionCreateAPIHandler creates an ephemeral service with ADD_ONION.
+// The private key is returned once, unless it was discarded.
+func EphemeralOnionCreateAPIHandler(onions map[string]*onion.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		m, ok := ephemeralManager(w, r, onions)
+		if !ok {
+			return
+		}
+		var req onion.CreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		svc, err := m.Create(req)
+		target := ""
+		if svc != nil {
+			target = svc.ServiceID + ".onion"
+		}
+		audit.Record(auth.GetUser(r), "onion-add", target, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.DiscardPK && req.PrivateKey == "" {
+			svc.PrivateKey = ""
+		}
+		writeJSON(w, svc)
+	}
+}
+
+// EphemeralOnionRemoveAPIHandler removes a service with DEL_ONION: POST {"service_id":"..."}
+func EphemeralOnionRemoveAPIHandler(onions map[string]*onion.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		m, ok := ephemeralManager(w, r, onions)
+		if !ok {
+			return
+		}
+		var req struct {
+			ServiceID string `json:"service_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		err := m.Remove(req.ServiceID)
+		audit.Record(auth.GetUser(r), "onion-del", req.ServiceID, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"removed": true})
+	}
+}
+
+// OnionClientsAPIHandler lists the authorized clients of ?dir=<HiddenServiceDir>
+func OnionClientsAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		dir := r.URL.Query().Get("dir")
+		if err := onion.ConfiguredDir(inst.Torrc, dir); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		clients, err := onion.ListClients(dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]any{"dir": dir, "clients": clients})
+	}
+}
+
+// OnionClientAddAPIHandler authorizes a new client: POST {"dir":"...","name":"laptop"}.
+// The response carries the client's .auth_private contents, which are not stored.
+func OnionClientAddAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, req, ok := decodeClientRequest(w, r)
+		if !ok {
+			return
+		}
+		client, err := onion.AddClient(req.Dir, req.Name)
+		audit.Record(auth.GetUser(r), "onion-client-add", req.Dir+"/"+req.Name, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"client": client, "reload": reloadResult(r, inst)})
+	}
+}
+
+// OnionClientRevokeAPIHandler removes a client's .auth file: POST {"dir":"...","name":"laptop"}
+func OnionClientRevokeAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, req, ok := decodeClientRequest(w, r)
+		if !ok {
+			return
+		}
+		err := onion.RevokeClient(req.Dir, req.Name)
+		audit.Record(auth.GetUser(r), "onion-client-revoke", req.Dir+"/"+req.Name, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"revoked": true, "reload": reloadResult(r, inst)})
+	}
+}
+
+type clientRequest struct {
+	Dir  string `json:"dir"`
+	Name string `json:"name"`
+}
+
+// decodeClientRequest reads a client request whose dir must belong to the
+// request's instance
+func decodeClientRequest(w http.ResponseWriter, r *http.Request) (*instance.Instance, *clientRequest, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, nil, false
+	}
+	inst, ok := requestInstance(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	var req clientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if err := onion.ConfiguredDir(inst.Torrc, req.Dir); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return inst, &req, true
+}
+
+// reloadResult sends RELOAD so inst re-reads on-disk onion state, reporting
+// the outcome without failing the request that already changed the files
+func reloadResult(r *http.Request, inst *instance.Instance) map[string]any {
+	ctl, err := inst.Dial()
+	if err == nil {
+		_, err = ctl.Signal("RELOAD")
+		ctl.Close()
+	}
+	audit.Record(auth.GetUser(r), "signal", inst.ID+": RELOAD", err)
+	if err != nil {
+		return map[string]any{"ok": false, "error": err.Error()}
+	}
+	return map[string]any{"ok": true}
+}
+
+// OnionCredentialsAPIHandler lists ClientOnionAuthDir files and, when tor is
+// reachable, the credentials it currently has loaded
+func OnionCredentialsAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		dir, err := onion.ClientAuthDir(inst.Torrc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		files, err := onion.ListCredentials(dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp := map[string]any{"dir": dir, "files": files}
+		if ctl, err := inst.Dial(); err != nil {
+			resp["live_error"] = err.Error()
+		} else {
+			if live, err := ctl.OnionClientAuthView(); err != nil {
+				resp["live_error"] = err.Error()
+			} else {
+				resp["live"] = live
+			}
+			ctl.Close()
+		}
+		writeJSON(w, resp)
+	}
+}
+
+// OnionCredentialImportAPIHandler stores an .auth_private credential:
+// POST {"name":"site","content":"<addr>:descriptor:x25519:<key>","load":true}.
+// With load set the key is also added to the running tor.
+func OnionCredentialImportAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Name    string `json:"name"`
+			Content string `json:"content"`
+			Load    bool   `json:"load"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		dir, err := onion.ClientAuthDir(inst.Torrc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cred, err := onion.ImportCredential(dir, req.Name, req.Content)
+		target := req.Name
+		if cred != nil {
+			target = cred.Address + ".onion"
+		}
+		audit.Record(auth.GetUser(r), "onion-credential-import", target, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := map[string]any{"imported": true, "address": cred.Address + ".onion"}
+		if req.Load {
+			resp["loaded"], resp["load_error"] = liveCredential(inst, func(ctl *torctl.Conn) error {
+				return ctl.OnionClientAuthAdd(cred.Address, cred.KeyBase64(), req.Name, false)
+			})
+		}
+		writeJSON(w, resp)
+	}
+}
+
+// OnionCredentialDeleteAPIHandler removes a stored credential and, with
+// "unload":true, drops it from the running tor: POST {"name":"site","unload":true}
+func OnionCredentialDeleteAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Name   string `json:"name"`
+			Unload bool   `json:"unload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		dir, err := onion.ClientAuthDir(inst.Torrc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cred, readErr := onion.ReadCredential(dir, req.Name)
+		err = onion.DeleteCredential(dir, req.Name)
+		audit.Record(auth.GetUser(r), "onion-credential-delete", req.Name, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := map[string]any{"deleted": true}
+		if req.Unload && readErr == nil {
+			resp["unloaded"], resp["unload_error"] = liveCredential(inst, func(ctl *torctl.Conn) error {
+				return ctl.OnionClientAuthRemove(cred.Address)
+			})
+		}
+		writeJSON(w, resp)
+	}
+}
+
+// liveCredential runs a client-auth command against inst, reporting success
+// and any error text for the JSON response
+func liveCredential(inst *instance.Instance, fn func(*torctl.Conn) error) (bool, string) {
+	ctl, err := inst.Dial()
+	if err == nil {
+		err = fn(ctl)
+		ctl.Close()
+	}
+	if err != nil {
+		return false, err.Error()
+	}
+	return true, ""
+}
+
+// VanityAPIHandler lists vanity address searches with progress and ETA
+func VanityAPIHandler(vanity *onion.Vanity) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"jobs": vanity.List(), "max_prefix": onion.MaxVanityPrefix})
+	}
+}
+
+// VanityStartAPIHandler starts a search: POST {"prefix":"abc"}
+func VanityStartAPIHandler(vanity *onion.Vanity) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Prefix string `json:"prefix"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		job, err := vanity.Start(req.Prefix)
+		audit.Record(auth.GetUser(r), "vanity-start", req.Prefix, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, job)
+	}
+}
+
+// VanityCancelAPIHandler stops a running search or discards a finished one: POST {"id":"..."}
+func VanityCancelAPIHandler(vanity *onion.Vanity) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if err := vanity.Cancel(req.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]any{"ok": true})
+	}
+}
+
+// VanityImportAPIHandler turns a found key into a new onion service:
+// POST {"id":"...","dir":"/var/lib/tor/myservice","ports":["80 127.0.0.1:8080"]}
+func VanityImportAPIHandler(vanity *onion.Vanity) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			ID    string   `json:"id"`
+			Dir   string   `json:"dir"`
+			Ports []string `json:"ports"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if !filepath.IsAbs(req.Dir) {
+			http.Error(w, "dir must be an absolute path", http.StatusBadRequest)
+			return
+		}
+		req.Dir = filepath.Clean(req.Dir)
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		tc, err := config.LoadTorrc(inst.Torrc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, ok := tc.HiddenService(req.Dir); ok {
+			http.Error(w, "hidden service directory already configured", http.StatusBadRequest)
+			return
+		}
+		if len(req.Ports) == 0 {
+			http.Error(w, "at least one port mapping is required", http.StatusBadRequest)
+			return
+		}
+		for _, p := range req.Ports {
+			if err := config.ValidatePortMapping(p); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		addr, err := vanity.Import(req.ID, req.Dir)
+		if err == nil {
+			err = tc.AddHiddenService(req.Dir, req.Ports)
+		}
+		if err == nil {
+			err = tc.Save(inst.Torrc)
+			metrics.ConfigSaves.Inc(metrics.Result(err))
+		}
+		audit.Record(auth.GetUser(r), "vanity-import", addr, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"address": addr, "dir": req.Dir, "reload": reloadResult(r, inst)})
+	}
+}
+
+// maxBackupSize bounds uploaded onion backup archives
+const maxBackupSize = 10 << 20
+
+// OnionBackupExportAPIHandler returns an encrypted archive of onion services:
+// POST {"dirs":[...],"passphrase":"..."}; an empty dirs list exports every service
+func OnionBackupExportAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Dirs       []string `json:"dirs"`
+			Passphrase string   `json:"passphrase"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		archive, err := onion.Export(inst.Torrc, req.Dirs, req.Passphrase)
+		audit.Record(auth.GetUser(r), "onion-backup-export", strings.Join(req.Dirs, ","), err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name := "onion-backup-" + time.Now().Format("20060102-150405") + ".tahs"
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		_, _ = w.Write(archive)
+	}
+}
+
+// readBackupForm reads the multipart "archive" file and "passphrase" field
+func readBackupForm(w http.ResponseWriter, r *http.Request) ([]byte, string, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, "", false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBackupSize)
+	if err := r.ParseMultipartForm(maxBackupSize); err != nil {
+		http.Error(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+		return nil, "", false
+	}
+	file, _, err := r.FormFile("archive")
+	if err != nil {
+		http.Error(w, "archive file is required", http.StatusBadRequest)
+		return nil, "", false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, "", false
+	}
+	return data, r.FormValue("passphrase"), true
+}
+
+// OnionBackupVerifyAPIHandler decrypts and checks an archive without restoring it
+func OnionBackupVerifyAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		archive, passphrase, ok := readBackupForm(w, r)
+		if !ok {
+			return
+		}
+		b, err := onion.Open(archive, passphrase)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"valid": true, "created": b.Created, "services": b.Summary()})
+	}
+}
+
+// OnionBackupRestoreAPIHandler restores services from a verified archive.
+// Form fields: archive, passphrase, optional dirs (repeatable), overwrite=true
+// and any_dir=true to allow directories outside the DataDirectory that the
+// torrc does not already list.
+func OnionBackupRestoreAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		archive, passphrase, ok := readBackupForm(w, r)
+		if !ok {
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		opts := onion.RestoreOptions{
+			Dirs:      r.MultipartForm.Value["dirs"],
+			Overwrite: r.FormValue("overwrite") == "true",
+			DataDir:   inst.DataDir,
+			AnyDir:    r.FormValue("any_dir") == "true",
+		}
+		restored, err := onion.Restore(inst.Torrc, archive, passphrase, opts)
+		if len(restored) > 0 {
+			metrics.ConfigSaves.Inc(metrics.Result(err))
+		}
+		audit.Record(auth.GetUser(r), "onion-backup-restore", strings.Join(restored, ","), err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"restored": restored, "reload": reloadResult(r, inst)})
+	}
+}
+
+// OnionDoSAPIHandler reports a service's DoS defenses (?dir=) with the
+// MetricsPort counters that show how they are working
+func OnionDoSAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		dir := r.URL.Query().Get("dir")
+		tc, err := config.LoadTorrc(inst.Torrc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		block, ok := tc.HiddenService(dir)
+		if !ok {
+			http.Error(w, "not a configured HiddenServiceDir", http.StatusBadRequest)
+			return
+		}
+		resp := map[string]any{"dir": dir, "options": onion.DoSOptions, "values": onion.ReadDoS(*block)}
+		hostname, _ := os.ReadFile(filepath.Join(dir, "hostname"))
+		if url := metrics.MetricsPortURL(inst.Torrc); url == "" {
+			resp["metrics_error"] = "MetricsPort is not configured"
+		} else if families, err := metrics.Scrape(r.Context(), url); err != nil {
+			resp["metrics_error"] = err.Error()
+		} else if len(hostname) > 0 {
+			panel, reasons := metrics.OnionDetail(families, strings.TrimSpace(string(hostname)))
+			resp["metrics"] = map[string]any{"panel": panel, "rejected_by_reason": reasons}
+		}
+		writeJSON(w, resp)
+	}
+}
+
+// OnionDoSSaveAPIHandler updates a service's DoS defenses:
+// POST {"dir":"...","values":{"HiddenServicePoWDefensesEnabled":"1"}}; "" restores tor's default
+func OnionDoSSaveAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Dir    string            `json:"dir"`
+			Values map[string]string `json:"values"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		err := onion.ApplyDoS(inst.Torrc, req.Dir, req.Values)
+		metrics.ConfigSaves.Inc(metrics.Result(err))
+		audit.Record(auth.GetUser(r), "onion-dos", req.Dir, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"saved": true, "reload": reloadResult(r, inst)})
+	}
+}
+
+// OnionBalanceAPIHandler sets up services as OnionBalance backends:
+// POST {"frontend_dir":"/var/lib/tor/front","backends":["/var/lib/tor/b1",...]}.
+// Without frontend_dir a new frontend key is generated. The response holds
+// config.yaml and the frontend key file, which tor-admin does not keep.
+func OnionBalanceAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			FrontendDir string   `json:"frontend_dir"`
+			Backends    []string `json:"backends"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		torrcPath := inst.Torrc
+		var key *onion.FrontendKey
+		var err error
+		if req.FrontendDir != "" {
+			if err = onion.ConfiguredDir(torrcPath, req.FrontendDir); err == nil {
+				key, err = onion.FrontendKeyFromDir(req.FrontendDir)
+			}
+		} else {
+			key, err = onion.GenerateFrontendKey()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		backends, err := onion.BalanceBackends(torrcPath, req.Backends, key.Address)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = onion.EnableBalanceInstances(torrcPath, backends, key.Address)
+		metrics.ConfigSaves.Inc(metrics.Result(err))
+		audit.Record(auth.GetUser(r), "onionbalance", key.Address, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]any{
+			"frontend":    key.Address,
+			"backends":    backends,
+			"config_yaml": onion.BalanceConfig(key, backends),
+			"key":         key,
+			"reload":      reloadResult(r, inst),
+		})
+	}
+}
+
+// ProxySitesAPIHandler lists Onion-Location reverse proxy sites
+func ProxySitesAPIHandler(sites *proxy.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"sites": sites.Sites()})
+	}
+}
+
+// ProxySiteSaveAPIHandler adds or replaces a proxy site:
+// POST {"name":"blog","listen":"127.0.0.1:8081","upstream":"http://127.0.0.1:3000","dir":"/var/lib/tor/blog"}
+func ProxySiteSaveAPIHandler(sites *proxy.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var site proxy.Site
+		if err := json.NewDecoder(r.Body).Decode(&site); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		inst, ok := requestInstance(w, r)
+		if !ok {
+			return
+		}
+		err := onion.ConfiguredDir(inst.Torrc, site.Dir)
+		if err == nil {
+			err = sites.Put(site)
+		}
+		audit.Record(auth.GetUser(r), "proxy-save", site.Name, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"saved": true, "sites": sites.Sites()})
+	}
+}
+
+// ProxySiteDeleteAPIHandler stops and removes a proxy site: POST {"name":"blog"}
+func ProxySiteDeleteAPIHandler(sites *proxy.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		err := sites.Delete(req.Name)
+		audit.Record(auth.GetUser(r), "proxy-delete", req.Name, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"deleted": true})
+	}
+}
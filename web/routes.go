@@ -1,37 +1,91 @@
-// File: internal/web/routes.go
+// File: web/routes.go
 // Purpose: Define and register web and API routes, applying auth middleware.
 
 package web
 
 import (
+	"io/fs"
 	"net/http"
 
 	"tor-admin/internal/auth"
+	"tor-admin/internal/onion"
+	"tor-admin/internal/proxy"
+	"tor-admin/internal/scheduler"
+	"tor-admin/internal/torlog"
 	"tor-admin/internal/ui"
-	"tor-admin/internal/web/handlers"
 )
 
-// RegisterRoutes sets up all HTTP routes for the web UI and API.
-func RegisterRoutes(mux *http.ServeMux) {
-	templateFS := ui.GetTemplateFS()
+type templateFS = fs.FS
+
+// Deps carries the long-lived background services handlers depend on
+type Deps struct {
+	Schedulers map[string]*scheduler.Scheduler // by instance id
+	Logs       *torlog.Buffer
+	Onions     map[string]*onion.Manager // ephemeral onions by instance id
+	Vanity     *onion.Vanity
+	Proxy      *proxy.Manager
+}
 
-	// Static file handler (served from /static/)
-	ui.RegisterStatic(mux)
+// RegisterRoutes sets up all HTTP routes for the web UI and API.
+// Static assets are registered separately by the caller.
+func RegisterRoutes(mux *http.ServeMux, deps Deps) {
+	tfs := ui.GetTemplateFS()
 
 	// Public routes (setup, login)
-	mux.HandleFunc("/setup", handlers.SetupHandler(templateFS))
-	mux.HandleFunc("/login", handlers.LoginHandler(templateFS))
+	mux.HandleFunc("/setup", SetupHandler(tfs))
+	mux.HandleFunc("/login", LoginHandler(tfs))
 
 	// Auth-protected routes
-	mux.Handle("/", auth.RequireLogin(handlers.IndexHandler(templateFS)))
-	mux.Handle("/config", auth.RequireLogin(handlers.ConfigHandler(templateFS)))
-	mux.Handle("/logout", auth.RequireLogin(http.HandlerFunc(handlers.LogoutHandler)))
-	mux.Handle("/api/hidden", auth.RequireLogin(http.HandlerFunc(handlers.HiddenServicesHandler)))
-	mux.Handle("/api/bandwidth", auth.RequireLogin(http.HandlerFunc(handlers.BandwidthHandler)))
-
-	// Optional: health check
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("ok"))
-	})
+	mux.Handle("/", auth.RequireLogin(IndexHandler(tfs)))
+	mux.Handle("/config", auth.RequireLogin(ConfigHandler(tfs)))
+	mux.Handle("/logout", auth.RequireLogin(LogoutHandler()))
+	mux.Handle("/api/service/status", auth.RequireLogin(ServiceAPIHandler()))
+	mux.Handle("/api/service/", auth.RequireLogin(auth.RequireAdmin(ServiceAPIHandler())))
+	mux.Handle("/api/instances", auth.RequireLogin(InstancesAPIHandler()))
+	mux.Handle("/api/hidden", auth.RequireLogin(HiddenServicesAPIHandler()))
+	mux.Handle("/api/torrc", auth.RequireLogin(TorrcUpdateAPIHandler()))
+	mux.Handle("/api/bandwidth", auth.RequireLogin(BandwidthAPIHandler()))
+	mux.Handle("/api/bandwidth/accounting", auth.RequireLogin(AccountingAPIHandler()))
+	mux.Handle("/api/bandwidth/plan", auth.RequireLogin(AccountingPlanAPIHandler()))
+	mux.Handle("/api/bandwidth/schedule", auth.RequireLogin(BandwidthScheduleAPIHandler(deps.Schedulers)))
+	mux.Handle("/api/metricsport", auth.RequireLogin(MetricsPortAPIHandler()))
+	mux.Handle("/circuits", auth.RequireLogin(CircuitsHandler(tfs)))
+	mux.Handle("/api/circuits", auth.RequireLogin(CircuitsAPIHandler()))
+	mux.Handle("/api/circuits/events", auth.RequireLogin(CircuitEventsHandler()))
+	mux.Handle("/api/circuits/close", auth.RequireLogin(auth.RequireAdmin(CircuitCloseAPIHandler())))
+	mux.Handle("/api/signal", auth.RequireLogin(auth.RequireAdmin(SignalAPIHandler())))
+
+	mux.Handle("/api/health", auth.RequireLogin(HealthAPIHandler()))
+	mux.Handle("/api/drift", auth.RequireLogin(DriftAPIHandler()))
+	mux.Handle("/api/drift/resolve", auth.RequireLogin(auth.RequireAdmin(DriftResolveAPIHandler())))
+	mux.Handle("/logs", auth.RequireLogin(LogsHandler(tfs)))
+	mux.Handle("/api/logs", auth.RequireLogin(LogsAPIHandler(deps.Logs)))
+	mux.Handle("/api/logs/stream", auth.RequireLogin(LogStreamHandler(deps.Logs)))
+
+	mux.Handle("/onions", auth.RequireLogin(OnionsHandler(tfs)))
+	mux.Handle("/api/onions/ephemeral", auth.RequireLogin(EphemeralOnionsAPIHandler(deps.Onions)))
+	mux.Handle("/api/onions/ephemeral/create", auth.RequireLogin(auth.RequireAdmin(EphemeralOnionCreateAPIHandler(deps.Onions))))
+	mux.Handle("/api/onions/ephemeral/remove", auth.RequireLogin(auth.RequireAdmin(EphemeralOnionRemoveAPIHandler(deps.Onions))))
+	mux.Handle("/api/onions/clients", auth.RequireLogin(OnionClientsAPIHandler()))
+	mux.Handle("/api/onions/clients/add", auth.RequireLogin(auth.RequireAdmin(OnionClientAddAPIHandler())))
+	mux.Handle("/api/onions/clients/revoke", auth.RequireLogin(auth.RequireAdmin(OnionClientRevokeAPIHandler())))
+	mux.Handle("/api/onions/credentials", auth.RequireLogin(OnionCredentialsAPIHandler()))
+	mux.Handle("/api/onions/credentials/import", auth.RequireLogin(auth.RequireAdmin(OnionCredentialImportAPIHandler())))
+	mux.Handle("/api/onions/credentials/delete", auth.RequireLogin(auth.RequireAdmin(OnionCredentialDeleteAPIHandler())))
+	mux.Handle("/api/onions/vanity", auth.RequireLogin(VanityAPIHandler(deps.Vanity)))
+	mux.Handle("/api/onions/vanity/start", auth.RequireLogin(auth.RequireAdmin(VanityStartAPIHandler(deps.Vanity))))
+	mux.Handle("/api/onions/vanity/cancel", auth.RequireLogin(auth.RequireAdmin(VanityCancelAPIHandler(deps.Vanity))))
+	mux.Handle("/api/onions/vanity/import", auth.RequireLogin(auth.RequireAdmin(VanityImportAPIHandler(deps.Vanity))))
+	mux.Handle("/api/onions/backup/export", auth.RequireLogin(auth.RequireAdmin(OnionBackupExportAPIHandler())))
+	mux.Handle("/api/onions/backup/verify", auth.RequireLogin(auth.RequireAdmin(OnionBackupVerifyAPIHandler())))
+	mux.Handle("/api/onions/backup/restore", auth.RequireLogin(auth.RequireAdmin(OnionBackupRestoreAPIHandler())))
+	mux.Handle("/api/onions/dos", auth.RequireLogin(OnionDoSAPIHandler()))
+	mux.Handle("/api/onions/dos/save", auth.RequireLogin(auth.RequireAdmin(OnionDoSSaveAPIHandler())))
+	mux.Handle("/api/onions/onionbalance", auth.RequireLogin(auth.RequireAdmin(OnionBalanceAPIHandler())))
+	mux.Handle("/api/onions/proxy", auth.RequireLogin(ProxySitesAPIHandler(deps.Proxy)))
+	mux.Handle("/api/onions/proxy/save", auth.RequireLogin(auth.RequireAdmin(ProxySiteSaveAPIHandler(deps.Proxy))))
+	mux.Handle("/api/onions/proxy/delete", auth.RequireLogin(auth.RequireAdmin(ProxySiteDeleteAPIHandler(deps.Proxy))))
+
+	// Optional: health check (set HEALTHZ_CHECK_TOR=true to include tor's state)
+	mux.HandleFunc("/healthz", HealthzHandler())
 }